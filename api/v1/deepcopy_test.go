@@ -6,6 +6,7 @@ import (
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
 // mutatedValue is written into an original object's shared reference fields to
@@ -55,6 +56,22 @@ func TestGlobalRule_DeepCopy(t *testing.T) {
 	}
 }
 
+func TestGlobalRule_DeepCopy_Peers(t *testing.T) {
+	in := &GlobalRule{
+		Type: policyTypeAllow, Port: 5432, Protocol: protocolTCP, Direction: directionIngress,
+		From: []RulePeer{{Namespaces: []string{nsOne}, PodSelector: map[string]string{"app": "api"}}},
+	}
+	out := in.DeepCopy()
+	if !reflect.DeepEqual(in, out) {
+		t.Fatalf("DeepCopy mismatch: got %+v, want %+v", out, in)
+	}
+	in.From[0].Namespaces[0] = mutatedValue
+	in.From[0].PodSelector["app"] = mutatedValue
+	if out.From[0].Namespaces[0] == mutatedValue || out.From[0].PodSelector["app"] == mutatedValue {
+		t.Fatal("DeepCopy did not deep copy From peers")
+	}
+}
+
 func TestGlobalRule_DeepCopy_Nil(t *testing.T) {
 	var in *GlobalRule
 	if in.DeepCopy() != nil {
@@ -186,6 +203,8 @@ func TestNetworkPolicyGeneratorSpec_DeepCopy(t *testing.T) {
 		CIDRRules: []CIDRRule{
 			{CIDR: cidr10Slash8, Except: []string{cidr10Slash16}, Direction: directionEgress},
 		},
+		RevisionHistoryLimit: ptr.To(int32(5)),
+		RollbackTo:           ptr.To(int64(2)),
 	}
 	out := in.DeepCopy()
 	if !reflect.DeepEqual(in, out) {
@@ -200,6 +219,10 @@ func TestNetworkPolicyGeneratorSpec_DeepCopy(t *testing.T) {
 	if out.CIDRRules[0].Except[0] == mutatedValue {
 		t.Fatal("DeepCopy did not deep copy CIDRRules Except")
 	}
+	*in.RollbackTo = 3
+	if *out.RollbackTo == 3 {
+		t.Fatal("DeepCopy did not deep copy RollbackTo")
+	}
 }
 
 func TestNetworkPolicyGeneratorSpec_DeepCopy_Nil(t *testing.T) {
@@ -299,13 +322,20 @@ func TestPolicyDiffEntry_DeepCopy(t *testing.T) {
 	in := &PolicyDiffEntry{
 		PolicyName: "pol1",
 		Namespace:  nsDefault,
-		Action:     "Created",
+		Action:     "Updated",
+		Changes:    []string{`spec.ingress[0].ports[0].port: 80 -> 443`},
+		Patch:      `[{"op":"replace","path":"/spec/ingress/0/ports/0/port","value":443}]`,
 		Timestamp:  now,
 	}
 	out := in.DeepCopy()
 	if !reflect.DeepEqual(in, out) {
 		t.Fatal("DeepCopy mismatch")
 	}
+
+	in.Changes[0] = mutatedValue
+	if out.Changes[0] == mutatedValue {
+		t.Fatal("DeepCopy did not deep copy Changes")
+	}
 }
 
 func TestPolicyDiffEntry_DeepCopy_Nil(t *testing.T) {
@@ -40,11 +40,31 @@ type NetworkPolicyGeneratorSpec struct {
 	// PolicyEngine specifies the CNI-specific policy engine to use
 	// "kubernetes" generates standard NetworkPolicy (networking.k8s.io/v1)
 	// "cilium" generates CiliumNetworkPolicy (cilium.io/v2)
-	// +kubebuilder:validation:Enum=kubernetes;cilium;calico
+	// "calico" generates Calico NetworkPolicy (crd.projectcalico.org/v1)
+	// "antrea" generates Antrea NetworkPolicy or ClusterNetworkPolicy (crd.antrea.io/v1beta1)
+	// "auto" picks the first engine whose CRDs the cluster serves, following the
+	// operator's --auto-engine-preference order, and falls back to "kubernetes"
+	// Any other name selects an external engine plugin registered through the
+	// operator's --engine-plugins-config; the webhook rejects unknown names.
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
 	// +kubebuilder:default=kubernetes
 	// +optional
 	PolicyEngine string `json:"policyEngine,omitempty"`
 
+	// PolicyEngines generates policies for several engines from one generator,
+	// e.g. while migrating from one CNI to another. When set, it takes
+	// precedence over policyEngine. Removing an engine from the list deletes
+	// the policies previously generated for it.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=4
+	// +kubebuilder:validation:items:MaxLength=63
+	// +kubebuilder:validation:items:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:XValidation:rule="self.all(e, e != 'auto')",message="policyEngines entries cannot be auto"
+	// +listType=set
+	// +optional
+	PolicyEngines []string `json:"policyEngines,omitempty"`
+
 	// TemplateName specifies a built-in policy template to use as a base
 	// Available templates: zero-trust, web-app, backend-api, database, monitoring
 	// Template rules are merged with user-defined globalRules (user rules take precedence)
@@ -57,6 +77,29 @@ type NetworkPolicyGeneratorSpec struct {
 	// +optional
 	DryRun bool `json:"dryRun,omitempty"`
 
+	// DriftPolicy selects what happens when a generated policy is edited or
+	// deleted by hand. "enforce" re-applies the generated policy; "report"
+	// leaves the drifted object alone and only emits a DriftDetected event.
+	// +kubebuilder:validation:Enum=enforce;report
+	// +kubebuilder:default=enforce
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// RevisionHistoryLimit is the number of earlier policy revisions kept
+	// besides the current one. Each distinct policy spec is stored as a
+	// ControllerRevision; addresses discovered from the cluster are not.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=10
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo generates the policies from the spec of an earlier
+	// revision, listed in the generator's ControllerRevisions, in place of
+	// the current spec until it is cleared
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	RollbackTo *int64 `json:"rollbackTo,omitempty"`
+
 	// Policy defines the main policy configuration
 	Policy PolicyConfig `json:"policy"`
 
@@ -65,14 +108,158 @@ type NetworkPolicyGeneratorSpec struct {
 	// +optional
 	GlobalRules []GlobalRule `json:"globalRules,omitempty"`
 
+	// IPFamilies lists the IP families that "any address" global rules cover:
+	// ["IPv4"], ["IPv6"], or both on dual-stack clusters. Defaults to the
+	// operator's --default-ip-families, or when that is unset to the
+	// cluster's families, read from the kubernetes Service in default.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:XValidation:rule="self.all(f, f == 'IPv4' || f == 'IPv6')",message="ipFamilies entries must be IPv4 or IPv6"
+	// +listType=set
+	// +optional
+	IPFamilies []string `json:"ipFamilies,omitempty"`
+
+	// PolicyTypes lists the traffic directions the generated policies
+	// enforce: ["ingress"], ["egress"], or both. Rules for a direction that is
+	// not listed are dropped and its traffic is left untouched. Defaults to
+	// both directions.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:XValidation:rule="self.all(t, t == 'ingress' || t == 'egress')",message="policyTypes entries must be ingress or egress"
+	// +listType=set
+	// +optional
+	PolicyTypes []string `json:"policyTypes,omitempty"`
+
 	// CIDRRules defines CIDR-based traffic rules for external IP ranges
 	// +kubebuilder:validation:MaxItems=256
 	// +optional
 	CIDRRules []CIDRRule `json:"cidrRules,omitempty"`
+
+	// FQDNRules allows egress to DNS names instead of fixed IP ranges.
+	// Supported by the cilium, calico and antrea engines; the kubernetes engine rejects them.
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	FQDNRules []FQDNRule `json:"fqdnRules,omitempty"`
+
+	// DNS configures the DNS egress rule added to every generated policy.
+	// Defaults to the cluster DNS pods (k8s-app=kube-dns in kube-system).
+	// +optional
+	DNS *DNSConfig `json:"dns,omitempty"`
+
+	// AllowNodeTraffic allows ingress from the cluster nodes, so kubelet
+	// health probes and NodePort/LoadBalancer health checks keep working.
+	// The controller tracks the node addresses in status.nodeCIDRs and
+	// updates the policies as nodes join or leave.
+	// +optional
+	AllowNodeTraffic bool `json:"allowNodeTraffic,omitempty"`
+
+	// AllowAPIServer allows egress to the Kubernetes API server, so operators
+	// and controllers in enforced namespaces keep working. The controller
+	// reads the addresses and ports from the default/kubernetes EndpointSlices
+	// into status.apiServerCIDRs and status.apiServerPorts and updates the
+	// policies when they change.
+	// +optional
+	AllowAPIServer bool `json:"allowAPIServer,omitempty"`
+
+	// Antrea holds settings that only apply when policyEngine is "antrea"
+	// +optional
+	Antrea *AntreaConfig `json:"antrea,omitempty"`
+}
+
+// DNSConfig selects where generated policies allow DNS lookups to go
+// +kubebuilder:validation:XValidation:rule="(has(self.mode) && self.mode == 'custom') == has(self.to)",message="to must be set when mode is custom, and only then"
+type DNSConfig struct {
+	// Mode selects the DNS destination.
+	// "auto" allows the cluster DNS pods (k8s-app=kube-dns in kube-system),
+	// "custom" allows the resolvers listed in to, and "none" adds no DNS rule.
+	// +kubebuilder:validation:Enum=auto;none;custom
+	// +kubebuilder:default=auto
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// To lists the resolvers allowed in custom mode: pod and namespace
+	// selectors, or CIDRs such as 169.254.20.10/32 for NodeLocal DNSCache
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	To []RulePeer `json:"to,omitempty"`
+
+	// Protocols lists the protocols DNS is allowed over on port 53.
+	// Defaults to both UDP and TCP.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=2
+	// +kubebuilder:validation:XValidation:rule="self.all(p, p == 'UDP' || p == 'TCP')",message="dns protocols must be UDP or TCP"
+	// +listType=set
+	// +optional
+	Protocols []string `json:"protocols,omitempty"`
+}
+
+// FQDNRule allows egress to destinations matched by DNS name
+// +kubebuilder:validation:XValidation:rule="has(self.matchName) != has(self.matchPattern)",message="exactly one of matchName or matchPattern must be specified"
+type FQDNRule struct {
+	// MatchName is an exact DNS name (e.g., "api.example.com")
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[-a-zA-Z0-9_.]+$`
+	// +optional
+	MatchName string `json:"matchName,omitempty"`
+
+	// MatchPattern is a DNS name where "*" matches any characters within a label (e.g., "*.example.com")
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern=`^[-a-zA-Z0-9_.*]+$`
+	// +optional
+	MatchPattern string `json:"matchPattern,omitempty"`
+
+	// Ports restricts the allowed destination ports. Empty allows every port.
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Ports []FQDNPort `json:"ports,omitempty"`
+}
+
+// FQDNPort is a destination port of an FQDN rule
+type FQDNPort struct {
+	// Port number (1-65535)
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// Protocol (TCP/UDP/SCTP)
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP
+	// +kubebuilder:default=TCP
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// AntreaConfig controls how the antrea engine renders Antrea-native policies
+type AntreaConfig struct {
+	// Tier is the Antrea tier the generated policies are attached to
+	// (e.g., "securityops", "application"). Defaults to "application".
+	// +kubebuilder:validation:MaxLength=63
+	// +optional
+	Tier string `json:"tier,omitempty"`
+
+	// Priority orders the policy within its tier; lower values are evaluated first.
+	// Defaults to 5.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10000
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// DefaultAction is taken for traffic that no allow rule matches.
+	// "Drop" silently discards it, "Reject" answers with a TCP RST or ICMP unreachable.
+	// Defaults to "Drop".
+	// +kubebuilder:validation:Enum=Drop;Reject
+	// +optional
+	DefaultAction string `json:"defaultAction,omitempty"`
+
+	// ClusterScoped renders a single ClusterNetworkPolicy instead of one
+	// namespaced NetworkPolicy per target namespace
+	// +optional
+	ClusterScoped bool `json:"clusterScoped,omitempty"`
 }
 
 // PolicyConfig defines the main policy configuration
 // +kubebuilder:validation:XValidation:rule="!has(self.allowedNamespaces) || !has(self.deniedNamespaces) || !self.allowedNamespaces.exists(n, n in self.deniedNamespaces)",message="a namespace cannot be listed in both allowedNamespaces and deniedNamespaces"
+// +kubebuilder:validation:XValidation:rule="!has(self.podSelector) || !has(self.podLabelSelector)",message="podSelector and podLabelSelector are mutually exclusive"
 type PolicyConfig struct {
 	// Type defines the policy type (allow/deny)
 	// +kubebuilder:validation:Enum=allow;deny
@@ -92,36 +279,170 @@ type PolicyConfig struct {
 	// +optional
 	DeniedNamespaces []string `json:"deniedNamespaces,omitempty"`
 
+	// AllowSameNamespace allows traffic between pods in the generator's own
+	// namespace when policy type is deny, as if the namespace were listed in
+	// allowedNamespaces. A new generator gets true, set by the defaulting
+	// webhook or, without webhooks, by the controller on its first reconcile.
+	// Generators created before the field existed keep it unset, which is off.
+	// +optional
+	AllowSameNamespace *bool `json:"allowSameNamespace,omitempty"`
+
+	// AllowedNamespaceSelector allows namespaces by label when policy type is
+	// deny, in addition to allowedNamespaces
+	// +optional
+	AllowedNamespaceSelector *metav1.LabelSelector `json:"allowedNamespaceSelector,omitempty"`
+
+	// DeniedNamespaceSelector denies namespaces by label when policy type is
+	// allow, in addition to deniedNamespaces. Policies are created in every
+	// matching namespace and removed when a namespace stops matching.
+	// +optional
+	DeniedNamespaceSelector *metav1.LabelSelector `json:"deniedNamespaceSelector,omitempty"`
+
 	// PodSelector restricts policy to pods matching these labels
 	// If empty, applies to all pods in the namespace
 	// +optional
 	PodSelector map[string]string `json:"podSelector,omitempty"`
+
+	// PodLabelSelector restricts policy to pods matching a full label
+	// selector, for targeting that plain labels cannot express such as
+	// "app in (api, worker)". Mutually exclusive with podSelector.
+	// +optional
+	PodLabelSelector *metav1.LabelSelector `json:"podLabelSelector,omitempty"`
 }
 
 // GlobalRule defines a single traffic rule
-// +kubebuilder:validation:XValidation:rule="has(self.port) != has(self.namedPort)",message="exactly one of port or namedPort must be specified"
+// +kubebuilder:validation:XValidation:rule="self.protocol in ['ICMP', 'ICMPv6'] || has(self.port) != has(self.namedPort)",message="exactly one of port or namedPort must be specified"
+// +kubebuilder:validation:XValidation:rule="!(self.protocol in ['ICMP', 'ICMPv6']) || ((has(self.icmp) || has(self.notICMP)) && !has(self.port) && !has(self.namedPort))",message="ICMP rules require icmp or notICMP and cannot specify port or namedPort"
+// +kubebuilder:validation:XValidation:rule="!has(self.icmp) || self.protocol in ['ICMP', 'ICMPv6']",message="icmp requires protocol ICMP or ICMPv6"
+// +kubebuilder:validation:XValidation:rule="!has(self.notICMP) || self.protocol in ['ICMP', 'ICMPv6']",message="notICMP requires protocol ICMP or ICMPv6"
+// +kubebuilder:validation:XValidation:rule="!has(self.http) || self.protocol == 'TCP'",message="http rules require protocol TCP"
+// +kubebuilder:validation:XValidation:rule="!has(self.http) || self.type == 'allow'",message="http rules cannot be combined with type deny"
+// +kubebuilder:validation:XValidation:rule="!has(self.endPort) || (has(self.port) && self.endPort >= self.port)",message="endPort requires port and must be greater than or equal to port"
+// +kubebuilder:validation:XValidation:rule="!has(self.http) || !has(self.endPort)",message="http rules cannot be combined with endPort"
+// +kubebuilder:validation:XValidation:rule="!has(self.from) || self.direction == 'ingress'",message="from requires direction ingress"
+// +kubebuilder:validation:XValidation:rule="!has(self.to) || self.direction == 'egress'",message="to requires direction egress"
 type GlobalRule struct {
-	// Type defines whether to allow or deny this rule
+	// Type defines whether to allow or deny this rule. Deny rules block the
+	// port for every peer, or for the peers in from/to, and take precedence
+	// over allow rules; the kubernetes engine cannot express them and rejects them.
 	// +kubebuilder:validation:Enum=allow;deny
 	Type string `json:"type"`
 
-	// Port number (1-65535). Either port or namedPort must be specified.
+	// Port number (1-65535). Either port or namedPort must be specified,
+	// except for ICMP rules, which match on icmp instead.
 	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:validation:Maximum=65535
 	// +optional
 	Port int32 `json:"port,omitempty"`
 
+	// EndPort turns port into the inclusive range port-endPort (e.g., 30000-32767).
+	// Requires a numeric port.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	EndPort int32 `json:"endPort,omitempty"`
+
 	// NamedPort is the port name (e.g., "http", "grpc") as an alternative to numeric port
 	// +optional
 	NamedPort string `json:"namedPort,omitempty"`
 
-	// Protocol (TCP/UDP)
-	// +kubebuilder:validation:Enum=TCP;UDP
+	// Protocol (TCP/UDP/SCTP/ICMP/ICMPv6). ICMP is supported by the cilium,
+	// calico and antrea engines, ICMPv6 only by cilium and calico.
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP;ICMP;ICMPv6
 	Protocol string `json:"protocol"`
 
+	// ICMP selects the ICMP message type and code. ICMP and ICMPv6 rules
+	// require icmp, notICMP or both.
+	// +optional
+	ICMP *ICMPMatch `json:"icmp,omitempty"`
+
+	// NotICMP excludes an ICMP message type and code from the rule, e.g. to
+	// match every ICMP message but redirects. Only the calico engine can
+	// render it.
+	// +optional
+	NotICMP *ICMPMatch `json:"notICMP,omitempty"`
+
 	// Direction of the traffic (ingress/egress)
 	// +kubebuilder:validation:Enum=ingress;egress
 	Direction string `json:"direction"`
+
+	// From restricts an ingress rule to traffic from these peers.
+	// If empty, the rule matches traffic from every address.
+	// +kubebuilder:validation:MaxItems=32
+	// +optional
+	From []RulePeer `json:"from,omitempty"`
+
+	// To restricts an egress rule to traffic to these peers.
+	// If empty, the rule matches traffic to every address.
+	// +kubebuilder:validation:MaxItems=32
+	// +optional
+	To []RulePeer `json:"to,omitempty"`
+
+	// HTTP restricts the rule to matching L7 HTTP requests on the port.
+	// Only the cilium engine can enforce L7 rules; other engines reject them.
+	// +kubebuilder:validation:MaxItems=64
+	// +optional
+	HTTP []HTTPRule `json:"http,omitempty"`
+}
+
+// RulePeer selects the pods or IP range a global rule applies to.
+// Namespaces or namespaceSelector select every pod in the matching namespaces,
+// and podSelector narrows them down; podSelector alone selects pods in the
+// namespace of the generated policy. CIDR cannot be combined with the others.
+// +kubebuilder:validation:XValidation:rule="has(self.cidr) != (has(self.namespaces) || has(self.namespaceSelector) || has(self.podSelector))",message="a peer sets either cidr or at least one of namespaces, namespaceSelector and podSelector"
+// +kubebuilder:validation:XValidation:rule="!has(self.namespaces) || !has(self.namespaceSelector)",message="namespaces and namespaceSelector are mutually exclusive"
+type RulePeer struct {
+	// Namespaces lists the namespaces by name
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=64
+	// +kubebuilder:validation:items:MaxLength=63
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects namespaces by their labels
+	// +kubebuilder:validation:MinProperties=1
+	// +optional
+	NamespaceSelector map[string]string `json:"namespaceSelector,omitempty"`
+
+	// PodSelector selects pods by their labels
+	// +kubebuilder:validation:MinProperties=1
+	// +optional
+	PodSelector map[string]string `json:"podSelector,omitempty"`
+
+	// CIDR is an IP range (e.g., "10.0.0.0/8")
+	// +optional
+	CIDR string `json:"cidr,omitempty"`
+}
+
+// ICMPMatch matches ICMP or ICMPv6 messages by type and optional code
+type ICMPMatch struct {
+	// Type is the ICMP type (e.g., 8 for IPv4 echo request, 128 for ICMPv6 echo request)
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	Type int32 `json:"type"`
+
+	// Code is the ICMP code. If omitted, every code of the type matches.
+	// The cilium engine cannot match on code.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=255
+	// +optional
+	Code *int32 `json:"code,omitempty"`
+}
+
+// HTTPRule matches L7 HTTP requests. All set fields must match; an empty rule matches any request.
+type HTTPRule struct {
+	// Method is an extended POSIX regex matched against the request method (e.g., "GET", "GET|POST")
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// Path is an extended POSIX regex matched against the request path (e.g., "/api/v1/.*")
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Headers lists headers that must be present, as "Name" or "Name: value"
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Headers []string `json:"headers,omitempty"`
 }
 
 // CIDRRule defines a CIDR-based traffic rule for external IP ranges
@@ -143,6 +464,23 @@ type NetworkPolicyGeneratorStatus struct {
 	// Phase represents the current phase of the generator: Learning, Analyzing, or Enforcing
 	Phase string `json:"phase,omitempty"`
 
+	// Conditions report the generator's state in a machine-readable form:
+	// Ready (policies are enforced as specified), Learning, PoliciesApplied,
+	// Degraded (the last reconcile failed) and DryRun
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the metadata.generation the status reflects
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// CurrentRevision is the revision of the policies applied on the last
+	// reconcile
+	// +optional
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+
 	// LastAnalyzed is the timestamp of when traffic was last analyzed
 	LastAnalyzed metav1.Time `json:"lastAnalyzed,omitempty"`
 
@@ -169,6 +507,83 @@ type NetworkPolicyGeneratorStatus struct {
 	// AppliedPoliciesCount is the number of currently applied policies
 	// +optional
 	AppliedPoliciesCount int `json:"appliedPoliciesCount,omitempty"`
+
+	// ResolvedEngine is the engine that policyEngine "auto" resolved to on the last reconcile
+	// +optional
+	ResolvedEngine string `json:"resolvedEngine,omitempty"`
+
+	// MatchedNamespaces lists the namespaces policy.deniedNamespaceSelector
+	// matched at the last reconcile. Allow-type policies are placed in them in
+	// addition to the denied namespaces.
+	// +optional
+	MatchedNamespaces []string `json:"matchedNamespaces,omitempty"`
+
+	// NodeCIDRs lists the node addresses (as host CIDRs) found at the last
+	// reconcile when spec.allowNodeTraffic is set
+	// +optional
+	NodeCIDRs []string `json:"nodeCIDRs,omitempty"`
+
+	// APIServerCIDRs lists the Kubernetes API server addresses (as host CIDRs)
+	// found at the last reconcile when spec.allowAPIServer is set
+	// +optional
+	APIServerCIDRs []string `json:"apiServerCIDRs,omitempty"`
+
+	// APIServerPorts lists the Kubernetes API server ports found alongside
+	// status.apiServerCIDRs
+	// +optional
+	APIServerPorts []int32 `json:"apiServerPorts,omitempty"`
+
+	// AppliedPolicies is the inventory of the objects applied on the last
+	// reconcile. Objects listed here or carrying the generator's owner labels
+	// that a later reconcile no longer generates are deleted.
+	// +listType=atomic
+	// +optional
+	AppliedPolicies []AppliedPolicy `json:"appliedPolicies,omitempty"`
+
+	// EnginePolicies reports the number of currently applied policies per engine
+	// +listType=map
+	// +listMapKey=engine
+	// +optional
+	EnginePolicies []EnginePolicyCount `json:"enginePolicies,omitempty"`
+}
+
+// EnginePolicyCount is the number of policies applied by a single engine
+type EnginePolicyCount struct {
+	// Engine is the policy engine name (e.g., "cilium")
+	Engine string `json:"engine"`
+
+	// Count is the number of policies applied by the engine
+	Count int `json:"count"`
+}
+
+// AppliedPolicy identifies a policy object applied by a generator
+type AppliedPolicy struct {
+	// Engine is the policy engine that generated the object
+	Engine string `json:"engine"`
+
+	// APIVersion is the object's group and version (e.g., "cilium.io/v2")
+	APIVersion string `json:"apiVersion"`
+
+	// Kind is the object's kind (e.g., "CiliumNetworkPolicy")
+	Kind string `json:"kind"`
+
+	// Namespace is the object's namespace, empty for cluster-scoped kinds
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name is the object's name
+	Name string `json:"name"`
+
+	// Generation is the object's metadata.generation after it was last
+	// applied. A live object with another generation has drifted.
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+
+	// ReportedDrift is the drifted state last reported with driftPolicy
+	// report: the live object's resourceVersion, or "Deleted". The same
+	// drift is not reported again.
+	// +optional
+	ReportedDrift string `json:"reportedDrift,omitempty"`
 }
 
 // PolicyDiffEntry represents a single diff entry for policy audit
@@ -179,9 +594,25 @@ type PolicyDiffEntry struct {
 	// Namespace is the namespace of the policy
 	Namespace string `json:"namespace"`
 
+	// Engine is the policy engine that generated the policy
+	// +optional
+	Engine string `json:"engine,omitempty"`
+
 	// Action is the type of change: Created, Updated, Unchanged
 	Action string `json:"action"`
 
+	// Changes summarizes what the apply changed: rules, peers, ports and
+	// other fields that were added, removed or modified. It holds at most
+	// ten lines; further changes are counted in the last one.
+	// +optional
+	Changes []string `json:"changes,omitempty"`
+
+	// Patch is the RFC 6902 JSON patch from the previous object to the
+	// applied one. It is empty for created policies and omitted when longer
+	// than 4096 bytes.
+	// +optional
+	Patch string `json:"patch,omitempty"`
+
 	// Timestamp is when the change was detected
 	Timestamp metav1.Time `json:"timestamp"`
 }
@@ -218,7 +649,9 @@ type TrafficFlow struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Revision",type="integer",JSONPath=".status.currentRevision"
 // +kubebuilder:printcolumn:name="LastAnalyzed",type="string",JSONPath=".status.lastAnalyzed"
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"slices"
 
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -23,31 +26,78 @@ const (
 	directionIngress = "ingress"
 	directionEgress  = "egress"
 
-	protocolTCP = "TCP"
+	protocolTCP    = "TCP"
+	protocolUDP    = "UDP"
+	protocolICMP   = "ICMP"
+	protocolICMPv6 = "ICMPv6"
+
+	engineKubernetes = "kubernetes"
+	engineCilium     = "cilium"
+	engineCalico     = "calico"
+	engineAntrea     = "antrea"
+	engineAuto       = "auto"
+
+	ipFamilyIPv4 = "IPv4"
+	ipFamilyIPv6 = "IPv6"
+
+	dnsModeAuto   = "auto"
+	dnsModeNone   = "none"
+	dnsModeCustom = "custom"
+
+	antreaActionDrop   = "Drop"
+	antreaActionReject = "Reject"
 )
 
-// SetupWebhookWithManager sets up the webhook with the Manager.
-func (r *NetworkPolicyGenerator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+// SetupWebhookWithManager sets up the webhook with the Manager. pluginEngines
+// are the names of the external engine plugins the operator loaded; any other
+// engine name outside the built-in set is rejected.
+func (r *NetworkPolicyGenerator) SetupWebhookWithManager(mgr ctrl.Manager, pluginEngines ...string) error {
 	return ctrl.NewWebhookManagedBy(mgr, r).
-		WithValidator(&networkPolicyGeneratorValidator{}).
+		WithDefaulter(&networkPolicyGeneratorDefaulter{}).
+		WithValidator(&networkPolicyGeneratorValidator{pluginEngines: pluginEngines}).
 		Complete()
 }
 
+// +kubebuilder:webhook:path=/mutate-security-policy-io-v1-networkpolicygenerator,mutating=true,failurePolicy=fail,sideEffects=None,groups=security.policy.io,resources=networkpolicygenerators,verbs=create,versions=v1,name=mnetworkpolicygenerator.kb.io,admissionReviewVersions=v1
+
+// networkPolicyGeneratorDefaulter implements admission.Defaulter[*NetworkPolicyGenerator]
+type networkPolicyGeneratorDefaulter struct{}
+
+var _ admission.Defaulter[*NetworkPolicyGenerator] = &networkPolicyGeneratorDefaulter{}
+
+// Default implements admission.Defaulter. It only defaults new generators:
+// a CRD schema default would also apply to stored generators without the
+// field on read and silently open their own namespace. The controller records
+// the same defaults on a generator's first reconcile, for installs without
+// webhooks.
+func (d *networkPolicyGeneratorDefaulter) Default(ctx context.Context, gen *NetworkPolicyGenerator) error {
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation != admissionv1.Create {
+		return nil
+	}
+	if gen.Spec.Policy.AllowSameNamespace == nil {
+		allow := true
+		gen.Spec.Policy.AllowSameNamespace = &allow
+	}
+	return nil
+}
+
 // +kubebuilder:webhook:path=/validate-security-policy-io-v1-networkpolicygenerator,mutating=false,failurePolicy=fail,sideEffects=None,groups=security.policy.io,resources=networkpolicygenerators,verbs=create;update,versions=v1,name=vnetworkpolicygenerator.kb.io,admissionReviewVersions=v1
 
 // networkPolicyGeneratorValidator implements admission.Validator[*NetworkPolicyGenerator]
-type networkPolicyGeneratorValidator struct{}
+type networkPolicyGeneratorValidator struct {
+	pluginEngines []string
+}
 
 var _ admission.Validator[*NetworkPolicyGenerator] = &networkPolicyGeneratorValidator{}
 
 // ValidateCreate implements admission.Validator
 func (v *networkPolicyGeneratorValidator) ValidateCreate(_ context.Context, gen *NetworkPolicyGenerator) (admission.Warnings, error) {
-	return validateGenerator(gen)
+	return validateGenerator(gen, v.pluginEngines...)
 }
 
 // ValidateUpdate implements admission.Validator
 func (v *networkPolicyGeneratorValidator) ValidateUpdate(_ context.Context, _ *NetworkPolicyGenerator, newGen *NetworkPolicyGenerator) (admission.Warnings, error) {
-	return validateGenerator(newGen)
+	return validateGenerator(newGen, v.pluginEngines...)
 }
 
 // ValidateDelete implements admission.Validator
@@ -60,7 +110,7 @@ func (v *networkPolicyGeneratorValidator) ValidateDelete(_ context.Context, _ *N
 // helper so this entry point stays flat and every rule is testable on its own.
 // Any validation error discards the warnings, so warnings are only ever
 // computed for a spec that already passed.
-func validateGenerator(gen *NetworkPolicyGenerator) (admission.Warnings, error) {
+func validateGenerator(gen *NetworkPolicyGenerator, pluginEngines ...string) (admission.Warnings, error) {
 	spec := &gen.Spec
 
 	if err := validateMode(spec); err != nil {
@@ -69,18 +119,36 @@ func validateGenerator(gen *NetworkPolicyGenerator) (admission.Warnings, error)
 	if err := validatePolicyType(spec); err != nil {
 		return nil, err
 	}
-	if err := validatePolicyEngine(spec); err != nil {
+	if err := validatePolicyEngine(spec, pluginEngines); err != nil {
+		return nil, err
+	}
+	if err := validateAntrea(spec); err != nil {
 		return nil, err
 	}
 	if err := validateNamespaceOverlap(spec); err != nil {
 		return nil, err
 	}
-	if err := validateGlobalRules(spec); err != nil {
+	if err := validateLabelSelectors(spec); err != nil {
+		return nil, err
+	}
+	if err := validateGlobalRules(spec, pluginEngines); err != nil {
+		return nil, err
+	}
+	if err := validateIPFamilies(spec); err != nil {
+		return nil, err
+	}
+	if err := validatePolicyTypes(spec); err != nil {
 		return nil, err
 	}
 	if err := validateCIDRRules(spec); err != nil {
 		return nil, err
 	}
+	if err := validateFQDNRules(spec); err != nil {
+		return nil, err
+	}
+	if err := validateDNS(spec); err != nil {
+		return nil, err
+	}
 
 	return specWarnings(spec), nil
 }
@@ -104,15 +172,73 @@ func validatePolicyType(spec *NetworkPolicyGeneratorSpec) error {
 	return nil
 }
 
-// validatePolicyEngine checks the optional policy engine enum. An empty value
-// is allowed and means the default engine.
-func validatePolicyEngine(spec *NetworkPolicyGeneratorSpec) error {
-	switch spec.PolicyEngine {
-	case "", "kubernetes", "cilium", "calico":
+// validatePolicyEngine checks the optional policy engine and every entry of
+// policyEngines against the built-in engines and the loaded plugins. An empty
+// policyEngine is allowed and means the default engine.
+func validatePolicyEngine(spec *NetworkPolicyGeneratorSpec, pluginEngines []string) error {
+	if spec.PolicyEngine != "" && spec.PolicyEngine != engineAuto && !isKnownEngine(spec.PolicyEngine, pluginEngines) {
+		return fmt.Errorf("spec.policyEngine must be 'kubernetes', 'cilium', 'calico', 'antrea', 'auto', or a configured plugin engine, got %q", spec.PolicyEngine)
+	}
+	seen := make(map[string]bool, len(spec.PolicyEngines))
+	for i, engine := range spec.PolicyEngines {
+		if !isKnownEngine(engine, pluginEngines) {
+			return fmt.Errorf("spec.policyEngines[%d] must be 'kubernetes', 'cilium', 'calico', 'antrea', or a configured plugin engine, got %q", i, engine)
+		}
+		if seen[engine] {
+			return fmt.Errorf("spec.policyEngines[%d]: duplicate engine %q", i, engine)
+		}
+		seen[engine] = true
+	}
+	return nil
+}
+
+// isKnownEngine reports whether name is a built-in policy engine or one of
+// the loaded plugin engines.
+func isKnownEngine(name string, pluginEngines []string) bool {
+	switch name {
+	case engineKubernetes, engineCilium, engineCalico, engineAntrea:
+		return true
+	}
+	return slices.Contains(pluginEngines, name)
+}
+
+// specEngines returns the engines the controller generates policies for:
+// policyEngines when set, otherwise policyEngine (empty meaning kubernetes).
+// "auto" is returned as is, since it is only resolved at reconcile time.
+func specEngines(spec *NetworkPolicyGeneratorSpec) []string {
+	if len(spec.PolicyEngines) > 0 {
+		return spec.PolicyEngines
+	}
+	if spec.PolicyEngine == "" {
+		return []string{engineKubernetes}
+	}
+	return []string{spec.PolicyEngine}
+}
+
+// usesEngine reports whether the spec generates policies for the named engine.
+func usesEngine(spec *NetworkPolicyGeneratorSpec, name string) bool {
+	for _, engine := range specEngines(spec) {
+		if engine == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAntrea checks the optional Antrea settings block.
+func validateAntrea(spec *NetworkPolicyGeneratorSpec) error {
+	if spec.Antrea == nil {
 		return nil
+	}
+	switch spec.Antrea.DefaultAction {
+	case "", antreaActionDrop, antreaActionReject:
 	default:
-		return fmt.Errorf("spec.policyEngine must be 'kubernetes', 'cilium', or 'calico', got %q", spec.PolicyEngine)
+		return fmt.Errorf("spec.antrea.defaultAction must be 'Drop' or 'Reject', got %q", spec.Antrea.DefaultAction)
+	}
+	if spec.Antrea.Priority < 0 || spec.Antrea.Priority > 10000 {
+		return fmt.Errorf("spec.antrea.priority must be between 1 and 10000, got %d", spec.Antrea.Priority)
 	}
+	return nil
 }
 
 // validateNamespaceOverlap rejects a namespace listed as both allowed and
@@ -133,29 +259,283 @@ func validateNamespaceOverlap(spec *NetworkPolicyGeneratorSpec) error {
 	return nil
 }
 
-// validateGlobalRules requires exactly one of port / namedPort per rule.
-func validateGlobalRules(spec *NetworkPolicyGeneratorSpec) error {
+// validateLabelSelectors rejects podSelector combined with podLabelSelector,
+// and pod or namespace label selectors that do not convert to a label
+// selector, such as an unknown operator or an In requirement without values.
+func validateLabelSelectors(spec *NetworkPolicyGeneratorSpec) error {
+	if len(spec.Policy.PodSelector) > 0 && spec.Policy.PodLabelSelector != nil {
+		return fmt.Errorf("spec.policy.podSelector and spec.policy.podLabelSelector are mutually exclusive")
+	}
+	selectors := []struct {
+		field    string
+		selector *metav1.LabelSelector
+	}{
+		{"podLabelSelector", spec.Policy.PodLabelSelector},
+		{"allowedNamespaceSelector", spec.Policy.AllowedNamespaceSelector},
+		{"deniedNamespaceSelector", spec.Policy.DeniedNamespaceSelector},
+	}
+	for _, s := range selectors {
+		if s.selector == nil {
+			continue
+		}
+		if _, err := metav1.LabelSelectorAsSelector(s.selector); err != nil {
+			return fmt.Errorf("spec.policy.%s is invalid: %w", s.field, err)
+		}
+	}
+	return nil
+}
+
+// validateGlobalRules requires exactly one of port / namedPort per rule,
+// rejects deny rules for the kubernetes engine, and only accepts L7 http
+// matches on allow TCP rules of the cilium engine. Plugin engines receive the
+// rules as is and decide for themselves.
+func validateGlobalRules(spec *NetworkPolicyGeneratorSpec, pluginEngines []string) error {
 	for i, rule := range spec.GlobalRules {
-		if rule.Port == 0 && rule.NamedPort == "" {
-			return fmt.Errorf("spec.globalRules[%d]: either port or namedPort must be specified", i)
+		if isICMP(rule.Protocol) {
+			if err := validateICMPRule(spec, rule); err != nil {
+				return fmt.Errorf("spec.globalRules[%d]: %v", i, err)
+			}
+		} else {
+			if rule.Port == 0 && rule.NamedPort == "" {
+				return fmt.Errorf("spec.globalRules[%d]: either port or namedPort must be specified", i)
+			}
+			if rule.Port != 0 && rule.NamedPort != "" {
+				return fmt.Errorf("spec.globalRules[%d]: port and namedPort are mutually exclusive", i)
+			}
+			if rule.ICMP != nil {
+				return fmt.Errorf("spec.globalRules[%d].icmp: requires protocol ICMP or ICMPv6, got %q", i, rule.Protocol)
+			}
+			if rule.NotICMP != nil {
+				return fmt.Errorf("spec.globalRules[%d].notICMP: requires protocol ICMP or ICMPv6, got %q", i, rule.Protocol)
+			}
+		}
+		if err := validateEndPort(rule); err != nil {
+			return fmt.Errorf("spec.globalRules[%d].endPort: %v", i, err)
+		}
+		if err := validateRulePeers(rule); err != nil {
+			return fmt.Errorf("spec.globalRules[%d].%v", i, err)
+		}
+		if rule.Type == policyTypeDeny && usesEngine(spec, engineKubernetes) {
+			return fmt.Errorf("spec.globalRules[%d]: deny rules are not supported by the 'kubernetes' policy engine, which can only allow traffic; use 'cilium', 'calico', or 'antrea'", i)
+		}
+		if len(rule.HTTP) == 0 {
+			continue
+		}
+		if rule.Type == policyTypeDeny {
+			return fmt.Errorf("spec.globalRules[%d].http: L7 rules cannot be combined with type deny", i)
+		}
+		if rule.EndPort != 0 {
+			return fmt.Errorf("spec.globalRules[%d].http: L7 rules cannot be combined with endPort", i)
 		}
-		if rule.Port != 0 && rule.NamedPort != "" {
-			return fmt.Errorf("spec.globalRules[%d]: port and namedPort are mutually exclusive", i)
+		for _, engine := range specEngines(spec) {
+			if engine != engineCilium && engine != engineAuto && !slices.Contains(pluginEngines, engine) {
+				return fmt.Errorf("spec.globalRules[%d].http: L7 rules are only supported by the 'cilium' policy engine, got %q", i, engine)
+			}
+		}
+		if rule.Protocol != protocolTCP {
+			return fmt.Errorf("spec.globalRules[%d].http: L7 rules require protocol TCP, got %q", i, rule.Protocol)
+		}
+	}
+	return nil
+}
+
+// isICMP reports whether a global rule protocol matches ICMP messages instead
+// of ports.
+func isICMP(protocol string) bool {
+	return protocol == protocolICMP || protocol == protocolICMPv6
+}
+
+// validateICMPRule checks an ICMP or ICMPv6 global rule: it matches on icmp
+// and/or notICMP instead of a port. The kubernetes engine cannot render ICMP,
+// antrea only IPv4 ICMP without exclusions, and cilium neither codes nor
+// exclusions.
+func validateICMPRule(spec *NetworkPolicyGeneratorSpec, rule GlobalRule) error {
+	if rule.Port != 0 || rule.NamedPort != "" {
+		return fmt.Errorf("%s rules cannot specify port or namedPort", rule.Protocol)
+	}
+	if rule.ICMP == nil && rule.NotICMP == nil {
+		return fmt.Errorf("%s rules require icmp or notICMP", rule.Protocol)
+	}
+	if err := validateICMPMatch("icmp", rule.ICMP); err != nil {
+		return err
+	}
+	if err := validateICMPMatch("notICMP", rule.NotICMP); err != nil {
+		return err
+	}
+	if usesEngine(spec, engineKubernetes) {
+		return fmt.Errorf("%s rules are not supported by the 'kubernetes' policy engine; use 'cilium', 'calico' or 'antrea'", rule.Protocol)
+	}
+	if rule.Protocol == protocolICMPv6 && usesEngine(spec, engineAntrea) {
+		return fmt.Errorf("ICMPv6 rules are not supported by the 'antrea' policy engine, whose icmp protocol match cannot select ICMPv6; use 'cilium' or 'calico'")
+	}
+	if rule.NotICMP != nil {
+		for _, engine := range []string{engineCilium, engineAntrea} {
+			if usesEngine(spec, engine) {
+				return fmt.Errorf("notICMP is not supported by the '%s' policy engine, which cannot exclude ICMP types; use 'calico'", engine)
+			}
 		}
 	}
+	if rule.ICMP != nil && rule.ICMP.Code != nil && usesEngine(spec, engineCilium) {
+		return fmt.Errorf("icmp.code is not supported by the 'cilium' policy engine, which only matches on icmp.type")
+	}
 	return nil
 }
 
+// validateICMPMatch range-checks the type and code of an icmp or notICMP match
+func validateICMPMatch(field string, match *ICMPMatch) error {
+	if match == nil {
+		return nil
+	}
+	if match.Type < 0 || match.Type > 255 {
+		return fmt.Errorf("%s.type %d is out of valid range (0-255)", field, match.Type)
+	}
+	if match.Code != nil && (*match.Code < 0 || *match.Code > 255) {
+		return fmt.Errorf("%s.code %d is out of valid range (0-255)", field, *match.Code)
+	}
+	return nil
+}
+
+// validateEndPort checks that a port range has a numeric start and does not
+// end before it starts.
+func validateEndPort(rule GlobalRule) error {
+	if rule.EndPort == 0 {
+		return nil
+	}
+	if rule.EndPort < 1 || rule.EndPort > 65535 {
+		return fmt.Errorf("%d is out of valid range (1-65535)", rule.EndPort)
+	}
+	if rule.Port == 0 {
+		return fmt.Errorf("requires a numeric port")
+	}
+	if rule.EndPort < rule.Port {
+		return fmt.Errorf("%d must be greater than or equal to port %d", rule.EndPort, rule.Port)
+	}
+	return nil
+}
+
+// validateRulePeers checks the from/to peers of a global rule: from only on
+// ingress rules, to only on egress rules, and every peer selects either pods
+// or a valid CIDR. The returned error is prefixed with the offending field.
+func validateRulePeers(rule GlobalRule) error {
+	if len(rule.From) > 0 && rule.Direction != directionIngress {
+		return fmt.Errorf("from: requires direction ingress")
+	}
+	if len(rule.To) > 0 && rule.Direction != directionEgress {
+		return fmt.Errorf("to: requires direction egress")
+	}
+	field, peers := "from", rule.From
+	if rule.Direction == directionEgress {
+		field, peers = "to", rule.To
+	}
+	return validatePeers(field, peers)
+}
+
+// validatePeers checks that every peer selects either pods or a valid CIDR.
+// The returned error is prefixed with the field holding the peers.
+func validatePeers(field string, peers []RulePeer) error {
+	for j, peer := range peers {
+		selectsPods := len(peer.Namespaces) > 0 || len(peer.NamespaceSelector) > 0 || len(peer.PodSelector) > 0
+		if (peer.CIDR != "") == selectsPods {
+			return fmt.Errorf("%s[%d]: set either cidr or at least one of namespaces, namespaceSelector and podSelector", field, j)
+		}
+		if len(peer.Namespaces) > 0 && len(peer.NamespaceSelector) > 0 {
+			return fmt.Errorf("%s[%d]: namespaces and namespaceSelector are mutually exclusive", field, j)
+		}
+		if peer.CIDR != "" {
+			if _, _, err := net.ParseCIDR(peer.CIDR); err != nil {
+				return fmt.Errorf("%s[%d].cidr: invalid CIDR %q: %v", field, j, peer.CIDR, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateDNS checks spec.dns: a known mode, resolvers listed in custom mode
+// only, and UDP and/or TCP as protocols.
+func validateDNS(spec *NetworkPolicyGeneratorSpec) error {
+	dns := spec.DNS
+	if dns == nil {
+		return nil
+	}
+	switch dns.Mode {
+	case "", dnsModeAuto, dnsModeNone, dnsModeCustom:
+	default:
+		return fmt.Errorf("spec.dns.mode must be 'auto', 'none' or 'custom', got %q", dns.Mode)
+	}
+	if (dns.Mode == dnsModeCustom) != (len(dns.To) > 0) {
+		return fmt.Errorf("spec.dns.to must be set when spec.dns.mode is 'custom', and only then")
+	}
+	if err := validatePeers("to", dns.To); err != nil {
+		return fmt.Errorf("spec.dns.%v", err)
+	}
+	for i, protocol := range dns.Protocols {
+		if protocol != protocolUDP && protocol != protocolTCP {
+			return fmt.Errorf("spec.dns.protocols[%d] must be 'UDP' or 'TCP', got %q", i, protocol)
+		}
+		if slices.Contains(dns.Protocols[:i], protocol) {
+			return fmt.Errorf("spec.dns.protocols[%d]: duplicate protocol %q", i, protocol)
+		}
+	}
+	return nil
+}
+
+// validateIPFamilies checks that spec.ipFamilies lists IPv4 and/or IPv6 once each.
+func validateIPFamilies(spec *NetworkPolicyGeneratorSpec) error {
+	for i, family := range spec.IPFamilies {
+		if family != ipFamilyIPv4 && family != ipFamilyIPv6 {
+			return fmt.Errorf("spec.ipFamilies[%d] must be 'IPv4' or 'IPv6', got %q", i, family)
+		}
+		if slices.Contains(spec.IPFamilies[:i], family) {
+			return fmt.Errorf("spec.ipFamilies[%d]: duplicate IP family %q", i, family)
+		}
+	}
+	return nil
+}
+
+// validatePolicyTypes requires spec.policyTypes entries to be ingress or
+// egress, each listed once.
+func validatePolicyTypes(spec *NetworkPolicyGeneratorSpec) error {
+	for i, direction := range spec.PolicyTypes {
+		if direction != directionIngress && direction != directionEgress {
+			return fmt.Errorf("spec.policyTypes[%d] must be 'ingress' or 'egress', got %q", i, direction)
+		}
+		if slices.Contains(spec.PolicyTypes[:i], direction) {
+			return fmt.Errorf("spec.policyTypes[%d]: duplicate policy type %q", i, direction)
+		}
+	}
+	return nil
+}
+
+// cidrFamily returns the IP family of a parsed CIDR.
+func cidrFamily(ipNet *net.IPNet) string {
+	if ipNet.IP.To4() != nil {
+		return ipFamilyIPv4
+	}
+	return ipFamilyIPv6
+}
+
 // validateCIDRRules checks each CIDR, its exceptions, and the direction enum.
+// Every exception must be of the same IP family as its CIDR and lie within it.
 func validateCIDRRules(spec *NetworkPolicyGeneratorSpec) error {
 	for i, rule := range spec.CIDRRules {
-		if _, _, err := net.ParseCIDR(rule.CIDR); err != nil {
+		_, ipNet, err := net.ParseCIDR(rule.CIDR)
+		if err != nil {
 			return fmt.Errorf("spec.cidrRules[%d]: invalid CIDR %q: %v", i, rule.CIDR, err)
 		}
 		for j, except := range rule.Except {
-			if _, _, err := net.ParseCIDR(except); err != nil {
+			_, exceptNet, err := net.ParseCIDR(except)
+			if err != nil {
 				return fmt.Errorf("spec.cidrRules[%d].except[%d]: invalid CIDR %q: %v", i, j, except, err)
 			}
+			if cidrFamily(exceptNet) != cidrFamily(ipNet) {
+				return fmt.Errorf("spec.cidrRules[%d].except[%d]: %s is %s but cidr %s is %s",
+					i, j, except, cidrFamily(exceptNet), rule.CIDR, cidrFamily(ipNet))
+			}
+			ones, _ := ipNet.Mask.Size()
+			exceptOnes, _ := exceptNet.Mask.Size()
+			if !ipNet.Contains(exceptNet.IP) || exceptOnes < ones {
+				return fmt.Errorf("spec.cidrRules[%d].except[%d]: %s is not within cidr %s", i, j, except, rule.CIDR)
+			}
 		}
 		if rule.Direction != directionIngress && rule.Direction != directionEgress {
 			return fmt.Errorf("spec.cidrRules[%d]: direction must be 'ingress' or 'egress'", i)
@@ -164,17 +544,106 @@ func validateCIDRRules(spec *NetworkPolicyGeneratorSpec) error {
 	return nil
 }
 
+// validateFQDNRules rejects FQDN rules for the kubernetes engine, which can
+// only match IP ranges, and requires exactly one of matchName / matchPattern.
+func validateFQDNRules(spec *NetworkPolicyGeneratorSpec) error {
+	if len(spec.FQDNRules) == 0 {
+		return nil
+	}
+	if usesEngine(spec, engineKubernetes) {
+		return fmt.Errorf("spec.fqdnRules is not supported by the 'kubernetes' policy engine; use 'cilium', 'calico', or 'antrea'")
+	}
+	for i, rule := range spec.FQDNRules {
+		if (rule.MatchName == "") == (rule.MatchPattern == "") {
+			return fmt.Errorf("spec.fqdnRules[%d]: exactly one of matchName or matchPattern must be specified", i)
+		}
+	}
+	return nil
+}
+
+// hasHTTPRules reports whether any global rule carries L7 http matches.
+func hasHTTPRules(spec *NetworkPolicyGeneratorSpec) bool {
+	for _, rule := range spec.GlobalRules {
+		if len(rule.HTTP) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDenyGlobalRules reports whether any global rule has type deny.
+func hasDenyGlobalRules(spec *NetworkPolicyGeneratorSpec) bool {
+	for _, rule := range spec.GlobalRules {
+		if rule.Type == policyTypeDeny {
+			return true
+		}
+	}
+	return false
+}
+
+// hasICMPGlobalRules reports whether any global rule matches ICMP or ICMPv6.
+func hasICMPGlobalRules(spec *NetworkPolicyGeneratorSpec) bool {
+	for _, rule := range spec.GlobalRules {
+		if isICMP(rule.Protocol) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDirectionRules reports whether any global, CIDR or FQDN rule targets the
+// direction. FQDN rules are egress only.
+func hasDirectionRules(spec *NetworkPolicyGeneratorSpec, direction string) bool {
+	for _, rule := range spec.GlobalRules {
+		if rule.Direction == direction {
+			return true
+		}
+	}
+	for _, rule := range spec.CIDRRules {
+		if rule.Direction == direction {
+			return true
+		}
+	}
+	return direction == directionEgress && len(spec.FQDNRules) > 0
+}
+
 // specWarnings collects the non-fatal advisories for an already-valid spec.
 func specWarnings(spec *NetworkPolicyGeneratorSpec) admission.Warnings {
 	var warnings admission.Warnings
-	if spec.Policy.Type == policyTypeDeny && len(spec.Policy.AllowedNamespaces) == 0 {
+	if spec.Policy.Type == policyTypeDeny && len(spec.Policy.AllowedNamespaces) == 0 && spec.Policy.AllowedNamespaceSelector == nil {
 		warnings = append(warnings, "spec.policy.type is 'deny' but no allowedNamespaces specified")
 	}
-	if spec.Policy.Type == policyTypeAllow && len(spec.Policy.DeniedNamespaces) == 0 {
+	if spec.Policy.Type == policyTypeAllow && len(spec.Policy.DeniedNamespaces) == 0 && spec.Policy.DeniedNamespaceSelector == nil {
 		warnings = append(warnings, "spec.policy.type is 'allow' but no deniedNamespaces specified")
 	}
+	if spec.Policy.Type == policyTypeDeny && spec.Policy.AllowSameNamespace != nil && !*spec.Policy.AllowSameNamespace {
+		warnings = append(warnings, "spec.policy.allowSameNamespace is false: pods in the generator's namespace cannot reach each other unless it is listed in allowedNamespaces")
+	}
+	for _, direction := range []string{directionIngress, directionEgress} {
+		if len(spec.PolicyTypes) > 0 && !slices.Contains(spec.PolicyTypes, direction) && hasDirectionRules(spec, direction) {
+			warnings = append(warnings, fmt.Sprintf("spec.policyTypes does not include '%s': rules with direction %s are ignored", direction, direction))
+		}
+	}
+	if spec.AllowNodeTraffic && len(spec.PolicyTypes) > 0 && !slices.Contains(spec.PolicyTypes, directionIngress) {
+		warnings = append(warnings, "spec.allowNodeTraffic is set but spec.policyTypes does not include 'ingress': node traffic is already unrestricted")
+	}
+	if spec.AllowAPIServer && len(spec.PolicyTypes) > 0 && !slices.Contains(spec.PolicyTypes, directionEgress) {
+		warnings = append(warnings, "spec.allowAPIServer is set but spec.policyTypes does not include 'egress': API server traffic is already unrestricted")
+	}
+	if spec.DNS != nil && spec.DNS.Mode == dnsModeNone && (len(spec.PolicyTypes) == 0 || slices.Contains(spec.PolicyTypes, directionEgress)) {
+		warnings = append(warnings, "spec.dns.mode is 'none': no DNS egress rule is generated, so name resolution fails unless another egress rule allows it")
+	}
 	if spec.DryRun {
 		warnings = append(warnings, "dry-run mode is enabled: policies will not be applied to the cluster")
 	}
+	if spec.Antrea != nil && !usesEngine(spec, engineAntrea) && !usesEngine(spec, engineAuto) {
+		warnings = append(warnings, "spec.antrea is set but the 'antrea' policy engine is not selected: the settings are ignored")
+	}
+	if usesEngine(spec, engineAuto) && (len(spec.FQDNRules) > 0 || hasHTTPRules(spec) || hasDenyGlobalRules(spec) || hasICMPGlobalRules(spec)) {
+		warnings = append(warnings, "spec.policyEngine is 'auto': http, fqdn, deny and ICMP global rules fail generation if the resolved engine cannot express them")
+	}
+	if len(spec.PolicyEngines) > 0 && spec.PolicyEngine != "" && spec.PolicyEngine != engineKubernetes {
+		warnings = append(warnings, "spec.policyEngines is set: spec.policyEngine is ignored")
+	}
 	return warnings
 }
@@ -5,7 +5,9 @@ import (
 	"testing"
 	"time"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 func TestValidateGenerator_ValidEnforcing(t *testing.T) {
@@ -152,6 +154,185 @@ func TestValidateGenerator_AllowWithoutDenied_Warning(t *testing.T) {
 	}
 }
 
+func TestValidateGenerator_NamespaceSelectors(t *testing.T) {
+	cases := map[string]struct {
+		policy       PolicyConfig
+		wantErr      bool
+		wantWarnings int
+	}{
+		"allow with denied selector": {
+			policy: PolicyConfig{
+				Type:                    policyTypeAllow,
+				DeniedNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}},
+			},
+		},
+		"deny with allowed selector": {
+			policy: PolicyConfig{
+				Type: policyTypeDeny,
+				AllowedNamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "team", Operator: metav1.LabelSelectorOpExists},
+				}},
+			},
+		},
+		"unknown operator": {
+			policy: PolicyConfig{
+				Type: policyTypeAllow,
+				DeniedNamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "env", Operator: "Equals", Values: []string{"dev"}},
+				}},
+			},
+			wantErr: true,
+		},
+		"in without values": {
+			policy: PolicyConfig{
+				Type: policyTypeDeny,
+				AllowedNamespaceSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "env", Operator: metav1.LabelSelectorOpIn},
+				}},
+			},
+			wantErr: true,
+		},
+		"selector of the other policy type": {
+			policy: PolicyConfig{
+				Type:                     policyTypeAllow,
+				AllowedNamespaceSelector: &metav1.LabelSelector{},
+			},
+			wantWarnings: 1,
+		},
+	}
+	for name, tc := range cases {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{Mode: modeEnforcing, Policy: tc.policy},
+		}
+		warnings, err := validateGenerator(gen)
+		if tc.wantErr && err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%s: expected no error, got: %v", name, err)
+		}
+		if !tc.wantErr && len(warnings) != tc.wantWarnings {
+			t.Errorf("%s: expected %d warnings, got: %v", name, tc.wantWarnings, warnings)
+		}
+	}
+}
+
+func TestValidateGenerator_PodLabelSelector(t *testing.T) {
+	cases := map[string]struct {
+		policy  PolicyConfig
+		wantErr bool
+	}{
+		"match expressions": {
+			policy: PolicyConfig{
+				Type:              policyTypeDeny,
+				AllowedNamespaces: []string{nsOne},
+				PodLabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"api", "worker"}},
+				}},
+			},
+		},
+		"combined with podSelector": {
+			policy: PolicyConfig{
+				Type:              policyTypeDeny,
+				AllowedNamespaces: []string{nsOne},
+				PodSelector:       map[string]string{"app": "web"},
+				PodLabelSelector:  &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "api"}},
+			},
+			wantErr: true,
+		},
+		"exists with values": {
+			policy: PolicyConfig{
+				Type:              policyTypeDeny,
+				AllowedNamespaces: []string{nsOne},
+				PodLabelSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "batch", Operator: metav1.LabelSelectorOpExists, Values: []string{"true"}},
+				}},
+			},
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{Mode: modeEnforcing, Policy: tc.policy},
+		}
+		_, err := validateGenerator(gen)
+		if tc.wantErr && err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%s: expected no error, got: %v", name, err)
+		}
+	}
+}
+
+func TestValidateGenerator_AllowSameNamespaceDisabled_Warning(t *testing.T) {
+	disabled := false
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode: modeEnforcing,
+			Policy: PolicyConfig{
+				Type:               policyTypeDeny,
+				AllowedNamespaces:  []string{nsOne},
+				AllowSameNamespace: &disabled,
+			},
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got: %v", warnings)
+	}
+
+	// Allow-type generators never deny their own namespace implicitly
+	gen.Spec.Policy = PolicyConfig{Type: policyTypeAllow, DeniedNamespaces: []string{nsOne}, AllowSameNamespace: &disabled}
+	warnings, err = validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got: %v", warnings)
+	}
+}
+
+func TestDefault_AllowSameNamespace(t *testing.T) {
+	defaulter := &networkPolicyGeneratorDefaulter{}
+	request := func(op admissionv1.Operation) context.Context {
+		return admission.NewContextWithRequest(context.Background(), admission.Request{
+			AdmissionRequest: admissionv1.AdmissionRequest{Operation: op},
+		})
+	}
+
+	// A new generator without the field allows its own namespace
+	gen := &NetworkPolicyGenerator{Spec: NetworkPolicyGeneratorSpec{Policy: PolicyConfig{Type: policyTypeDeny}}}
+	if err := defaulter.Default(request(admissionv1.Create), gen); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if gen.Spec.Policy.AllowSameNamespace == nil || !*gen.Spec.Policy.AllowSameNamespace {
+		t.Fatalf("expected allowSameNamespace to default to true on create, got: %v", gen.Spec.Policy.AllowSameNamespace)
+	}
+
+	// An explicit false is kept
+	disabled := false
+	gen.Spec.Policy.AllowSameNamespace = &disabled
+	if err := defaulter.Default(request(admissionv1.Create), gen); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if *gen.Spec.Policy.AllowSameNamespace {
+		t.Fatal("expected an explicit allowSameNamespace: false to be kept")
+	}
+
+	// A generator stored before the field existed stays unset on update
+	existing := &NetworkPolicyGenerator{Spec: NetworkPolicyGeneratorSpec{Policy: PolicyConfig{Type: policyTypeDeny}}}
+	if err := defaulter.Default(request(admissionv1.Update), existing); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if existing.Spec.Policy.AllowSameNamespace != nil {
+		t.Fatalf("expected allowSameNamespace to stay unset on update, got: %v", *existing.Spec.Policy.AllowSameNamespace)
+	}
+}
+
 func TestValidateGenerator_GlobalRulePortAndNamedPort(t *testing.T) {
 	gen := &NetworkPolicyGenerator{
 		Spec: NetworkPolicyGeneratorSpec{
@@ -184,6 +365,470 @@ func TestValidateGenerator_GlobalRuleNoPort(t *testing.T) {
 	}
 }
 
+func TestValidateGenerator_GlobalRuleHTTPWithCilium(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineCilium,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Port: 80, Protocol: protocolTCP, Direction: directionIngress,
+					HTTP: []HTTPRule{{Method: "GET", Path: "/api/.*"}}},
+			},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateGenerator_GlobalRuleHTTPWithOtherEngine(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:   modeEnforcing,
+			Policy: PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Port: 80, Protocol: protocolTCP, Direction: directionIngress,
+					HTTP: []HTTPRule{{Method: "GET"}}},
+			},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err == nil {
+		t.Fatal("expected error for http rules with the kubernetes engine")
+	}
+}
+
+func TestValidateGenerator_GlobalRuleHTTPWithUDP(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineCilium,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Port: 53, Protocol: "UDP", Direction: directionEgress,
+					HTTP: []HTTPRule{{Method: "GET"}}},
+			},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err == nil {
+		t.Fatal("expected error for http rules on a UDP rule")
+	}
+}
+
+func TestValidateGenerator_GlobalRulePortRange(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:   modeEnforcing,
+			Policy: PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Port: 30000, EndPort: 32767, Protocol: protocolTCP, Direction: directionIngress},
+			},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateGenerator_GlobalRuleInvalidPortRange(t *testing.T) {
+	invalid := map[string]GlobalRule{
+		"end before start": {Type: policyTypeAllow, Port: 8080, EndPort: 8000, Protocol: protocolTCP, Direction: directionIngress},
+		"named port":       {Type: policyTypeAllow, NamedPort: "http", EndPort: 8000, Protocol: protocolTCP, Direction: directionIngress},
+		"http rules": {Type: policyTypeAllow, Port: 8080, EndPort: 8090, Protocol: protocolTCP, Direction: directionIngress,
+			HTTP: []HTTPRule{{Method: "GET"}}},
+	}
+	for name, rule := range invalid {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:         modeEnforcing,
+				PolicyEngine: engineCilium,
+				Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				GlobalRules:  []GlobalRule{rule},
+			},
+		}
+		if _, err := validateGenerator(gen); err == nil {
+			t.Errorf("%s: expected error for an invalid port range", name)
+		}
+	}
+}
+
+func TestValidateGenerator_GlobalRulePeers(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:   modeEnforcing,
+			Policy: PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Port: 5432, Protocol: protocolTCP, Direction: directionIngress,
+					From: []RulePeer{
+						{Namespaces: []string{nsTwo}, PodSelector: map[string]string{"app": "api"}},
+						{NamespaceSelector: map[string]string{"tenant": "a"}},
+					}},
+				{Type: policyTypeAllow, Port: 443, Protocol: protocolTCP, Direction: directionEgress,
+					To: []RulePeer{{CIDR: "2001:db8::/32"}}},
+			},
+		},
+	}
+	if _, err := validateGenerator(gen); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateGenerator_InvalidGlobalRulePeers(t *testing.T) {
+	invalid := map[string]GlobalRule{
+		"from on egress": {Type: policyTypeAllow, Port: 443, Protocol: protocolTCP, Direction: directionEgress,
+			From: []RulePeer{{CIDR: "10.0.0.0/8"}}},
+		"to on ingress": {Type: policyTypeAllow, Port: 443, Protocol: protocolTCP, Direction: directionIngress,
+			To: []RulePeer{{CIDR: "10.0.0.0/8"}}},
+		"empty peer": {Type: policyTypeAllow, Port: 443, Protocol: protocolTCP, Direction: directionIngress,
+			From: []RulePeer{{}}},
+		"cidr with selector": {Type: policyTypeAllow, Port: 443, Protocol: protocolTCP, Direction: directionIngress,
+			From: []RulePeer{{CIDR: "10.0.0.0/8", PodSelector: map[string]string{"app": "api"}}}},
+		"namespaces with namespaceSelector": {Type: policyTypeAllow, Port: 443, Protocol: protocolTCP, Direction: directionIngress,
+			From: []RulePeer{{Namespaces: []string{nsOne}, NamespaceSelector: map[string]string{"tenant": "a"}}}},
+		"invalid cidr": {Type: policyTypeAllow, Port: 443, Protocol: protocolTCP, Direction: directionEgress,
+			To: []RulePeer{{CIDR: "10.0.0.0"}}},
+	}
+	for name, rule := range invalid {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:        modeEnforcing,
+				Policy:      PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				GlobalRules: []GlobalRule{rule},
+			},
+		}
+		if _, err := validateGenerator(gen); err == nil {
+			t.Errorf("%s: expected error for invalid peers", name)
+		}
+	}
+}
+
+func TestValidateGenerator_ICMPGlobalRule(t *testing.T) {
+	for _, engine := range []string{engineCilium, engineCalico} {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:         modeEnforcing,
+				PolicyEngine: engine,
+				Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				GlobalRules: []GlobalRule{
+					{Type: policyTypeAllow, Protocol: protocolICMP, Direction: directionIngress, ICMP: &ICMPMatch{Type: 8}},
+					{Type: policyTypeAllow, Protocol: protocolICMPv6, Direction: directionIngress, ICMP: &ICMPMatch{Type: 128}},
+					{Type: policyTypeAllow, Port: 38412, Protocol: "SCTP", Direction: directionIngress},
+				},
+			},
+		}
+		if _, err := validateGenerator(gen); err != nil {
+			t.Fatalf("%s: expected no error, got: %v", engine, err)
+		}
+	}
+
+	code := int32(4)
+	antrea := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineAntrea,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Protocol: protocolICMP, Direction: directionIngress, ICMP: &ICMPMatch{Type: 3, Code: &code}},
+			},
+		},
+	}
+	if _, err := validateGenerator(antrea); err != nil {
+		t.Fatalf("antrea: expected no error, got: %v", err)
+	}
+
+	calico := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineCalico,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Protocol: protocolICMP, Direction: directionIngress, NotICMP: &ICMPMatch{Type: 5}},
+				{Type: policyTypeAllow, Protocol: protocolICMP, Direction: directionEgress, ICMP: &ICMPMatch{Type: 3}, NotICMP: &ICMPMatch{Type: 3, Code: &code}},
+			},
+		},
+	}
+	if _, err := validateGenerator(calico); err != nil {
+		t.Fatalf("calico notICMP: expected no error, got: %v", err)
+	}
+}
+
+func TestValidateGenerator_InvalidICMPGlobalRule(t *testing.T) {
+	code := int32(4)
+	invalid := map[string]struct {
+		engine string
+		rule   GlobalRule
+	}{
+		"kubernetes engine": {"", GlobalRule{Protocol: protocolICMP, ICMP: &ICMPMatch{Type: 8}}},
+		"antrea ICMPv6":     {engineAntrea, GlobalRule{Protocol: protocolICMPv6, ICMP: &ICMPMatch{Type: 128}}},
+		"antrea notICMP":    {engineAntrea, GlobalRule{Protocol: protocolICMP, NotICMP: &ICMPMatch{Type: 5}}},
+		"cilium code":       {engineCilium, GlobalRule{Protocol: protocolICMP, ICMP: &ICMPMatch{Type: 3, Code: &code}}},
+		"cilium notICMP":    {engineCilium, GlobalRule{Protocol: protocolICMP, ICMP: &ICMPMatch{Type: 3}, NotICMP: &ICMPMatch{Type: 5}}},
+		"port":              {engineCalico, GlobalRule{Protocol: protocolICMP, Port: 8, ICMP: &ICMPMatch{Type: 8}}},
+		"missing icmp":      {engineCalico, GlobalRule{Protocol: protocolICMPv6}},
+		"icmp on TCP":       {engineCalico, GlobalRule{Protocol: protocolTCP, Port: 80, ICMP: &ICMPMatch{Type: 8}}},
+		"notICMP on TCP":    {engineCalico, GlobalRule{Protocol: protocolTCP, Port: 80, NotICMP: &ICMPMatch{Type: 8}}},
+		"notICMP type":      {engineCalico, GlobalRule{Protocol: protocolICMP, NotICMP: &ICMPMatch{Type: 256}}},
+	}
+	for name, tc := range invalid {
+		tc.rule.Type = policyTypeAllow
+		tc.rule.Direction = directionIngress
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:         modeEnforcing,
+				PolicyEngine: tc.engine,
+				Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				GlobalRules:  []GlobalRule{tc.rule},
+			},
+		}
+		if _, err := validateGenerator(gen); err == nil {
+			t.Errorf("%s: expected error for an invalid ICMP rule", name)
+		}
+	}
+}
+
+func TestValidateGenerator_IPFamilies(t *testing.T) {
+	valid := [][]string{{ipFamilyIPv4}, {ipFamilyIPv6}, {ipFamilyIPv6, ipFamilyIPv4}}
+	for _, families := range valid {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:       modeEnforcing,
+				Policy:     PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				IPFamilies: families,
+			},
+		}
+		if _, err := validateGenerator(gen); err != nil {
+			t.Errorf("%v: expected no error, got: %v", families, err)
+		}
+	}
+
+	invalid := [][]string{{"ipv4"}, {ipFamilyIPv4, ipFamilyIPv4}}
+	for _, families := range invalid {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:       modeEnforcing,
+				Policy:     PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				IPFamilies: families,
+			},
+		}
+		if _, err := validateGenerator(gen); err == nil {
+			t.Errorf("%v: expected error for invalid ipFamilies", families)
+		}
+	}
+}
+
+func TestValidateGenerator_PolicyTypes(t *testing.T) {
+	cases := map[string]struct {
+		policyTypes  []string
+		globalRules  []GlobalRule
+		fqdnRules    []FQDNRule
+		wantErr      bool
+		wantWarnings int
+	}{
+		"both directions": {policyTypes: []string{directionIngress, directionEgress}},
+		"ingress only":    {policyTypes: []string{directionIngress}},
+		"unknown type":    {policyTypes: []string{"Ingress"}, wantErr: true},
+		"duplicate type":  {policyTypes: []string{directionEgress, directionEgress}, wantErr: true},
+		"unenforced rules": {
+			policyTypes:  []string{directionIngress},
+			globalRules:  []GlobalRule{{Port: 443, Protocol: protocolTCP, Direction: directionEgress}},
+			wantWarnings: 1,
+		},
+		"unenforced fqdn rules": {
+			policyTypes:  []string{directionIngress},
+			fqdnRules:    []FQDNRule{{MatchName: "api.example.com"}},
+			wantWarnings: 1,
+		},
+	}
+	for name, tc := range cases {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:         modeEnforcing,
+				PolicyEngine: engineCilium,
+				PolicyTypes:  tc.policyTypes,
+				Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				GlobalRules:  tc.globalRules,
+				FQDNRules:    tc.fqdnRules,
+			},
+		}
+		warnings, err := validateGenerator(gen)
+		if tc.wantErr && err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%s: expected no error, got: %v", name, err)
+		}
+		if !tc.wantErr && len(warnings) != tc.wantWarnings {
+			t.Errorf("%s: expected %d warnings, got: %v", name, tc.wantWarnings, warnings)
+		}
+	}
+}
+
+func TestValidateGenerator_DNS(t *testing.T) {
+	nodeLocalDNS := []RulePeer{{CIDR: "169.254.20.10/32"}}
+	cases := map[string]struct {
+		dns          *DNSConfig
+		policyTypes  []string
+		wantErr      bool
+		wantWarnings int
+	}{
+		"auto":                      {dns: &DNSConfig{Mode: dnsModeAuto}},
+		"custom cidr":               {dns: &DNSConfig{Mode: dnsModeCustom, To: nodeLocalDNS}},
+		"custom selectors udp only": {dns: &DNSConfig{Mode: dnsModeCustom, To: []RulePeer{{Namespaces: []string{nsOne}, PodSelector: map[string]string{"app": "dns"}}}, Protocols: []string{protocolUDP}}},
+		"none":                      {dns: &DNSConfig{Mode: dnsModeNone}, wantWarnings: 1},
+		"none ingress only":         {dns: &DNSConfig{Mode: dnsModeNone}, policyTypes: []string{directionIngress}},
+		"unknown mode":              {dns: &DNSConfig{Mode: "coredns"}, wantErr: true},
+		"custom without to":         {dns: &DNSConfig{Mode: dnsModeCustom}, wantErr: true},
+		"to without custom":         {dns: &DNSConfig{To: nodeLocalDNS}, wantErr: true},
+		"invalid cidr":              {dns: &DNSConfig{Mode: dnsModeCustom, To: []RulePeer{{CIDR: "169.254.20.10"}}}, wantErr: true},
+		"empty peer":                {dns: &DNSConfig{Mode: dnsModeCustom, To: []RulePeer{{}}}, wantErr: true},
+		"unknown protocol":          {dns: &DNSConfig{Protocols: []string{"SCTP"}}, wantErr: true},
+		"duplicate protocol":        {dns: &DNSConfig{Protocols: []string{protocolTCP, protocolTCP}}, wantErr: true},
+	}
+	for name, tc := range cases {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:         modeEnforcing,
+				PolicyEngine: engineKubernetes,
+				PolicyTypes:  tc.policyTypes,
+				Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				DNS:          tc.dns,
+			},
+		}
+		warnings, err := validateGenerator(gen)
+		if tc.wantErr && err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%s: expected no error, got: %v", name, err)
+		}
+		if !tc.wantErr && len(warnings) != tc.wantWarnings {
+			t.Errorf("%s: expected %d warnings, got: %v", name, tc.wantWarnings, warnings)
+		}
+	}
+}
+
+func TestValidateGenerator_AllowNodeTrafficEgressOnly_Warning(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:             modeEnforcing,
+			PolicyEngine:     engineKubernetes,
+			PolicyTypes:      []string{directionEgress},
+			Policy:           PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			AllowNodeTraffic: true,
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected 1 warning, got: %v", warnings)
+	}
+}
+
+func TestValidateGenerator_AllowAPIServerIngressOnly_Warning(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:           modeEnforcing,
+			PolicyEngine:   engineKubernetes,
+			PolicyTypes:    []string{directionIngress},
+			Policy:         PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			AllowAPIServer: true,
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected 1 warning, got: %v", warnings)
+	}
+}
+
+func TestValidateGenerator_CIDRExceptFamily(t *testing.T) {
+	cases := map[string]struct {
+		rule    CIDRRule
+		wantErr bool
+	}{
+		"IPv6 except within IPv6 cidr": {CIDRRule{CIDR: "2001:db8::/32", Except: []string{"2001:db8:1::/48"}, Direction: directionEgress}, false},
+		"IPv6 except on IPv4 cidr":     {CIDRRule{CIDR: "10.0.0.0/8", Except: []string{"2001:db8::/32"}, Direction: directionEgress}, true},
+		"IPv4 except on IPv6 cidr":     {CIDRRule{CIDR: "2001:db8::/32", Except: []string{"10.0.0.0/8"}, Direction: directionEgress}, true},
+		"except outside cidr":          {CIDRRule{CIDR: "192.168.0.0/24", Except: []string{"192.168.1.0/28"}, Direction: directionEgress}, true},
+		"except wider than cidr":       {CIDRRule{CIDR: "192.168.0.0/24", Except: []string{"192.168.0.0/16"}, Direction: directionEgress}, true},
+	}
+	for name, tc := range cases {
+		gen := &NetworkPolicyGenerator{
+			Spec: NetworkPolicyGeneratorSpec{
+				Mode:      modeEnforcing,
+				Policy:    PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				CIDRRules: []CIDRRule{tc.rule},
+			},
+		}
+		_, err := validateGenerator(gen)
+		if tc.wantErr && err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("%s: expected no error, got: %v", name, err)
+		}
+	}
+}
+
+func TestValidateGenerator_DenyGlobalRuleWithCilium(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineCilium,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeDeny, Port: 22, Protocol: protocolTCP, Direction: directionIngress},
+			},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateGenerator_DenyGlobalRuleWithKubernetes(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:   modeEnforcing,
+			Policy: PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeDeny, Port: 22, Protocol: protocolTCP, Direction: directionIngress},
+			},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err == nil {
+		t.Fatal("expected error for deny global rules with the kubernetes engine")
+	}
+}
+
+func TestValidateGenerator_DenyGlobalRuleWithHTTP(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineCilium,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeDeny, Port: 80, Protocol: protocolTCP, Direction: directionIngress,
+					HTTP: []HTTPRule{{Method: "GET"}}},
+			},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err == nil {
+		t.Fatal("expected error for http rules on a deny rule")
+	}
+}
+
 func TestValidateGenerator_InvalidCIDR(t *testing.T) {
 	gen := &NetworkPolicyGenerator{
 		Spec: NetworkPolicyGeneratorSpec{
@@ -232,6 +877,50 @@ func TestValidateGenerator_InvalidCIDRDirection(t *testing.T) {
 	}
 }
 
+func TestValidateGenerator_FQDNRulesWithCilium(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineCilium,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			FQDNRules:    []FQDNRule{{MatchPattern: "*.example.com", Ports: []FQDNPort{{Port: 443, Protocol: protocolTCP}}}},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestValidateGenerator_FQDNRulesWithKubernetes(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:      modeEnforcing,
+			Policy:    PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			FQDNRules: []FQDNRule{{MatchName: "api.example.com"}},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err == nil {
+		t.Fatal("expected error for fqdnRules with the kubernetes engine")
+	}
+}
+
+func TestValidateGenerator_FQDNRuleNameAndPattern(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineCilium,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			FQDNRules:    []FQDNRule{{MatchName: "api.example.com", MatchPattern: "*.example.com"}},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err == nil {
+		t.Fatal("expected error for matchName and matchPattern both set")
+	}
+}
+
 func TestValidateGenerator_DryRunWarning(t *testing.T) {
 	gen := &NetworkPolicyGenerator{
 		Spec: NetworkPolicyGeneratorSpec{
@@ -286,6 +975,223 @@ func TestValidateGenerator_ValidCalicoEngine(t *testing.T) {
 	}
 }
 
+func TestValidateGenerator_ValidAntreaEngine(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineAntrea,
+			Antrea:       &AntreaConfig{Tier: "securityops", Priority: 10, DefaultAction: antreaActionReject},
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got: %v", warnings)
+	}
+}
+
+func TestValidateGenerator_InvalidAntreaDefaultAction(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineAntrea,
+			Antrea:       &AntreaConfig{DefaultAction: "Pass"},
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err == nil {
+		t.Fatal("expected error for invalid antrea defaultAction")
+	}
+}
+
+func TestValidateGenerator_InvalidAntreaPriority(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineAntrea,
+			Antrea:       &AntreaConfig{Priority: 10001},
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+		},
+	}
+	_, err := validateGenerator(gen)
+	if err == nil {
+		t.Fatal("expected error for out-of-range antrea priority")
+	}
+}
+
+func TestValidateGenerator_AntreaSettingsWithOtherEngine_Warning(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: "calico",
+			Antrea:       &AntreaConfig{Tier: "securityops"},
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got: %v", warnings)
+	}
+}
+
+func TestValidateGenerator_ValidPolicyEngines(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:          modeEnforcing,
+			PolicyEngines: []string{engineCalico, engineCilium},
+			Policy:        PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got: %v", warnings)
+	}
+}
+
+func TestValidateGenerator_InvalidPolicyEngines(t *testing.T) {
+	for name, engines := range map[string][]string{
+		"unknown":   {engineCilium, valueInvalid},
+		"duplicate": {engineCilium, engineCilium},
+	} {
+		t.Run(name, func(t *testing.T) {
+			gen := &NetworkPolicyGenerator{
+				Spec: NetworkPolicyGeneratorSpec{
+					Mode:          modeEnforcing,
+					PolicyEngines: engines,
+					Policy:        PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+				},
+			}
+			if _, err := validateGenerator(gen); err == nil {
+				t.Fatalf("expected error for policyEngines %v", engines)
+			}
+		})
+	}
+}
+
+func TestValidateGenerator_PolicyEnginesOverridePolicyEngine_Warning(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:          modeEnforcing,
+			PolicyEngine:  engineAntrea,
+			PolicyEngines: []string{engineCalico, engineCilium},
+			Policy:        PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got: %v", warnings)
+	}
+}
+
+func TestValidateGenerator_PolicyEnginesRuleSupport(t *testing.T) {
+	httpGen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:          modeEnforcing,
+			PolicyEngines: []string{engineCalico, engineCilium},
+			Policy:        PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Port: 80, Protocol: protocolTCP, Direction: directionIngress,
+					HTTP: []HTTPRule{{Method: "GET"}}},
+			},
+		},
+	}
+	if _, err := validateGenerator(httpGen); err == nil {
+		t.Fatal("expected error for http rules when a listed engine is not cilium")
+	}
+
+	fqdnGen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:          modeEnforcing,
+			PolicyEngines: []string{engineCilium, engineKubernetes},
+			Policy:        PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			FQDNRules:     []FQDNRule{{MatchName: "api.example.com"}},
+		},
+	}
+	if _, err := validateGenerator(fqdnGen); err == nil {
+		t.Fatal("expected error for fqdnRules when kubernetes is a listed engine")
+	}
+}
+
+func TestValidateGenerator_AutoEngine(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineAuto,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			Antrea:       &AntreaConfig{Tier: "application"},
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got: %v", warnings)
+	}
+}
+
+func TestValidateGenerator_AutoEngineWithEngineSpecificRules_Warning(t *testing.T) {
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:         modeEnforcing,
+			PolicyEngine: engineAuto,
+			Policy:       PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Port: 80, Protocol: protocolTCP, Direction: directionIngress,
+					HTTP: []HTTPRule{{Method: "GET"}}},
+			},
+		},
+	}
+	warnings, err := validateGenerator(gen)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got: %v", warnings)
+	}
+}
+
+func TestValidateGenerator_PluginEngine(t *testing.T) {
+	const plugin = "acme"
+	gen := &NetworkPolicyGenerator{
+		Spec: NetworkPolicyGeneratorSpec{
+			Mode:          modeEnforcing,
+			PolicyEngines: []string{engineCilium, plugin},
+			Policy:        PolicyConfig{Type: policyTypeDeny, AllowedNamespaces: []string{nsOne}},
+			GlobalRules: []GlobalRule{
+				{Type: policyTypeAllow, Port: 80, Protocol: protocolTCP, Direction: directionIngress,
+					HTTP: []HTTPRule{{Method: "GET"}}},
+			},
+		},
+	}
+	if _, err := validateGenerator(gen); err == nil {
+		t.Fatal("expected error for an unregistered plugin engine")
+	}
+	if _, err := validateGenerator(gen, plugin); err != nil {
+		t.Fatalf("expected no error for a registered plugin engine, got: %v", err)
+	}
+
+	gen.Spec.PolicyEngines = nil
+	gen.Spec.PolicyEngine = plugin
+	v := &networkPolicyGeneratorValidator{pluginEngines: []string{plugin}}
+	if _, err := v.ValidateCreate(context.Background(), gen); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
 func TestValidatorCreate_Valid(t *testing.T) {
 	v := &networkPolicyGeneratorValidator{}
 	gen := &NetworkPolicyGenerator{
@@ -21,9 +21,40 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AntreaConfig) DeepCopyInto(out *AntreaConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AntreaConfig.
+func (in *AntreaConfig) DeepCopy() *AntreaConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedPolicy) DeepCopyInto(out *AppliedPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedPolicy.
+func (in *AppliedPolicy) DeepCopy() *AppliedPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CIDRRule) DeepCopyInto(out *CIDRRule) {
 	*out = *in
@@ -44,9 +75,117 @@ func (in *CIDRRule) DeepCopy() *CIDRRule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DNSConfig) DeepCopyInto(out *DNSConfig) {
+	*out = *in
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]RulePeer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Protocols != nil {
+		in, out := &in.Protocols, &out.Protocols
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DNSConfig.
+func (in *DNSConfig) DeepCopy() *DNSConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EnginePolicyCount) DeepCopyInto(out *EnginePolicyCount) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EnginePolicyCount.
+func (in *EnginePolicyCount) DeepCopy() *EnginePolicyCount {
+	if in == nil {
+		return nil
+	}
+	out := new(EnginePolicyCount)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FQDNPort) DeepCopyInto(out *FQDNPort) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FQDNPort.
+func (in *FQDNPort) DeepCopy() *FQDNPort {
+	if in == nil {
+		return nil
+	}
+	out := new(FQDNPort)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FQDNRule) DeepCopyInto(out *FQDNRule) {
+	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]FQDNPort, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FQDNRule.
+func (in *FQDNRule) DeepCopy() *FQDNRule {
+	if in == nil {
+		return nil
+	}
+	out := new(FQDNRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GlobalRule) DeepCopyInto(out *GlobalRule) {
 	*out = *in
+	if in.ICMP != nil {
+		in, out := &in.ICMP, &out.ICMP
+		*out = new(ICMPMatch)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NotICMP != nil {
+		in, out := &in.NotICMP, &out.NotICMP
+		*out = new(ICMPMatch)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.From != nil {
+		in, out := &in.From, &out.From
+		*out = make([]RulePeer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]RulePeer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = make([]HTTPRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalRule.
@@ -59,6 +198,46 @@ func (in *GlobalRule) DeepCopy() *GlobalRule {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HTTPRule) DeepCopyInto(out *HTTPRule) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HTTPRule.
+func (in *HTTPRule) DeepCopy() *HTTPRule {
+	if in == nil {
+		return nil
+	}
+	out := new(HTTPRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ICMPMatch) DeepCopyInto(out *ICMPMatch) {
+	*out = *in
+	if in.Code != nil {
+		in, out := &in.Code, &out.Code
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ICMPMatch.
+func (in *ICMPMatch) DeepCopy() *ICMPMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(ICMPMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkPolicyGenerator) DeepCopyInto(out *NetworkPolicyGenerator) {
 	*out = *in
@@ -122,10 +301,37 @@ func (in *NetworkPolicyGeneratorList) DeepCopyObject() runtime.Object {
 func (in *NetworkPolicyGeneratorSpec) DeepCopyInto(out *NetworkPolicyGeneratorSpec) {
 	*out = *in
 	out.Duration = in.Duration
+	if in.PolicyEngines != nil {
+		in, out := &in.PolicyEngines, &out.PolicyEngines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(int64)
+		**out = **in
+	}
 	in.Policy.DeepCopyInto(&out.Policy)
 	if in.GlobalRules != nil {
 		in, out := &in.GlobalRules, &out.GlobalRules
 		*out = make([]GlobalRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.IPFamilies != nil {
+		in, out := &in.IPFamilies, &out.IPFamilies
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PolicyTypes != nil {
+		in, out := &in.PolicyTypes, &out.PolicyTypes
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	if in.CIDRRules != nil {
@@ -135,6 +341,23 @@ func (in *NetworkPolicyGeneratorSpec) DeepCopyInto(out *NetworkPolicyGeneratorSp
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.FQDNRules != nil {
+		in, out := &in.FQDNRules, &out.FQDNRules
+		*out = make([]FQDNRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.DNS != nil {
+		in, out := &in.DNS, &out.DNS
+		*out = new(DNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Antrea != nil {
+		in, out := &in.Antrea, &out.Antrea
+		*out = new(AntreaConfig)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicyGeneratorSpec.
@@ -150,6 +373,13 @@ func (in *NetworkPolicyGeneratorSpec) DeepCopy() *NetworkPolicyGeneratorSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkPolicyGeneratorStatus) DeepCopyInto(out *NetworkPolicyGeneratorStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.LastAnalyzed.DeepCopyInto(&out.LastAnalyzed)
 	if in.ObservedTraffic != nil {
 		in, out := &in.ObservedTraffic, &out.ObservedTraffic
@@ -178,6 +408,36 @@ func (in *NetworkPolicyGeneratorStatus) DeepCopyInto(out *NetworkPolicyGenerator
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MatchedNamespaces != nil {
+		in, out := &in.MatchedNamespaces, &out.MatchedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NodeCIDRs != nil {
+		in, out := &in.NodeCIDRs, &out.NodeCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.APIServerCIDRs != nil {
+		in, out := &in.APIServerCIDRs, &out.APIServerCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.APIServerPorts != nil {
+		in, out := &in.APIServerPorts, &out.APIServerPorts
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppliedPolicies != nil {
+		in, out := &in.AppliedPolicies, &out.AppliedPolicies
+		*out = make([]AppliedPolicy, len(*in))
+		copy(*out, *in)
+	}
+	if in.EnginePolicies != nil {
+		in, out := &in.EnginePolicies, &out.EnginePolicies
+		*out = make([]EnginePolicyCount, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicyGeneratorStatus.
@@ -203,6 +463,21 @@ func (in *PolicyConfig) DeepCopyInto(out *PolicyConfig) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.AllowSameNamespace != nil {
+		in, out := &in.AllowSameNamespace, &out.AllowSameNamespace
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AllowedNamespaceSelector != nil {
+		in, out := &in.AllowedNamespaceSelector, &out.AllowedNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeniedNamespaceSelector != nil {
+		in, out := &in.DeniedNamespaceSelector, &out.DeniedNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.PodSelector != nil {
 		in, out := &in.PodSelector, &out.PodSelector
 		*out = make(map[string]string, len(*in))
@@ -210,6 +485,11 @@ func (in *PolicyConfig) DeepCopyInto(out *PolicyConfig) {
 			(*out)[key] = val
 		}
 	}
+	if in.PodLabelSelector != nil {
+		in, out := &in.PodLabelSelector, &out.PodLabelSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyConfig.
@@ -225,6 +505,11 @@ func (in *PolicyConfig) DeepCopy() *PolicyConfig {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PolicyDiffEntry) DeepCopyInto(out *PolicyDiffEntry) {
 	*out = *in
+	if in.Changes != nil {
+		in, out := &in.Changes, &out.Changes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	in.Timestamp.DeepCopyInto(&out.Timestamp)
 }
 
@@ -238,6 +523,40 @@ func (in *PolicyDiffEntry) DeepCopy() *PolicyDiffEntry {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RulePeer) DeepCopyInto(out *RulePeer) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RulePeer.
+func (in *RulePeer) DeepCopy() *RulePeer {
+	if in == nil {
+		return nil
+	}
+	out := new(RulePeer)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SuggestedRule) DeepCopyInto(out *SuggestedRule) {
 	*out = *in
@@ -20,15 +20,22 @@ import (
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -37,6 +44,7 @@ import (
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 	"github.com/somaz94/network-policy-generator/internal/controller"
+	"github.com/somaz94/network-policy-generator/internal/policy"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -66,6 +74,9 @@ func main() {
 	var secureMetrics bool
 	var enableHTTP2 bool
 	var enableWebhooks bool
+	var autoEnginePreference string
+	var enginePluginsConfig string
+	var defaultIPFamilies string
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -79,6 +90,14 @@ func main() {
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
 	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
 		"Enable admission webhooks. Requires cert-manager or manual TLS cert setup.")
+	flag.StringVar(&autoEnginePreference, "auto-engine-preference", strings.Join(controller.DefaultEnginePreference, ","),
+		"Comma-separated order in which policyEngine 'auto' picks an installed engine. "+
+			"Falls back to kubernetes when none of them is served.")
+	flag.StringVar(&enginePluginsConfig, "engine-plugins-config", "",
+		"Path to a YAML file registering external policy engine plugins. Leave empty to use the built-in engines only.")
+	flag.StringVar(&defaultIPFamilies, "default-ip-families", "",
+		"Comma-separated IP families that global rules cover when a generator sets no spec.ipFamilies. "+
+			"Leave empty to use the cluster's families, read from the kubernetes Service.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -131,13 +150,45 @@ func main() {
 		// this setup is not recommended for production.
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	enginePreference, err := controller.ParseEnginePreference(autoEnginePreference)
+	if err != nil {
+		setupLog.Error(err, "invalid --auto-engine-preference")
+		os.Exit(1)
+	}
+
+	ipFamilies, err := policy.ParseIPFamilies(defaultIPFamilies)
+	if err != nil {
+		setupLog.Error(err, "invalid --default-ip-families")
+		os.Exit(1)
+	}
+
+	var plugins *policy.PluginRegistry
+	if enginePluginsConfig != "" {
+		plugins, err = policy.LoadPluginRegistry(enginePluginsConfig)
+		if err != nil {
+			setupLog.Error(err, "invalid --engine-plugins-config")
+			os.Exit(1)
+		}
+		setupLog.Info("loaded policy engine plugins", "plugins", plugins.Names())
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "1a46b0b9.policy.io",
+		// Only the API server's EndpointSlices and Service and the
+		// generators' ControllerRevisions are read, so only they are cached
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Service{}:            controller.APIServerServiceCache(),
+				&discoveryv1.EndpointSlice{}: controller.APIServerEndpointSliceCache(),
+				&appsv1.ControllerRevision{}: controller.RevisionCache(),
+			},
+		},
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -155,19 +206,35 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err = controller.NewReconciler(
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(restConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
+	engineDetector := controller.NewEngineDetector(discoveryClient, enginePreference)
+	if err := engineDetector.Refresh(); err != nil {
+		// Not fatal: "auto" resolves to kubernetes until the next engine CRD change.
+		setupLog.Error(err, "unable to discover installed policy engines")
+	}
+	setupLog.Info("policy engine discovery", "autoEngine", engineDetector.Resolve(), "preference", enginePreference)
+
+	reconciler := controller.NewReconciler(
 		mgr.GetClient(),
 		mgr.GetScheme(),
 		// SA1019: GetEventRecorder returns the events.k8s.io/v1 recorder, whose
 		// Eventf signature differs. Migrating the event surface is tracked separately.
 		//nolint:staticcheck
 		mgr.GetEventRecorderFor("network-policy-generator"),
-	).SetupWithManager(mgr); err != nil {
+	)
+	reconciler.EngineDetector = engineDetector
+	reconciler.Plugins = plugins
+	reconciler.DefaultIPFamilies = ipFamilies
+	if err = reconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "NetworkPolicyGenerator")
 		os.Exit(1)
 	}
 	if enableWebhooks {
-		if err = (&securityv1.NetworkPolicyGenerator{}).SetupWebhookWithManager(mgr); err != nil {
+		if err = (&securityv1.NetworkPolicyGenerator{}).SetupWebhookWithManager(mgr, plugins.Names()...); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "NetworkPolicyGenerator")
 			os.Exit(1)
 		}
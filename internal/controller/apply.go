@@ -11,6 +11,9 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -20,6 +23,16 @@ import (
 	"github.com/somaz94/network-policy-generator/internal/policy"
 )
 
+const (
+	// fieldManager is the server-side apply field manager that owns the
+	// fields of generated policies.
+	fieldManager = "network-policy-generator"
+
+	// legacyFieldManager is the manager name the API server derived from the
+	// binary name when earlier releases wrote policies with Update.
+	legacyFieldManager = "manager"
+)
+
 // ownerReference creates a standard owner reference for the generator.
 func ownerReference(g *securityv1.NetworkPolicyGenerator) metav1.OwnerReference {
 	return metav1.OwnerReference{
@@ -32,24 +45,24 @@ func ownerReference(g *securityv1.NetworkPolicyGenerator) metav1.OwnerReference
 	}
 }
 
-// gvkForEngine maps a policy engine name to its target GroupVersionKind.
+// gvkForEngine maps a built-in policy engine name to its default
+// GroupVersionKind, falling back to Kubernetes NetworkPolicy.
 func gvkForEngine(engineName string) schema.GroupVersionKind {
-	switch engineName {
-	case policy.EngineCilium:
-		return schema.GroupVersionKind{
-			Group:   policy.CiliumGroup,
-			Version: policy.CiliumVersion,
-			Kind:    policy.CiliumKind,
-		}
-	case policy.EngineCalico:
-		return schema.GroupVersionKind{
-			Group:   policy.CalicoGroup,
-			Version: policy.CalicoVersion,
-			Kind:    policy.CalicoKind,
-		}
-	default:
-		return networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy")
+	if kinds := policy.EngineKinds(engineName); len(kinds) > 0 {
+		return kinds[0].GroupVersionKind
 	}
+	return networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy")
+}
+
+// gvkForObject returns the GroupVersionKind an object should be applied as.
+// Engines that emit more than one kind (e.g. Antrea NetworkPolicy and
+// ClusterNetworkPolicy) set TypeMeta explicitly, which takes precedence over
+// the per-engine default.
+func gvkForObject(obj runtime.Object, engineName string) schema.GroupVersionKind {
+	if gvk := obj.GetObjectKind().GroupVersionKind(); gvk.Kind != "" && gvk.Version != "" {
+		return gvk
+	}
+	return gvkForEngine(engineName)
 }
 
 // toUnstructured converts a runtime.Object into an *unstructured.Unstructured
@@ -68,55 +81,142 @@ func toUnstructured(obj runtime.Object, gvk schema.GroupVersionKind) (*unstructu
 	return u, nil
 }
 
-// applyPolicyWithDiff creates or updates a policy object for any supported
-// engine, returning the action performed ("Created" / "Updated").
+// applyPolicyWithDiff server-side applies a policy object for any supported
+// engine, labeled with the generator's owner labels. It returns the action
+// performed ("Created" / "Updated" / "Unchanged"), what the apply changed and
+// the object's inventory entry. An object that drifted since it was last
+// applied is reverted, or left alone and reported when spec.driftPolicy is
+// "report"; the entry remembers the reported drift, so it is reported once.
 func (r *NetworkPolicyGeneratorReconciler) applyPolicyWithDiff(
 	ctx context.Context,
 	g *securityv1.NetworkPolicyGenerator,
 	obj runtime.Object,
 	engineName string,
-) (string, error) {
-	u, err := toUnstructured(obj, gvkForEngine(engineName))
+) (string, policy.PolicyDiff, securityv1.AppliedPolicy, error) {
+	u, err := toUnstructured(obj, gvkForObject(obj, engineName))
 	if err != nil {
-		return "", err
+		return "", policy.PolicyDiff{}, securityv1.AppliedPolicy{}, err
 	}
-	u.SetOwnerReferences([]metav1.OwnerReference{ownerReference(g)})
+	setOwnerMetadata(u, g, engineName)
+	unstructured.RemoveNestedField(u.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(u.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(u.Object, "status")
 
-	action, err := r.createOrUpdateWithAction(ctx, u)
+	existing, err := r.getLiveObject(ctx, u)
 	if err != nil {
-		return "", err
+		return "", policy.PolicyDiff{}, securityv1.AppliedPolicy{}, err
+	}
+	prior := previouslyApplied(g, appliedPolicy(u, engineName))
+	drifted := hasDrifted(prior, existing)
+	if drifted && g.Spec.DriftPolicy == policy.DriftPolicyReport {
+		changed, err := r.wouldChange(ctx, u, existing)
+		if err != nil {
+			return "", policy.PolicyDiff{}, securityv1.AppliedPolicy{}, err
+		}
+		if changed {
+			entry := *prior
+			if state := driftState(existing); entry.ReportedDrift != state {
+				r.reportDrift(ctx, g, engineName, u)
+				entry.ReportedDrift = state
+			}
+			return policy.DiffActionUnchanged, policy.PolicyDiff{}, entry, nil
+		}
+	}
+
+	action, diff, err := r.applyWithAction(ctx, u, existing, drifted)
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			r.Recorder.Eventf(g, "Warning", "ApplyConflict",
+				"Fields of %s policy %s/%s are managed by another field manager: %v",
+				engineName, u.GetNamespace(), u.GetName(), err)
+		}
+		return "", policy.PolicyDiff{}, securityv1.AppliedPolicy{}, err
+	}
+	if action != policy.DiffActionUnchanged {
+		PolicyOperations.WithLabelValues(action).Inc()
+		if drifted {
+			r.correctedDrift(ctx, g, engineName, u)
+		}
 	}
-	PolicyOperations.WithLabelValues(action).Inc()
-	return action, nil
+	return action, diff, appliedPolicy(u, engineName), nil
 }
 
-// createOrUpdateWithAction issues a Create or Update against the API server
-// and reports which action it took.
-func (r *NetworkPolicyGeneratorReconciler) createOrUpdateWithAction(
+// getLiveObject returns the object u would be applied over, or nil when it
+// does not exist.
+func (r *NetworkPolicyGeneratorReconciler) getLiveObject(
 	ctx context.Context, u *unstructured.Unstructured,
-) (string, error) {
+) (*unstructured.Unstructured, error) {
 	existing := &unstructured.Unstructured{}
 	existing.SetGroupVersionKind(u.GroupVersionKind())
 	err := r.Get(ctx, client.ObjectKey{
 		Name:      u.GetName(),
 		Namespace: u.GetNamespace(),
 	}, existing)
-
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
 	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			return "", err
-		}
-		if err := r.Create(ctx, u); err != nil {
-			return "", err
+		return nil, err
+	}
+	return existing, nil
+}
+
+// applyWithAction server-side applies u over existing (nil when absent) under
+// the controller's field manager and reports whether the object was created,
+// updated or left unchanged, and what changed. A no-op apply keeps the
+// resourceVersion; an apply that only changed fields the generator does not
+// render, such as managed fields, is reported as Unchanged too. Fields owned
+// by another manager are only taken over with force; otherwise the conflict
+// is returned.
+func (r *NetworkPolicyGeneratorReconciler) applyWithAction(
+	ctx context.Context, u, existing *unstructured.Unstructured, force bool,
+) (string, policy.PolicyDiff, error) {
+	if existing != nil {
+		if err := r.upgradeManagedFields(ctx, existing); err != nil {
+			return "", policy.PolicyDiff{}, err
 		}
-		return policy.DiffActionCreated, nil
 	}
 
-	u.SetResourceVersion(existing.GetResourceVersion())
-	if err := r.Update(ctx, u); err != nil {
-		return "", err
+	opts := []client.ApplyOption{client.FieldOwner(fieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	if err := r.Apply(ctx, client.ApplyConfigurationFromUnstructured(u), opts...); err != nil {
+		return "", policy.PolicyDiff{}, err
+	}
+
+	if existing == nil {
+		diff, err := policy.DiffPolicies(nil, u.Object)
+		return policy.DiffActionCreated, diff, err
+	}
+	if u.GetResourceVersion() == existing.GetResourceVersion() {
+		return policy.DiffActionUnchanged, policy.PolicyDiff{}, nil
+	}
+	diff, err := policy.DiffPolicies(existing.Object, u.Object)
+	if err != nil {
+		return "", policy.PolicyDiff{}, err
+	}
+	if diff.Empty() {
+		return policy.DiffActionUnchanged, diff, nil
+	}
+	return policy.DiffActionUpdated, diff, nil
+}
+
+// upgradeManagedFields hands the fields earlier releases set with Update over
+// to the server-side apply field manager, so applying them again does not
+// conflict with the controller's own past writes.
+func (r *NetworkPolicyGeneratorReconciler) upgradeManagedFields(
+	ctx context.Context, existing *unstructured.Unstructured,
+) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(existing, sets.New(legacyFieldManager), fieldManager)
+	if err != nil || patch == nil {
+		return err
+	}
+	if err := r.Patch(ctx, existing, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		return fmt.Errorf("failed to upgrade managed fields of %s/%s: %w",
+			existing.GetNamespace(), existing.GetName(), err)
 	}
-	return policy.DiffActionUpdated, nil
+	return nil
 }
 
 // updateStatusAndRequeue stamps LastAnalyzed, persists status and returns a
@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+	"github.com/somaz94/network-policy-generator/internal/policy"
+)
+
+// setCondition sets a status condition for the generator's current
+// generation and reports whether it changed
+func setCondition(g *securityv1.NetworkPolicyGenerator, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	return meta.SetStatusCondition(&g.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: g.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// markReconciled clears Degraded and records the generation the status
+// reflects. It reports whether anything changed.
+func markReconciled(g *securityv1.NetworkPolicyGenerator) bool {
+	changed := g.Status.ObservedGeneration != g.Generation
+	g.Status.ObservedGeneration = g.Generation
+	return setCondition(g, policy.ConditionDegraded, metav1.ConditionFalse,
+		policy.ReasonReconcileSucceeded, "The last reconcile succeeded") || changed
+}
+
+// markLearning sets the conditions of a generator observing traffic and
+// reports whether any changed
+func markLearning(g *securityv1.NetworkPolicyGenerator) bool {
+	changed := markReconciled(g)
+	changed = setCondition(g, policy.ConditionReady, metav1.ConditionFalse,
+		policy.ReasonLearning, "Policies are not enforced while learning") || changed
+	changed = setCondition(g, policy.ConditionLearning, metav1.ConditionTrue,
+		policy.ReasonLearning, fmt.Sprintf("Observing traffic for %s", g.Spec.Duration.Duration)) || changed
+	changed = setCondition(g, policy.ConditionPoliciesApplied, metav1.ConditionFalse,
+		policy.ReasonLearning, "Policies are applied once learning completes") || changed
+	changed = setCondition(g, policy.ConditionDryRun, metav1.ConditionFalse,
+		policy.ReasonLearning, "Policies are not generated while learning") || changed
+	return changed
+}
+
+// markLearningCompleted sets the Learning condition of a generator switching
+// to enforcing mode
+func markLearningCompleted(g *securityv1.NetworkPolicyGenerator) {
+	setCondition(g, policy.ConditionLearning, metav1.ConditionFalse,
+		policy.ReasonLearningCompleted, "Learning completed, switching to enforcing mode")
+}
+
+// markDryRun sets the conditions of a generator that generated count
+// policies without applying them
+func markDryRun(g *securityv1.NetworkPolicyGenerator, count int) {
+	markReconciled(g)
+	setCondition(g, policy.ConditionReady, metav1.ConditionFalse,
+		policy.ReasonDryRun, "Policies are not applied in dry-run mode")
+	setCondition(g, policy.ConditionLearning, metav1.ConditionFalse,
+		policy.ReasonEnforcing, "The generator is in enforcing mode")
+	setCondition(g, policy.ConditionPoliciesApplied, metav1.ConditionFalse,
+		policy.ReasonDryRun, "Policies are not applied in dry-run mode")
+	setCondition(g, policy.ConditionDryRun, metav1.ConditionTrue,
+		policy.ReasonDryRun, fmt.Sprintf("Generated %d policies without applying them", count))
+}
+
+// markEnforced sets the conditions of a generator that applied count
+// policies
+func markEnforced(g *securityv1.NetworkPolicyGenerator, count int) {
+	markReconciled(g)
+	message := fmt.Sprintf("Applied %d policies", count)
+	setCondition(g, policy.ConditionReady, metav1.ConditionTrue, policy.ReasonPoliciesApplied, message)
+	setCondition(g, policy.ConditionLearning, metav1.ConditionFalse,
+		policy.ReasonEnforcing, "The generator is in enforcing mode")
+	setCondition(g, policy.ConditionPoliciesApplied, metav1.ConditionTrue, policy.ReasonPoliciesApplied, message)
+	setCondition(g, policy.ConditionDryRun, metav1.ConditionFalse,
+		policy.ReasonEnforcing, "Policies are applied")
+}
+
+// markRolledBack sets the conditions of a generator that applied count
+// policies of the revision spec.rollbackTo pins
+func markRolledBack(g *securityv1.NetworkPolicyGenerator, count int, revision int64) {
+	markEnforced(g, count)
+	setCondition(g, policy.ConditionReady, metav1.ConditionTrue, policy.ReasonRolledBack,
+		fmt.Sprintf("Applied %d policies of revision %d, pinned by spec.rollbackTo", count, revision))
+}
+
+// markDegraded sets the conditions of a generator whose reconcile failed
+func markDegraded(g *securityv1.NetworkPolicyGenerator, err error) {
+	g.Status.ObservedGeneration = g.Generation
+	setCondition(g, policy.ConditionDegraded, metav1.ConditionTrue, policy.ReasonReconcileFailed, err.Error())
+	setCondition(g, policy.ConditionReady, metav1.ConditionFalse, policy.ReasonReconcileFailed, err.Error())
+}
+
+// recordFailure persists the Degraded condition for a failed reconcile. It
+// re-reads the generator so that status changes the failed reconcile made in
+// memory are not written. Errors are only logged; the reconcile error is what
+// the caller returns.
+func (r *NetworkPolicyGeneratorReconciler) recordFailure(ctx context.Context, key types.NamespacedName, reconcileErr error) {
+	log := log.FromContext(ctx)
+
+	latest := &securityv1.NetworkPolicyGenerator{}
+	if err := r.Get(ctx, key, latest); err != nil {
+		log.Error(err, "failed to get NetworkPolicyGenerator to record failure")
+		return
+	}
+	markDegraded(latest, reconcileErr)
+	if err := r.Status().Update(ctx, latest); err != nil {
+		log.Error(err, "failed to record Degraded condition")
+	}
+}
@@ -145,6 +145,118 @@ var _ = Describe("NetworkPolicyGenerator CRD validation", func() {
 
 			Expect(k8sClient.Create(ctx, gen)).To(Succeed())
 		})
+
+		It("accepts an ICMP rule without a port", func() {
+			gen := newGenerator("rule-icmp")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) {
+					r.Protocol = policy.ProtocolICMP
+					r.ICMP = &securityv1.ICMPMatch{Type: 8}
+				}),
+			}
+
+			Expect(k8sClient.Create(ctx, gen)).To(Succeed())
+		})
+
+		It("rejects an ICMP rule without icmp", func() {
+			gen := newGenerator("rule-icmp-missing")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) { r.Protocol = policy.ProtocolICMPv6 }),
+			}
+
+			err := k8sClient.Create(ctx, gen)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("ICMP rules require icmp"))
+		})
+
+		It("accepts an ICMP rule with only notICMP", func() {
+			gen := newGenerator("rule-not-icmp")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) {
+					r.Protocol = policy.ProtocolICMP
+					r.NotICMP = &securityv1.ICMPMatch{Type: 5}
+				}),
+			}
+
+			Expect(k8sClient.Create(ctx, gen)).To(Succeed())
+		})
+
+		It("rejects notICMP on a port rule", func() {
+			gen := newGenerator("rule-not-icmp-tcp")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) { r.NotICMP = &securityv1.ICMPMatch{Type: 5} }),
+			}
+
+			err := k8sClient.Create(ctx, gen)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("notICMP requires protocol ICMP or ICMPv6"))
+		})
+
+		It("accepts a port range", func() {
+			gen := newGenerator("rule-port-range")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) { r.Port = 30000; r.EndPort = 32767 }),
+			}
+
+			Expect(k8sClient.Create(ctx, gen)).To(Succeed())
+		})
+
+		It("rejects an endPort below port or without a numeric port", func() {
+			gen := newGenerator("rule-bad-range")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) { r.Port = 8080; r.EndPort = 8000 }),
+			}
+			err := k8sClient.Create(ctx, gen)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("endPort requires port"))
+
+			gen = newGenerator("rule-named-range")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) { r.NamedPort = namedPortHTTP; r.EndPort = 8000 }),
+			}
+			err = k8sClient.Create(ctx, gen)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("endPort requires port"))
+		})
+
+		It("accepts from peers on an ingress rule", func() {
+			gen := newGenerator("rule-from-peers")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) {
+					r.Port = 5432
+					r.From = []securityv1.RulePeer{
+						{Namespaces: []string{nsA}, PodSelector: map[string]string{"app": "api"}},
+						{CIDR: "10.0.0.0/8"},
+					}
+				}),
+			}
+
+			Expect(k8sClient.Create(ctx, gen)).To(Succeed())
+		})
+
+		It("rejects to peers on an ingress rule and a peer mixing cidr with selectors", func() {
+			gen := newGenerator("rule-to-on-ingress")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) {
+					r.Port = 5432
+					r.To = []securityv1.RulePeer{{CIDR: "10.0.0.0/8"}}
+				}),
+			}
+			err := k8sClient.Create(ctx, gen)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("to requires direction egress"))
+
+			gen = newGenerator("rule-mixed-peer")
+			gen.Spec.GlobalRules = []securityv1.GlobalRule{
+				rule(func(r *securityv1.GlobalRule) {
+					r.Port = 5432
+					r.From = []securityv1.RulePeer{{CIDR: "10.0.0.0/8", Namespaces: []string{nsA}}}
+				}),
+			}
+			err = k8sClient.Create(ctx, gen)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("a peer sets either cidr"))
+		})
 	})
 
 	Context("policy namespace overlap", func() {
@@ -167,6 +279,60 @@ var _ = Describe("NetworkPolicyGenerator CRD validation", func() {
 		})
 	})
 
+	Context("policy allowSameNamespace", func() {
+		It("has no schema default, so stored generators without it stay unset", func() {
+			gen := newGenerator("same-namespace-default")
+
+			Expect(k8sClient.Create(ctx, gen)).To(Succeed())
+			Expect(gen.Spec.Policy.AllowSameNamespace).To(BeNil())
+		})
+	})
+
+	Context("policy pod selectors", func() {
+		It("rejects podSelector combined with podLabelSelector", func() {
+			gen := newGenerator("pod-selector-both")
+			gen.Spec.Policy.PodSelector = map[string]string{"app": "web"}
+			gen.Spec.Policy.PodLabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "api"}}
+
+			err := k8sClient.Create(ctx, gen)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("mutually exclusive"))
+		})
+
+		It("accepts a podLabelSelector with match expressions", func() {
+			gen := newGenerator("pod-label-selector")
+			gen.Spec.Policy.PodLabelSelector = &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"api", "worker"}},
+				},
+			}
+
+			Expect(k8sClient.Create(ctx, gen)).To(Succeed())
+		})
+	})
+
+	Context("dns", func() {
+		It("rejects custom mode without resolvers", func() {
+			gen := newGenerator("dns-custom-no-to")
+			gen.Spec.DNS = &securityv1.DNSConfig{Mode: policy.DNSModeCustom}
+
+			err := k8sClient.Create(ctx, gen)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("to must be set when mode is custom"))
+		})
+
+		It("accepts a NodeLocal DNSCache resolver over UDP", func() {
+			gen := newGenerator("dns-node-local")
+			gen.Spec.DNS = &securityv1.DNSConfig{
+				Mode:      policy.DNSModeCustom,
+				To:        []securityv1.RulePeer{{CIDR: "169.254.20.10/32"}},
+				Protocols: []string{policy.ProtocolUDP},
+			}
+
+			Expect(k8sClient.Create(ctx, gen)).To(Succeed())
+		})
+	})
+
 	Context("update path", func() {
 		It("rejects an update that violates a CEL rule", func() {
 			gen := newGenerator(fmt.Sprintf("update-guard-%d", time.Now().UnixNano()))
@@ -0,0 +1,259 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+	"github.com/somaz94/network-policy-generator/internal/policy"
+)
+
+// driftStateDeleted is the AppliedPolicy.ReportedDrift of a deleted object
+const driftStateDeleted = "Deleted"
+
+// watchedEngines lists the built-in engines whose kinds are watched for
+// drift. Plugin kinds are only checked on the periodic requeue, since the
+// controller may not be granted watch on them.
+var watchedEngines = []string{policy.EngineKubernetes, policy.EngineCilium, policy.EngineCalico, policy.EngineAntrea}
+
+// previouslyApplied returns the inventory entry recorded for the object in
+// status.appliedPolicies, or nil when the generator has not applied it yet.
+func previouslyApplied(g *securityv1.NetworkPolicyGenerator, entry securityv1.AppliedPolicy) *securityv1.AppliedPolicy {
+	i := slices.IndexFunc(g.Status.AppliedPolicies, func(p securityv1.AppliedPolicy) bool { return sameObject(p, entry) })
+	if i < 0 {
+		return nil
+	}
+	return &g.Status.AppliedPolicies[i]
+}
+
+// hasDrifted reports whether the live object (nil when deleted) was changed
+// since the generator last applied it. Entries recorded without a generation
+// predate drift detection and are never treated as drifted.
+func hasDrifted(prior *securityv1.AppliedPolicy, existing *unstructured.Unstructured) bool {
+	if prior == nil || prior.Generation == 0 {
+		return false
+	}
+	return existing == nil || existing.GetGeneration() != prior.Generation
+}
+
+// driftState identifies the drifted state of a live object (nil when
+// deleted) for AppliedPolicy.ReportedDrift
+func driftState(existing *unstructured.Unstructured) string {
+	if existing == nil {
+		return driftStateDeleted
+	}
+	return existing.GetResourceVersion()
+}
+
+// wouldChange reports whether applying u would change the live object, by
+// server-side applying it as a dry run. A conflict means another manager set
+// fields the generator renders to other values, which is a change too.
+func (r *NetworkPolicyGeneratorReconciler) wouldChange(
+	ctx context.Context, u, existing *unstructured.Unstructured,
+) (bool, error) {
+	if existing == nil {
+		return true, nil
+	}
+	dryRun := u.DeepCopy()
+	err := r.Apply(ctx, client.ApplyConfigurationFromUnstructured(dryRun),
+		client.FieldOwner(fieldManager), client.DryRunAll)
+	if apierrors.IsConflict(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return dryRun.GetGeneration() != existing.GetGeneration(), nil
+}
+
+// correctedDrift records that a drifted object was re-applied
+func (r *NetworkPolicyGeneratorReconciler) correctedDrift(
+	ctx context.Context, g *securityv1.NetworkPolicyGenerator, engineName string, u *unstructured.Unstructured,
+) {
+	r.Recorder.Eventf(g, "Normal", "DriftCorrected",
+		"Reverted manual changes to %s policy %s/%s", engineName, u.GetNamespace(), u.GetName())
+	PolicyDrift.WithLabelValues(g.Name, g.Namespace, engineName, "Corrected").Inc()
+	log.FromContext(ctx).Info("Reverted drifted policy",
+		"engine", engineName, "kind", u.GetKind(), "namespace", u.GetNamespace(), "name", u.GetName())
+}
+
+// reportDrift records that a drifted object was left alone because
+// spec.driftPolicy is "report". It is called once per drifted state.
+func (r *NetworkPolicyGeneratorReconciler) reportDrift(
+	ctx context.Context, g *securityv1.NetworkPolicyGenerator, engineName string, u *unstructured.Unstructured,
+) {
+	r.Recorder.Eventf(g, "Warning", "DriftDetected",
+		"%s policy %s/%s was changed or deleted outside the generator", engineName, u.GetNamespace(), u.GetName())
+	PolicyDrift.WithLabelValues(g.Name, g.Namespace, engineName, "Reported").Inc()
+	log.FromContext(ctx).Info("Detected drifted policy",
+		"engine", engineName, "kind", u.GetKind(), "namespace", u.GetNamespace(), "name", u.GetName())
+}
+
+// policyKindWatches tracks the drift watches on the kinds of the built-in
+// engines. Kinds whose CRDs are not installed when the manager starts stay
+// pending until the CRD watch sees them installed.
+type policyKindWatches struct {
+	mapper meta.RESTMapper
+	cache  cache.Cache
+
+	mu         sync.Mutex
+	controller controller.Controller
+	pending    []schema.GroupVersionKind
+}
+
+// watchPolicyKinds adds a metadata-only watch for every kind of the built-in
+// engines that the API server serves, and records the others as pending.
+func (r *NetworkPolicyGeneratorReconciler) watchPolicyKinds(b *builder.Builder, watches *policyKindWatches) (*builder.Builder, error) {
+	for _, engineName := range watchedEngines {
+		for _, kind := range policy.EngineKinds(engineName) {
+			gvk := kind.GroupVersionKind
+			served, err := servesKind(watches.mapper, gvk)
+			if err != nil {
+				return nil, err
+			}
+			if !served {
+				watches.pending = append(watches.pending, gvk)
+				continue
+			}
+			b = b.Watches(policyKindObject(gvk),
+				handler.EnqueueRequestsFromMapFunc(r.ownerGenerators),
+				builder.WithPredicates(driftPredicate()),
+			)
+		}
+	}
+	return b, nil
+}
+
+// watchInstalledPolicyKinds starts the drift watch of every pending kind the
+// API server serves by now. A kind that fails stays pending and is retried on
+// the next CRD event.
+func (r *NetworkPolicyGeneratorReconciler) watchInstalledPolicyKinds(ctx context.Context) {
+	w := r.policyWatches
+	if w == nil {
+		return
+	}
+	log := log.FromContext(ctx)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.controller == nil {
+		return
+	}
+	var pending []schema.GroupVersionKind
+	for _, gvk := range w.pending {
+		served, err := servesKind(w.mapper, gvk)
+		if err == nil && served {
+			err = w.controller.Watch(source.Kind(w.cache, client.Object(policyKindObject(gvk)),
+				handler.EnqueueRequestsFromMapFunc(r.ownerGenerators), driftPredicate()))
+			if err == nil {
+				log.Info("Watching installed policy kind for drift", "kind", gvk.String())
+				continue
+			}
+		}
+		if err != nil {
+			log.Error(err, "failed to watch policy kind for drift", "kind", gvk.String())
+		}
+		pending = append(pending, gvk)
+	}
+	w.pending = pending
+}
+
+// servesKind reports whether the API server serves the kind. The manager's
+// REST mapper re-discovers a group it does not know yet, so kinds of CRDs
+// installed later are found.
+func servesKind(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (bool, error) {
+	if _, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// policyKindObject returns the metadata-only object watched for a kind
+func policyKindObject(gvk schema.GroupVersionKind) *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
+
+// driftPredicate passes spec changes and deletions of objects carrying the
+// generator owner labels
+func driftPredicate() predicate.Predicate {
+	return predicate.And(
+		predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			_, ok := obj.GetLabels()[policy.LabelGeneratorName]
+			return ok
+		}),
+		predicate.Funcs{
+			CreateFunc:  func(event.CreateEvent) bool { return false },
+			UpdateFunc:  predicate.GenerationChangedPredicate{}.Update,
+			DeleteFunc:  func(event.DeleteEvent) bool { return true },
+			GenericFunc: func(event.GenericEvent) bool { return false },
+		},
+	)
+}
+
+// ownerGenerators maps a generated object to the generator named by its
+// owner annotation. Owner references cannot cross namespaces, so objects
+// without the annotation fall back to the owner labels; long generator names
+// are hashed in the label, so the generators of the labeled namespace are
+// matched by their label value.
+func (r *NetworkPolicyGeneratorReconciler) ownerGenerators(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	if owner, ok := ownerFromAnnotation(obj); ok {
+		return []reconcile.Request{{NamespacedName: owner}}
+	}
+
+	labels := obj.GetLabels()
+	name, namespace := labels[policy.LabelGeneratorName], labels[policy.LabelGeneratorNamespace]
+	if name == "" || namespace == "" {
+		return nil
+	}
+
+	generators := &securityv1.NetworkPolicyGeneratorList{}
+	if err := r.List(ctx, generators, client.InNamespace(namespace)); err != nil {
+		log.Error(err, "failed to list NetworkPolicyGenerators", "namespace", namespace)
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, g := range generators.Items {
+		if ownerLabelValue(g.Name) == name {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: g.Name, Namespace: g.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
+// ownerFromAnnotation parses the owner annotation of a generated object
+func ownerFromAnnotation(obj client.Object) (types.NamespacedName, bool) {
+	namespace, name, ok := strings.Cut(obj.GetAnnotations()[policy.AnnotationOwner], "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, false
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, true
+}
@@ -4,107 +4,452 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
 
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 	"github.com/somaz94/network-policy-generator/internal/policy"
 )
 
-// handleEnforcingMode resolves the policy engine from the spec and delegates
-// to the generic enforcing handler. Templates are applied first so that any
-// engine sees the merged spec.
+// handleEnforcingMode resolves the policy engines from the spec and delegates
+// to the generic enforcing handler. The revision spec.rollbackTo pins,
+// templates, the default or cluster IP families, the namespaces deniedNamespaceSelector
+// matches, the node CIDRs and the API server endpoints are resolved first so
+// that every engine sees the merged spec.
 func (r *NetworkPolicyGeneratorReconciler) handleEnforcingMode(
 	ctx context.Context, generator *securityv1.NetworkPolicyGenerator,
 ) (ctrl.Result, error) {
+	if err := r.rollBackSpec(ctx, generator); err != nil {
+		return ctrl.Result{}, err
+	}
 	if generator.Spec.TemplateName != "" {
 		if tmpl := policy.GetTemplate(generator.Spec.TemplateName); tmpl != nil {
 			tmpl.Apply(&generator.Spec)
 		}
 	}
+	if len(generator.Spec.IPFamilies) == 0 {
+		ipFamilies, err := r.defaultIPFamilies(ctx)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		generator.Spec.IPFamilies = ipFamilies
+	}
+	if err := r.resolveMatchedNamespaces(ctx, generator); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.resolveNodeCIDRs(ctx, generator); err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.resolveAPIServerEndpoints(ctx, generator); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	engineTypes := policy.EngineTypes(&generator.Spec)
+	generator.Status.ResolvedEngine = ""
+	engines := make([]policy.PolicyEngine, 0, len(engineTypes))
+	for _, engineType := range engineTypes {
+		if engineType == policy.EngineAuto {
+			engineType = r.resolveAutoEngine()
+			generator.Status.ResolvedEngine = engineType
+		}
+		engine, err := r.newPolicyEngine(engineType)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		engines = append(engines, engine)
+	}
 
-	engineType := generator.Spec.PolicyEngine
-	if engineType == "" {
-		engineType = policy.EngineKubernetes
+	return r.handleEnforcing(ctx, generator, engines)
+}
+
+// defaultIPFamilies returns the IP families of generators that set no
+// spec.ipFamilies: the operator's --default-ip-families when given, otherwise
+// the cluster's, read from spec.ipFamilies of the API server's Service.
+// Without that Service, global rules cover IPv4.
+func (r *NetworkPolicyGeneratorReconciler) defaultIPFamilies(ctx context.Context) ([]string, error) {
+	if len(r.DefaultIPFamilies) > 0 {
+		return r.DefaultIPFamilies, nil
 	}
 
-	engine, err := policy.NewPolicyEngine(engineType)
+	svc := &corev1.Service{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: apiServerServiceName}, svc)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get the API server Service: %w", err)
+	}
+	if len(svc.Spec.IPFamilies) == 0 {
+		return []string{policy.IPFamilyIPv4}, nil
+	}
+	ipFamilies := make([]string, 0, len(svc.Spec.IPFamilies))
+	for _, family := range svc.Spec.IPFamilies {
+		ipFamilies = append(ipFamilies, string(family))
+	}
+	return ipFamilies, nil
+}
+
+// resolveMatchedNamespaces records the namespaces an allow-type generator's
+// deniedNamespaceSelector matches in status.matchedNamespaces, skipping
+// namespaces that are being deleted. Policies left in namespaces it no longer
+// matches are garbage-collected after the apply.
+func (r *NetworkPolicyGeneratorReconciler) resolveMatchedNamespaces(
+	ctx context.Context, generator *securityv1.NetworkPolicyGenerator,
+) error {
+	generator.Status.MatchedNamespaces = nil
+
+	sel := generator.Spec.Policy.DeniedNamespaceSelector
+	if sel == nil || generator.Spec.Policy.Type != policy.PolicyTypeAllow {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(sel)
 	if err != nil {
-		return ctrl.Result{}, err
+		return fmt.Errorf("invalid deniedNamespaceSelector: %w", err)
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := r.List(ctx, namespaces, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	for _, ns := range namespaces.Items {
+		if ns.Status.Phase != corev1.NamespaceTerminating {
+			generator.Status.MatchedNamespaces = append(generator.Status.MatchedNamespaces, ns.Name)
+		}
+	}
+	slices.Sort(generator.Status.MatchedNamespaces)
+	return nil
+}
+
+// resolveNodeCIDRs records the addresses of every node in status.nodeCIDRs
+// when spec.allowNodeTraffic is set. Node addresses become host CIDRs (/32
+// or /128).
+func (r *NetworkPolicyGeneratorReconciler) resolveNodeCIDRs(
+	ctx context.Context, generator *securityv1.NetworkPolicyGenerator,
+) error {
+	generator.Status.NodeCIDRs = nil
+	if !generator.Spec.AllowNodeTraffic {
+		return nil
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes); err != nil {
+		return fmt.Errorf("failed to list nodes: %w", err)
+	}
+	var cidrs []string
+	for _, node := range nodes.Items {
+		cidrs = append(cidrs, nodeCIDRs(&node)...)
+	}
+	slices.Sort(cidrs)
+	generator.Status.NodeCIDRs = slices.Compact(cidrs)
+	return nil
+}
+
+// nodeCIDRs returns the internal and external addresses of a node as host
+// CIDRs. Unparsable addresses are skipped. The node's pod CIDRs are left out:
+// together they cover every pod in the cluster, so allowing them would let
+// any pod reach the enforced pods.
+func nodeCIDRs(node *corev1.Node) []string {
+	var cidrs []string
+	for _, addr := range node.Status.Addresses {
+		if addr.Type != corev1.NodeInternalIP && addr.Type != corev1.NodeExternalIP {
+			continue
+		}
+		ip, err := netip.ParseAddr(addr.Address)
+		if err != nil {
+			continue
+		}
+		cidrs = append(cidrs, netip.PrefixFrom(ip, ip.BitLen()).String())
+	}
+	return cidrs
+}
+
+// resolveAPIServerEndpoints records the ready addresses (as host CIDRs) and
+// the ports of the default/kubernetes EndpointSlices in status.apiServerCIDRs
+// and status.apiServerPorts when spec.allowAPIServer is set.
+func (r *NetworkPolicyGeneratorReconciler) resolveAPIServerEndpoints(
+	ctx context.Context, generator *securityv1.NetworkPolicyGenerator,
+) error {
+	generator.Status.APIServerCIDRs = nil
+	generator.Status.APIServerPorts = nil
+	if !generator.Spec.AllowAPIServer {
+		return nil
+	}
+
+	endpointSlices := &discoveryv1.EndpointSliceList{}
+	if err := r.List(ctx, endpointSlices,
+		client.InNamespace(metav1.NamespaceDefault),
+		client.MatchingLabels{discoveryv1.LabelServiceName: apiServerServiceName},
+	); err != nil {
+		return fmt.Errorf("failed to list API server EndpointSlices: %w", err)
+	}
+	cidrs, ports := apiServerEndpoints(endpointSlices.Items)
+	generator.Status.APIServerCIDRs = cidrs
+	generator.Status.APIServerPorts = ports
+	return nil
+}
+
+// apiServerEndpoints returns the sorted, unique host CIDRs of the ready
+// endpoints and the ports of the given EndpointSlices
+func apiServerEndpoints(endpointSlices []discoveryv1.EndpointSlice) ([]string, []int32) {
+	var cidrs []string
+	var ports []int32
+	for _, slice := range endpointSlices {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.Conditions.Ready != nil && !*endpoint.Conditions.Ready {
+				continue
+			}
+			for _, address := range endpoint.Addresses {
+				ip, err := netip.ParseAddr(address)
+				if err != nil {
+					continue
+				}
+				cidrs = append(cidrs, netip.PrefixFrom(ip, ip.BitLen()).String())
+			}
+		}
+		for _, port := range slice.Ports {
+			if port.Port != nil {
+				ports = append(ports, *port.Port)
+			}
+		}
 	}
+	slices.Sort(cidrs)
+	slices.Sort(ports)
+	return slices.Compact(cidrs), slices.Compact(ports)
+}
 
-	return r.handleEnforcing(ctx, generator, engine)
+// newPolicyEngine returns the plugin registered under engineType, or the
+// built-in engine of that name.
+func (r *NetworkPolicyGeneratorReconciler) newPolicyEngine(engineType string) (policy.PolicyEngine, error) {
+	if plugin, ok := r.Plugins.Engine(engineType); ok {
+		return plugin, nil
+	}
+	return policy.NewPolicyEngine(engineType)
+}
+
+// resolveAutoEngine returns the engine policyEngine "auto" stands for. Without
+// a detector (e.g. discovery is unavailable) it falls back to kubernetes.
+func (r *NetworkPolicyGeneratorReconciler) resolveAutoEngine() string {
+	if r.EngineDetector == nil {
+		return policy.EngineKubernetes
+	}
+	return r.EngineDetector.Resolve()
+}
+
+// enginePolicies pairs an engine with the objects it generated.
+type enginePolicies struct {
+	engineName string
+	objects    []runtime.Object
 }
 
 // handleEnforcing is the single code path that applies policies for any
-// PolicyEngine backend. It records a PolicyDiff entry, a per-policy Kubernetes
-// event and increments the PolicyOperations metric for every applied object,
-// regardless of which engine produced it.
+// PolicyEngine backend. Every engine generates its objects before anything is
+// applied, so a generation error in one engine leaves the cluster untouched.
+// It records a PolicyDiff entry with the rule-level changes for every applied
+// object and, for every object that actually changed, a Kubernetes event and
+// a PolicyOperations increment, regardless of which engine produced it. It
+// records the applied objects in status.appliedPolicies, then
+// garbage-collects the objects an earlier reconcile applied that are no
+// longer generated: those of deselected engines, of namespaces that are no
+// longer targeted, or of a policy type that changed. The applied policies
+// are recorded as the current revision, unless spec.rollbackTo pins an
+// earlier revision, whose spec they were generated from.
 func (r *NetworkPolicyGeneratorReconciler) handleEnforcing(
 	ctx context.Context,
 	generator *securityv1.NetworkPolicyGenerator,
-	engine policy.PolicyEngine,
+	engines []policy.PolicyEngine,
 ) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
-	objects, err := engine.GeneratePolicies(generator)
+	generated, err := r.generatePolicies(ctx, generator, engines)
 	if err != nil {
-		r.Recorder.Eventf(generator, "Warning", "GenerationFailed",
-			"Failed to generate %s policies: %v", engine.EngineName(), err)
-		log.Error(err, "failed to generate policies", "engine", engine.EngineName())
 		return ctrl.Result{}, err
 	}
+	var allObjects []runtime.Object
+	for _, g := range generated {
+		allObjects = append(allObjects, g.objects...)
+	}
 
 	if generator.Spec.DryRun {
 		r.Recorder.Eventf(generator, "Normal", "DryRun",
 			"Dry-run mode: generated %d %s policies without applying",
-			len(objects), engine.EngineName())
-		return r.handleDryRun(ctx, generator, objects)
+			len(allObjects), engineNames(engines))
+		return r.handleDryRun(ctx, generator, allObjects)
 	}
 
 	var diff []securityv1.PolicyDiffEntry
-	for _, obj := range objects {
-		accessor, accErr := meta.Accessor(obj)
-		if accErr != nil {
-			return ctrl.Result{}, fmt.Errorf("failed to access object metadata: %w", accErr)
-		}
+	var inventory []securityv1.AppliedPolicy
+	counts := make([]securityv1.EnginePolicyCount, 0, len(generated))
+	for _, g := range generated {
+		engineName := g.engineName
+		for _, obj := range g.objects {
+			accessor, accErr := meta.Accessor(obj)
+			if accErr != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to access object metadata: %w", accErr)
+			}
+
+			action, changes, applied, applyErr := r.applyPolicyWithDiff(ctx, generator, obj, engineName)
+			if applyErr != nil {
+				r.Recorder.Eventf(generator, "Warning", "ApplyFailed",
+					"Failed to apply %s policy %s/%s: %v",
+					engineName, accessor.GetNamespace(), accessor.GetName(), applyErr)
+				log.Error(applyErr, "failed to apply policy",
+					"engine", engineName,
+					"namespace", accessor.GetNamespace(),
+					"name", accessor.GetName())
+				return ctrl.Result{}, applyErr
+			}
 
-		action, applyErr := r.applyPolicyWithDiff(ctx, generator, obj, engine.EngineName())
-		if applyErr != nil {
-			r.Recorder.Eventf(generator, "Warning", "ApplyFailed",
-				"Failed to apply %s policy %s/%s: %v",
-				engine.EngineName(), accessor.GetNamespace(), accessor.GetName(), applyErr)
-			log.Error(applyErr, "failed to apply policy",
-				"engine", engine.EngineName(),
-				"namespace", accessor.GetNamespace(),
-				"name", accessor.GetName())
-			return ctrl.Result{}, applyErr
+			if action != policy.DiffActionUnchanged {
+				r.Recorder.Eventf(generator, "Normal", "Policy"+action,
+					"%s policy %s/%s %s: %s",
+					engineName, accessor.GetNamespace(), accessor.GetName(), action, summarizeChanges(changes))
+			}
+
+			inventory = append(inventory, applied)
+			diff = append(diff, securityv1.PolicyDiffEntry{
+				PolicyName: accessor.GetName(),
+				Namespace:  accessor.GetNamespace(),
+				Engine:     engineName,
+				Action:     action,
+				Changes:    changes.Changes,
+				Patch:      changes.Patch,
+				Timestamp:  metav1.Now(),
+			})
 		}
 
-		r.Recorder.Eventf(generator, "Normal", "Policy"+action,
-			"%s policy %s/%s %s",
-			engine.EngineName(), accessor.GetNamespace(), accessor.GetName(), action)
+		counts = append(counts, securityv1.EnginePolicyCount{Engine: engineName, Count: len(g.objects)})
+		PoliciesApplied.WithLabelValues(generator.Name, generator.Namespace, engineName).
+			Set(float64(len(g.objects)))
+	}
 
-		diff = append(diff, securityv1.PolicyDiffEntry{
-			PolicyName: accessor.GetName(),
-			Namespace:  accessor.GetNamespace(),
-			Action:     action,
-			Timestamp:  metav1.Now(),
-		})
+	if generator.Status.AppliedPolicies == nil {
+		if err := r.cleanupRemovedEngines(ctx, generator, engines); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	if err := r.collectGarbage(ctx, generator, inventory, cleanupEngineTypes(generator)); err != nil {
+		return ctrl.Result{}, err
+	}
+	selected := make(map[string]bool, len(generated))
+	for _, g := range generated {
+		selected[g.engineName] = true
+	}
+	for _, applied := range generator.Status.EnginePolicies {
+		if !selected[applied.Engine] {
+			PoliciesApplied.DeleteLabelValues(generator.Name, generator.Namespace, applied.Engine)
+		}
+	}
+
+	if rollbackTo := generator.Spec.RollbackTo; rollbackTo != nil {
+		if generator.Status.CurrentRevision != *rollbackTo {
+			r.Recorder.Eventf(generator, "Normal", "RolledBack",
+				"Re-applied the policies of revision %d", *rollbackTo)
+		}
+		generator.Status.CurrentRevision = *rollbackTo
+	} else if err := r.recordRevision(ctx, generator, generated); err != nil {
+		log.Error(err, "failed to record policy revision")
+		return ctrl.Result{}, err
 	}
 
 	generator.Status.PolicyDiff = diff
-	generator.Status.AppliedPoliciesCount = len(objects)
-	PoliciesApplied.WithLabelValues(generator.Name, generator.Namespace, engine.EngineName()).
-		Set(float64(len(objects)))
+	generator.Status.AppliedPoliciesCount = len(allObjects)
+	generator.Status.AppliedPolicies = inventory
+	generator.Status.EnginePolicies = counts
+	if generator.Spec.RollbackTo != nil {
+		markRolledBack(generator, len(allObjects), *generator.Spec.RollbackTo)
+	} else {
+		markEnforced(generator, len(allObjects))
+	}
 
 	return r.updateStatusAndRequeue(ctx, generator)
 }
 
+// generatePolicies returns the policies every engine generates. All engines
+// generate before anything is applied.
+func (r *NetworkPolicyGeneratorReconciler) generatePolicies(
+	ctx context.Context,
+	generator *securityv1.NetworkPolicyGenerator,
+	engines []policy.PolicyEngine,
+) ([]enginePolicies, error) {
+	log := log.FromContext(ctx)
+
+	generated := make([]enginePolicies, 0, len(engines))
+	for _, engine := range engines {
+		objects, err := engine.GeneratePolicies(generator)
+		if err != nil {
+			r.Recorder.Eventf(generator, "Warning", "GenerationFailed",
+				"Failed to generate %s policies: %v", engine.EngineName(), err)
+			log.Error(err, "failed to generate policies", "engine", engine.EngineName())
+			return nil, err
+		}
+		generated = append(generated, enginePolicies{engineName: engine.EngineName(), objects: objects})
+	}
+	return generated, nil
+}
+
+// cleanupRemovedEngines deletes, by the names the current spec yields, the
+// policies of engines recorded in status.enginePolicies that are no longer
+// selected. It covers generators last reconciled before status.appliedPolicies
+// existed, whose objects carry no owner labels yet.
+func (r *NetworkPolicyGeneratorReconciler) cleanupRemovedEngines(
+	ctx context.Context,
+	generator *securityv1.NetworkPolicyGenerator,
+	engines []policy.PolicyEngine,
+) error {
+	log := log.FromContext(ctx)
+
+	selected := make(map[string]bool, len(engines))
+	for _, engine := range engines {
+		selected[engine.EngineName()] = true
+	}
+
+	for _, applied := range generator.Status.EnginePolicies {
+		if selected[applied.Engine] {
+			continue
+		}
+		if err := r.deleteEnginePolicies(ctx, generator, applied.Engine); err != nil {
+			r.Recorder.Eventf(generator, "Warning", "CleanupFailed",
+				"Failed to delete %s policies for deselected engine: %v", applied.Engine, err)
+			log.Error(err, "failed to delete policies of deselected engine", "engine", applied.Engine)
+			return err
+		}
+		r.Recorder.Eventf(generator, "Normal", "PoliciesDeleted",
+			"Deleted %s policies: engine is no longer selected", applied.Engine)
+		PolicyOperations.WithLabelValues("Deleted").Inc()
+	}
+	return nil
+}
+
+// engineNames joins the names of the engines for event messages.
+func engineNames(engines []policy.PolicyEngine) string {
+	names := make([]string, len(engines))
+	for i, engine := range engines {
+		names[i] = engine.EngineName()
+	}
+	return strings.Join(names, "+")
+}
+
+// summarizeChanges renders the first change of a policy diff, and how many
+// more there are, for event messages.
+func summarizeChanges(diff policy.PolicyDiff) string {
+	switch len(diff.Changes) {
+	case 0:
+		return "no rule changes"
+	case 1:
+		return diff.Changes[0]
+	default:
+		return fmt.Sprintf("%s (and %d more lines in status.policyDiff)", diff.Changes[0], len(diff.Changes)-1)
+	}
+}
+
 // handleDryRun serializes generated policies into status.GeneratedPolicies
 // without touching the API server.
 func (r *NetworkPolicyGeneratorReconciler) handleDryRun(
@@ -128,6 +473,7 @@ func (r *NetworkPolicyGeneratorReconciler) handleDryRun(
 	generator.Status.GeneratedPolicies = yamls
 	generator.Status.AppliedPoliciesCount = 0
 	generator.Status.PolicyDiff = nil
+	markDryRun(generator, len(objects))
 
 	return r.updateStatusAndRequeue(ctx, generator)
 }
@@ -0,0 +1,116 @@
+package controller
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/discovery"
+
+	"github.com/somaz94/network-policy-generator/internal/policy"
+)
+
+// DefaultEnginePreference is the order in which policyEngine "auto" picks an
+// installed engine when no preference is configured.
+var DefaultEnginePreference = []string{policy.EngineCilium, policy.EngineCalico, policy.EngineAntrea}
+
+// engineCRDs maps the CRD names that back each CNI-specific engine. A change
+// to any of them triggers re-discovery and watches newly served kinds.
+var engineCRDs = map[string]string{
+	"ciliumnetworkpolicies.cilium.io":       policy.EngineCilium,
+	"networkpolicies.crd.projectcalico.org": policy.EngineCalico,
+	"networkpolicies.crd.antrea.io":         policy.EngineAntrea,
+	"clusternetworkpolicies.crd.antrea.io":  policy.EngineAntrea,
+}
+
+// EngineDetector records which CNI-specific policy APIs the cluster serves
+// and resolves policyEngine "auto" against a preference order. Discovery runs
+// at startup and whenever one of the engine CRDs changes; Resolve only reads
+// the cached result.
+type EngineDetector struct {
+	discovery  discovery.DiscoveryInterface
+	preference []string
+
+	mu        sync.RWMutex
+	available map[string]bool
+}
+
+// NewEngineDetector creates an EngineDetector. An empty preference uses
+// DefaultEnginePreference.
+func NewEngineDetector(dc discovery.DiscoveryInterface, preference []string) *EngineDetector {
+	if len(preference) == 0 {
+		preference = DefaultEnginePreference
+	}
+	return &EngineDetector{
+		discovery:  dc,
+		preference: preference,
+		available:  map[string]bool{},
+	}
+}
+
+// ParseEnginePreference parses a comma-separated engine preference list, as
+// given to the --auto-engine-preference flag.
+func ParseEnginePreference(value string) ([]string, error) {
+	var preference []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, err := policy.NewPolicyEngine(name); err != nil {
+			return nil, err
+		}
+		if slices.Contains(preference, name) {
+			return nil, fmt.Errorf("duplicate policy engine in preference: %s", name)
+		}
+		preference = append(preference, name)
+	}
+	return preference, nil
+}
+
+// Refresh queries API discovery for every engine in the preference order.
+// A group version that is not served marks the engine unavailable; any other
+// discovery error is returned and leaves the previous result in place.
+func (d *EngineDetector) Refresh() error {
+	available := make(map[string]bool, len(d.preference))
+	for _, engine := range d.preference {
+		if engine == policy.EngineKubernetes {
+			available[engine] = true
+			continue
+		}
+		gvk := gvkForEngine(engine)
+		resources, err := d.discovery.ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to discover %s: %w", gvk.GroupVersion(), err)
+		}
+		for _, resource := range resources.APIResources {
+			if resource.Kind == gvk.Kind {
+				available[engine] = true
+				break
+			}
+		}
+	}
+
+	d.mu.Lock()
+	d.available = available
+	d.mu.Unlock()
+	return nil
+}
+
+// Resolve returns the first available engine in preference order, or
+// "kubernetes" when none of them is served.
+func (d *EngineDetector) Resolve() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, engine := range d.preference {
+		if d.available[engine] {
+			return engine
+		}
+	}
+	return policy.EngineKubernetes
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+	"github.com/somaz94/network-policy-generator/internal/policy"
+)
+
+// newFakeDiscovery returns a discovery client serving the given engines' policy kinds.
+func newFakeDiscovery(engines ...string) *fakediscovery.FakeDiscovery {
+	fake := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	for _, engine := range engines {
+		gvk := gvkForEngine(engine)
+		fake.Resources = append(fake.Resources, &metav1.APIResourceList{
+			GroupVersion: gvk.GroupVersion().String(),
+			APIResources: []metav1.APIResource{{Kind: gvk.Kind}},
+		})
+	}
+	return fake
+}
+
+var _ = Describe("EngineDetector", func() {
+	It("should resolve to kubernetes before discovery has run", func() {
+		detector := NewEngineDetector(newFakeDiscovery(policy.EngineCilium), nil)
+		Expect(detector.Resolve()).To(Equal(policy.EngineKubernetes))
+	})
+
+	It("should pick the first served engine in the default preference", func() {
+		detector := NewEngineDetector(newFakeDiscovery(policy.EngineCalico, policy.EngineCilium), nil)
+		Expect(detector.Refresh()).To(Succeed())
+		Expect(detector.Resolve()).To(Equal(policy.EngineCilium))
+	})
+
+	It("should follow a configured preference", func() {
+		detector := NewEngineDetector(newFakeDiscovery(policy.EngineCalico, policy.EngineCilium),
+			[]string{policy.EngineCalico, policy.EngineCilium})
+		Expect(detector.Refresh()).To(Succeed())
+		Expect(detector.Resolve()).To(Equal(policy.EngineCalico))
+	})
+
+	It("should fall back to kubernetes when no engine is served", func() {
+		detector := NewEngineDetector(newFakeDiscovery(), nil)
+		Expect(detector.Refresh()).To(Succeed())
+		Expect(detector.Resolve()).To(Equal(policy.EngineKubernetes))
+	})
+
+	It("should keep the previous result when discovery fails", func() {
+		fake := newFakeDiscovery(policy.EngineCilium)
+		detector := NewEngineDetector(fake, nil)
+		Expect(detector.Refresh()).To(Succeed())
+
+		fake.PrependReactor("get", "resource", func(clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, fmt.Errorf("discovery unavailable")
+		})
+		err := detector.Refresh()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("discovery unavailable"))
+		Expect(detector.Resolve()).To(Equal(policy.EngineCilium))
+	})
+
+	Context("ParseEnginePreference", func() {
+		It("should parse a comma-separated list", func() {
+			preference, err := ParseEnginePreference(" calico, cilium ,,antrea")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(preference).To(Equal([]string{policy.EngineCalico, policy.EngineCilium, policy.EngineAntrea}))
+		})
+
+		It("should reject unknown and duplicate engines", func() {
+			_, err := ParseEnginePreference("cilium,flannel")
+			Expect(err).To(HaveOccurred())
+
+			_, err = ParseEnginePreference("cilium,cilium")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("duplicate"))
+		})
+	})
+
+	Context("policyEngine auto", func() {
+		It("should generate policies for the resolved engine and report it in status", func() {
+			ctx := context.Background()
+			namespace, err := setupTestNamespace(ctx, k8sClient)
+			Expect(err).NotTo(HaveOccurred())
+
+			generator := &securityv1.NetworkPolicyGenerator{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      testGeneratorName + "-auto",
+					Namespace: namespace,
+				},
+				Spec: securityv1.NetworkPolicyGeneratorSpec{
+					Mode:         policy.ModeEnforcing,
+					PolicyEngine: policy.EngineAuto,
+					DryRun:       true,
+					Duration:     metav1.Duration{Duration: time.Minute},
+					Policy: securityv1.PolicyConfig{
+						Type:              policy.PolicyTypeDeny,
+						AllowedNamespaces: []string{nsOne},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			detector := NewEngineDetector(newFakeDiscovery(policy.EngineCalico), nil)
+			Expect(detector.Refresh()).To(Succeed())
+			autoReconciler := &NetworkPolicyGeneratorReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				Generator:      policy.NewGenerator(),
+				Validator:      policy.NewValidator(),
+				Recorder:       record.NewFakeRecorder(100),
+				EngineDetector: detector,
+			}
+
+			_, err = autoReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.ResolvedEngine).To(Equal(policy.EngineCalico))
+			Expect(generator.Status.GeneratedPolicies).To(HaveLen(1))
+			Expect(generator.Status.GeneratedPolicies[0]).To(ContainSubstring(policy.CalicoAPIVersion))
+		})
+
+		It("should clean up the engine auto last resolved to", func() {
+			generator := &securityv1.NetworkPolicyGenerator{
+				Spec: securityv1.NetworkPolicyGeneratorSpec{PolicyEngine: policy.EngineAuto},
+			}
+			generator.Status.ResolvedEngine = policy.EngineCilium
+			Expect(cleanupEngineTypes(generator)).To(Equal([]string{policy.EngineCilium}))
+		})
+	})
+
+	Context("engine CRD installed after startup", func() {
+		It("should start the drift watch once the kind is served", func() {
+			ciliumGVK := policy.EngineKinds(policy.EngineCilium)[0].GroupVersionKind
+			calicoGVK := policy.EngineKinds(policy.EngineCalico)[0].GroupVersionKind
+			mapper := meta.NewDefaultRESTMapper(nil)
+			watcher := &watchRecorder{}
+			r := &NetworkPolicyGeneratorReconciler{policyWatches: &policyKindWatches{
+				mapper:     mapper,
+				controller: watcher,
+				pending:    []schema.GroupVersionKind{ciliumGVK, calicoGVK},
+			}}
+
+			r.watchInstalledPolicyKinds(context.Background())
+			Expect(watcher.sources).To(BeEmpty())
+			Expect(r.policyWatches.pending).To(HaveLen(2))
+
+			mapper.Add(ciliumGVK, meta.RESTScopeNamespace)
+			r.watchInstalledPolicyKinds(context.Background())
+			Expect(watcher.sources).To(HaveLen(1))
+			Expect(r.policyWatches.pending).To(Equal([]schema.GroupVersionKind{calicoGVK}))
+
+			r.watchInstalledPolicyKinds(context.Background())
+			Expect(watcher.sources).To(HaveLen(1))
+		})
+	})
+})
+
+// watchRecorder is a controller that records the sources it is asked to watch
+type watchRecorder struct {
+	controller.Controller
+	sources []source.Source
+}
+
+func (w *watchRecorder) Watch(src source.Source) error {
+	w.sources = append(w.sources, src)
+	return nil
+}
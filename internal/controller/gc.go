@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+	"github.com/somaz94/network-policy-generator/internal/policy"
+)
+
+// setOwnerMetadata marks u as owned by the generator. Owner references
+// cannot cross namespaces, so only objects in the generator's namespace keep
+// one, replacing any the engine set; every object carries the owner labels
+// and annotation, which the finalizer uses to delete it.
+func setOwnerMetadata(u *unstructured.Unstructured, g *securityv1.NetworkPolicyGenerator, engineName string) {
+	if u.GetNamespace() == g.Namespace {
+		u.SetOwnerReferences([]metav1.OwnerReference{ownerReference(g)})
+	} else {
+		u.SetOwnerReferences(nil)
+	}
+	labels := u.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	maps.Copy(labels, ownerLabels(g, engineName))
+	u.SetLabels(labels)
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[policy.AnnotationOwner] = g.Namespace + "/" + g.Name
+	u.SetAnnotations(annotations)
+}
+
+// ownerLabels returns the labels that mark an object as applied by the
+// generator for an engine.
+func ownerLabels(g *securityv1.NetworkPolicyGenerator, engineName string) map[string]string {
+	labels := generatorLabels(g)
+	labels[policy.LabelEngine] = ownerLabelValue(engineName)
+	return labels
+}
+
+// generatorLabels returns the owner labels that select every object applied
+// by the generator, whatever the engine.
+func generatorLabels(g *securityv1.NetworkPolicyGenerator) map[string]string {
+	return map[string]string{
+		policy.LabelGeneratorName:      ownerLabelValue(g.Name),
+		policy.LabelGeneratorNamespace: g.Namespace,
+	}
+}
+
+// ownerLabelValue returns s when it is a valid label value. Longer names are
+// cut and suffixed with a hash of the full name, so they stay unique.
+func ownerLabelValue(s string) string {
+	if len(s) <= validation.LabelValueMaxLength {
+		return s
+	}
+	sum := sha256.Sum256([]byte(s))
+	hash := hex.EncodeToString(sum[:])[:10]
+	return s[:validation.LabelValueMaxLength-len(hash)-1] + "-" + hash
+}
+
+// appliedPolicy returns the inventory entry of an applied object.
+func appliedPolicy(u *unstructured.Unstructured, engineName string) securityv1.AppliedPolicy {
+	return securityv1.AppliedPolicy{
+		Engine:     engineName,
+		APIVersion: u.GetAPIVersion(),
+		Kind:       u.GetKind(),
+		Namespace:  u.GetNamespace(),
+		Name:       u.GetName(),
+		Generation: u.GetGeneration(),
+	}
+}
+
+// sameObject reports whether two inventory entries refer to the same object.
+func sameObject(a, b securityv1.AppliedPolicy) bool {
+	return a.APIVersion == b.APIVersion && a.Kind == b.Kind && a.Namespace == b.Namespace && a.Name == b.Name
+}
+
+// collectGarbage deletes the objects the generator applied earlier that are
+// not in desired: the entries of status.appliedPolicies, and every object of
+// the engines' kinds that carries the generator's owner labels. Each deletion
+// emits a PolicyDeleted event.
+func (r *NetworkPolicyGeneratorReconciler) collectGarbage(
+	ctx context.Context,
+	generator *securityv1.NetworkPolicyGenerator,
+	desired []securityv1.AppliedPolicy,
+	engineTypes []string,
+) error {
+	log := log.FromContext(ctx)
+
+	candidates := slices.Clone(generator.Status.AppliedPolicies)
+	labeled, err := r.listLabeledPolicies(ctx, generator, engineTypes)
+	if err != nil {
+		return err
+	}
+	candidates = append(candidates, labeled...)
+
+	var deleted []securityv1.AppliedPolicy
+	for _, stale := range candidates {
+		isDesired := slices.ContainsFunc(desired, func(p securityv1.AppliedPolicy) bool { return sameObject(p, stale) })
+		isDeleted := slices.ContainsFunc(deleted, func(p securityv1.AppliedPolicy) bool { return sameObject(p, stale) })
+		if isDesired || isDeleted {
+			continue
+		}
+
+		gvk := schema.FromAPIVersionAndKind(stale.APIVersion, stale.Kind)
+		if err := r.deleteUnstructuredPolicy(ctx, stale.Namespace, stale.Name, gvk); err != nil {
+			r.Recorder.Eventf(generator, "Warning", "CleanupFailed",
+				"Failed to delete stale %s policy %s/%s: %v", stale.Engine, stale.Namespace, stale.Name, err)
+			log.Error(err, "failed to delete stale policy",
+				"engine", stale.Engine, "kind", stale.Kind, "namespace", stale.Namespace, "name", stale.Name)
+			return err
+		}
+		deleted = append(deleted, stale)
+
+		r.Recorder.Eventf(generator, "Normal", "PolicyDeleted",
+			"Deleted stale %s policy %s/%s", stale.Engine, stale.Namespace, stale.Name)
+		PolicyOperations.WithLabelValues("Deleted").Inc()
+		log.Info("Deleted stale policy",
+			"engine", stale.Engine, "kind", stale.Kind, "namespace", stale.Namespace, "name", stale.Name)
+	}
+	return nil
+}
+
+// listLabeledPolicies lists the objects of the engines' kinds that carry the
+// generator's owner labels. Kinds the API server does not serve are skipped.
+func (r *NetworkPolicyGeneratorReconciler) listLabeledPolicies(
+	ctx context.Context,
+	generator *securityv1.NetworkPolicyGenerator,
+	engineTypes []string,
+) ([]securityv1.AppliedPolicy, error) {
+	var found []securityv1.AppliedPolicy
+	for _, engineType := range engineTypes {
+		for _, kind := range r.engineKinds(engineType) {
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(kind.GroupVersionKind.GroupVersion().WithKind(kind.Kind + "List"))
+			if err := r.List(ctx, list, client.MatchingLabels(generatorLabels(generator))); err != nil {
+				if meta.IsNoMatchError(err) {
+					continue
+				}
+				return nil, err
+			}
+			for i := range list.Items {
+				item := &list.Items[i]
+				engineName := item.GetLabels()[policy.LabelEngine]
+				if engineName == "" {
+					engineName = engineType
+				}
+				found = append(found, appliedPolicy(item, engineName))
+			}
+		}
+	}
+	return found, nil
+}
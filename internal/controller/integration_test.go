@@ -11,6 +11,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
@@ -90,8 +91,9 @@ var _ = Describe("NetworkPolicyGenerator Integration", func() {
 					Mode:     policy.ModeEnforcing,
 					Duration: metav1.Duration{Duration: time.Minute},
 					Policy: securityv1.PolicyConfig{
-						Type:              policy.PolicyTypeDeny,
-						AllowedNamespaces: []string{targetNs},
+						Type:               policy.PolicyTypeDeny,
+						AllowedNamespaces:  []string{targetNs},
+						AllowSameNamespace: ptr.To(false),
 					},
 					GlobalRules: []securityv1.GlobalRule{
 						{
@@ -20,6 +20,8 @@ func (r *NetworkPolicyGeneratorReconciler) handleLearningMode(
 ) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
+	conditionsChanged := markLearning(generator)
+
 	// Initial setup for a freshly created generator.
 	if generator.Status.Phase == "" || generator.Status.LastAnalyzed.IsZero() {
 		generator.Status.Phase = policy.PhaseLearning
@@ -50,6 +52,7 @@ func (r *NetworkPolicyGeneratorReconciler) handleLearningMode(
 			len(generator.Status.SuggestedRules))
 
 		generator.Status.Phase = policy.PhaseEnforcing
+		markLearningCompleted(generator)
 		if err := r.Status().Update(ctx, generator); err != nil {
 			log.Error(err, "failed to update status to Enforcing")
 			return ctrl.Result{}, err
@@ -64,6 +67,12 @@ func (r *NetworkPolicyGeneratorReconciler) handleLearningMode(
 		return ctrl.Result{Requeue: true}, nil
 	}
 
+	if conditionsChanged {
+		if err := r.Status().Update(ctx, generator); err != nil {
+			log.Error(err, "failed to update status conditions")
+			return ctrl.Result{}, err
+		}
+	}
 	return ctrl.Result{RequeueAfter: generator.Spec.Duration.Duration - elapsed}, nil
 }
 
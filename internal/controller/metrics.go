@@ -33,6 +33,15 @@ var (
 		[]string{"action"}, // "Created", "Updated", "Deleted"
 	)
 
+	// PolicyDrift counts generated policies found edited or deleted by hand
+	PolicyDrift = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "npg_policy_drift_total",
+			Help: "Total number of drifted policies by action",
+		},
+		[]string{"name", "namespace", "engine", "action"}, // action: "Corrected", "Reported"
+	)
+
 	// GeneratorsActive tracks the number of active generators by phase
 	GeneratorsActive = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
@@ -75,6 +84,7 @@ func init() {
 		ReconcileTotal,
 		PoliciesApplied,
 		PolicyOperations,
+		PolicyDrift,
 		GeneratorsActive,
 		ReconcileDuration,
 		DryRunTotal,
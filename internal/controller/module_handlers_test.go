@@ -3,18 +3,28 @@ package controller
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/somaz94/network-policy-generator/internal/policy"
 )
@@ -136,6 +146,89 @@ var _ = Describe("Mode Handlers", func() {
 				return networkPolicy.Spec.Ingress
 			}, timeout, interval).Should(Not(BeEmpty()))
 		})
+
+		It("should apply the default IP families when the spec omits them", func() {
+			reconciler.DefaultIPFamilies = []string{policy.IPFamilyIPv4, policy.IPFamilyIPv6}
+			generator := createBasicGenerator(namespace, generatorName)
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Status.Phase = policy.PhaseEnforcing
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+
+			networkPolicy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      generatorName + "-generated",
+				Namespace: namespace,
+			}, networkPolicy)).To(Succeed())
+
+			var cidrs []string
+			for _, rule := range networkPolicy.Spec.Egress {
+				for _, peer := range rule.To {
+					if peer.IPBlock != nil {
+						cidrs = append(cidrs, peer.IPBlock.CIDR)
+					}
+				}
+			}
+			Expect(cidrs).To(ContainElements(policy.CIDRAllTraffic, policy.CIDRAllTrafficIPv6))
+		})
+	})
+
+	Context("Cluster IP Families", func() {
+		It("should use the API server Service's IP families when no default is configured", func() {
+			svc := &corev1.Service{}
+			key := types.NamespacedName{Namespace: metav1.NamespaceDefault, Name: apiServerServiceName}
+			if err := k8sClient.Get(ctx, key, svc); apierrors.IsNotFound(err) {
+				// A real API server creates the Service itself
+				svc = &corev1.Service{
+					ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+					Spec: corev1.ServiceSpec{
+						Ports:      []corev1.ServicePort{{Port: 443}},
+						IPFamilies: []corev1.IPFamily{corev1.IPv6Protocol},
+					},
+				}
+				Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+				DeferCleanup(func() {
+					Expect(k8sClient.Delete(ctx, svc)).To(Succeed())
+				})
+			} else {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			var clusterFamilies []string
+			for _, family := range svc.Spec.IPFamilies {
+				clusterFamilies = append(clusterFamilies, string(family))
+			}
+			Expect(clusterFamilies).NotTo(BeEmpty())
+
+			reconciler.DefaultIPFamilies = nil
+			generator := createBasicGenerator(namespace, generatorName+"-cluster-families")
+			generator.Spec.Mode = policy.ModeEnforcing
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			Expect(reconciler.defaultIPFamilies(ctx)).To(Equal(clusterFamilies))
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+
+			networkPolicy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name:      generator.Name + "-generated",
+				Namespace: namespace,
+			}, networkPolicy)).To(Succeed())
+			var cidrs []string
+			for _, rule := range networkPolicy.Spec.Egress {
+				for _, peer := range rule.To {
+					if peer.IPBlock != nil {
+						cidrs = append(cidrs, peer.IPBlock.CIDR)
+					}
+				}
+			}
+			Expect(cidrs).To(Equal(policy.AllTrafficCIDRs(clusterFamilies)))
+
+			By("Preferring --default-ip-families when it is set")
+			reconciler.DefaultIPFamilies = []string{policy.IPFamilyIPv4, policy.IPFamilyIPv6}
+			Expect(reconciler.defaultIPFamilies(ctx)).To(Equal(reconciler.DefaultIPFamilies))
+		})
 	})
 
 	Context("Enforcing Mode with PolicyEngine", func() {
@@ -505,8 +598,8 @@ var _ = Describe("Mode Handlers", func() {
 			generator.Spec.Mode = policy.ModeEnforcing
 			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
 
-			// Mock that fails on Create (applyNetworkPolicy)
-			mockCl := &mockClient{Client: k8sClient, createError: fmt.Errorf("create policy failed")}
+			// Mock that fails on Apply (applyPolicyWithDiff)
+			mockCl := &mockClient{Client: k8sClient, applyError: fmt.Errorf("create policy failed")}
 			errReconciler := &NetworkPolicyGeneratorReconciler{
 				Client:    mockCl,
 				Scheme:    k8sClient.Scheme(),
@@ -682,6 +775,88 @@ var _ = Describe("Mode Handlers", func() {
 		})
 	})
 
+	Context("Status Conditions", func() {
+		fetch := func(name string) *securityv1.NetworkPolicyGenerator {
+			generator := &securityv1.NetworkPolicyGenerator{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, generator)).To(Succeed())
+			return generator
+		}
+
+		It("should report Ready once policies are applied", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-cond-enforced")
+			generator.Spec.Mode = policy.ModeEnforcing
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+
+			generator = fetch(generatorName + "-cond-enforced")
+			Expect(generator.Status.ObservedGeneration).To(Equal(generator.Generation))
+			Expect(meta.IsStatusConditionTrue(generator.Status.Conditions, policy.ConditionReady)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(generator.Status.Conditions, policy.ConditionPoliciesApplied)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(generator.Status.Conditions, policy.ConditionLearning)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(generator.Status.Conditions, policy.ConditionDegraded)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(generator.Status.Conditions, policy.ConditionDryRun)).To(BeTrue())
+		})
+
+		It("should report DryRun and not Ready in dry-run mode", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-cond-dryrun")
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.DryRun = true
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+
+			generator = fetch(generatorName + "-cond-dryrun")
+			Expect(meta.IsStatusConditionTrue(generator.Status.Conditions, policy.ConditionDryRun)).To(BeTrue())
+			ready := meta.FindStatusCondition(generator.Status.Conditions, policy.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Reason).To(Equal(policy.ReasonDryRun))
+		})
+
+		It("should report Learning while observing traffic", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-cond-learning")
+			generator.Spec.Duration = metav1.Duration{Duration: time.Minute}
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			_, err := reconciler.handleLearningMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+
+			generator = fetch(generatorName + "-cond-learning")
+			Expect(meta.IsStatusConditionTrue(generator.Status.Conditions, policy.ConditionLearning)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(generator.Status.Conditions, policy.ConditionReady)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(generator.Status.Conditions, policy.ConditionPoliciesApplied)).To(BeTrue())
+		})
+
+		It("should report Degraded when the reconcile fails", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-cond-degraded")
+			generator.Spec.Mode = policy.ModeEnforcing
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			failingReconciler := &NetworkPolicyGeneratorReconciler{
+				Client:    &mockClient{Client: k8sClient, applyError: fmt.Errorf("apply policy failed")},
+				Scheme:    k8sClient.Scheme(),
+				Generator: policy.NewGenerator(),
+				Validator: policy.NewValidator(),
+				Recorder:  record.NewFakeRecorder(100),
+			}
+			_, err := failingReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: generatorName + "-cond-degraded", Namespace: namespace},
+			})
+			Expect(err).To(HaveOccurred())
+
+			generator = fetch(generatorName + "-cond-degraded")
+			degraded := meta.FindStatusCondition(generator.Status.Conditions, policy.ConditionDegraded)
+			Expect(degraded).NotTo(BeNil())
+			Expect(degraded.Status).To(Equal(metav1.ConditionTrue))
+			Expect(degraded.Message).To(ContainSubstring("apply policy failed"))
+			Expect(meta.IsStatusConditionFalse(generator.Status.Conditions, policy.ConditionReady)).To(BeTrue())
+			Expect(generator.Status.ObservedGeneration).To(Equal(generator.Generation))
+		})
+	})
+
 	Context("Kubernetes Dry Run Mode", func() {
 		It("should store generated policies in status without applying", func() {
 			generator := createBasicGenerator(namespace, generatorName+"-k8s-dryrun")
@@ -812,32 +987,415 @@ var _ = Describe("Mode Handlers", func() {
 			}, generator)).To(Succeed())
 			Expect(generator.Status.PolicyDiff).NotTo(BeEmpty())
 			Expect(generator.Status.PolicyDiff[0].Action).To(Equal("Created"))
+			Expect(generator.Status.PolicyDiff[0].Changes).NotTo(BeEmpty())
+			Expect(generator.Status.PolicyDiff[0].Patch).To(BeEmpty())
 			Expect(generator.Status.AppliedPoliciesCount).To(Equal(1))
 		})
 
-		It("should track Updated action for existing policies", func() {
+		It("should track Unchanged and Updated actions for existing policies", func() {
 			generator := createBasicGenerator(namespace, generatorName+"-diff-update")
 			generator.Spec.Mode = policy.ModeEnforcing
 			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+			key := types.NamespacedName{Name: generatorName + "-diff-update", Namespace: namespace}
+
+			recorder := record.NewFakeRecorder(100)
+			diffReconciler := &NetworkPolicyGeneratorReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: policy.NewGenerator(),
+				Validator: policy.NewValidator(),
+				Recorder:  recorder,
+			}
 
 			// First apply creates the policy
+			_, err := diffReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("PolicyCreated")))
+
+			// Applying the same spec again is a no-op
+			Expect(k8sClient.Get(ctx, key, generator)).To(Succeed())
+			_, err = diffReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, generator)).To(Succeed())
+			Expect(generator.Status.PolicyDiff).NotTo(BeEmpty())
+			Expect(generator.Status.PolicyDiff[0].Action).To(Equal("Unchanged"))
+			Expect(generator.Status.PolicyDiff[0].Changes).To(BeEmpty())
+			Expect(generator.Status.PolicyDiff[0].Patch).To(BeEmpty())
+			Expect(recordedEvents(recorder)).NotTo(ContainElement(ContainSubstring("PolicyUpdated")))
+
+			// Changing the spec updates the policy
+			generator.Spec.Policy.PodSelector = map[string]string{"app": "web"}
+			_, err = diffReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, generator)).To(Succeed())
+			Expect(generator.Status.PolicyDiff).NotTo(BeEmpty())
+			Expect(generator.Status.PolicyDiff[0].Action).To(Equal("Updated"))
+			Expect(generator.Status.PolicyDiff[0].Changes).To(ConsistOf(`spec.podSelector.matchLabels.app: added "web"`))
+			Expect(generator.Status.PolicyDiff[0].Patch).To(MatchJSON(
+				`[{"op":"add","path":"/spec/podSelector/matchLabels","value":{"app":"web"}}]`))
+			Expect(recordedEvents(recorder)).To(ContainElement(SatisfyAll(
+				ContainSubstring("PolicyUpdated"), ContainSubstring("spec.podSelector.matchLabels"))))
+		})
+
+		It("should apply policies under the controller's field manager", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-field-manager")
+			generator.Spec.Mode = policy.ModeEnforcing
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
 			_, err := reconciler.handleEnforcingMode(ctx, generator)
 			Expect(err).NotTo(HaveOccurred())
 
-			// Re-fetch
+			np := &networkingv1.NetworkPolicy{}
 			Expect(k8sClient.Get(ctx, types.NamespacedName{
-				Name: generatorName + "-diff-update", Namespace: namespace,
-			}, generator)).To(Succeed())
+				Name: generatorName + "-field-manager-generated", Namespace: namespace,
+			}, np)).To(Succeed())
+			Expect(np.ManagedFields).To(ContainElement(And(
+				HaveField("Manager", fieldManager),
+				HaveField("Operation", metav1.ManagedFieldsOperationApply),
+			)))
+		})
 
-			// Second apply should be "Updated"
-			_, err = reconciler.handleEnforcingMode(ctx, generator)
+		It("should report a conflict with fields owned by another manager", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-conflict")
+			generator.Spec.Mode = policy.ModeEnforcing
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			By("Creating the policy with another field manager")
+			other := &networkingv1.NetworkPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: generatorName + "-conflict-generated", Namespace: namespace},
+				Spec: networkingv1.NetworkPolicySpec{
+					PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+				},
+			}
+			Expect(k8sClient.Create(ctx, other, client.FieldOwner("someone-else"))).To(Succeed())
+
+			recorder := record.NewFakeRecorder(100)
+			conflictReconciler := &NetworkPolicyGeneratorReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: policy.NewGenerator(),
+				Validator: policy.NewValidator(),
+				Recorder:  recorder,
+			}
+			_, err := conflictReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).To(HaveOccurred())
+			Expect(apierrors.IsConflict(err)).To(BeTrue())
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("ApplyConflict")))
+		})
+	})
+
+	Context("Policy Revisions", func() {
+		var (
+			recorder           *record.FakeRecorder
+			revisionReconciler *NetworkPolicyGeneratorReconciler
+		)
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(100)
+			revisionReconciler = &NetworkPolicyGeneratorReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: policy.NewGenerator(),
+				Validator: policy.NewValidator(),
+				Recorder:  recorder,
+			}
+		})
+
+		// enforce updates the generator's pod selector and applies it
+		enforce := func(generator *securityv1.NetworkPolicyGenerator, app string) {
+			generator.Spec.Policy.PodSelector = map[string]string{"app": app}
+			Expect(k8sClient.Update(ctx, generator)).To(Succeed())
+			_, err := revisionReconciler.handleEnforcingMode(ctx, generator)
 			Expect(err).NotTo(HaveOccurred())
+		}
+
+		revisions := func(generator *securityv1.NetworkPolicyGenerator) []appsv1.ControllerRevision {
+			revs, err := revisionReconciler.listRevisions(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			return revs
+		}
 
+		enforcedApp := func(generator *securityv1.NetworkPolicyGenerator) string {
+			np := &networkingv1.NetworkPolicy{}
 			Expect(k8sClient.Get(ctx, types.NamespacedName{
-				Name: generatorName + "-diff-update", Namespace: namespace,
-			}, generator)).To(Succeed())
-			Expect(generator.Status.PolicyDiff).NotTo(BeEmpty())
-			Expect(generator.Status.PolicyDiff[0].Action).To(Equal("Updated"))
+				Name: generator.Name + "-generated", Namespace: namespace,
+			}, np)).To(Succeed())
+			return np.Spec.PodSelector.MatchLabels["app"]
+		}
+
+		It("should record a revision per distinct set of policies", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-revisions")
+			generator.Spec.Mode = policy.ModeEnforcing
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			enforce(generator, "web")
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(1)))
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("RevisionCreated")))
+
+			By("Applying the same policies again")
+			enforce(generator, "web")
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(1)))
+			Expect(revisions(generator)).To(HaveLen(1))
+
+			By("Changing the policies")
+			enforce(generator, "api")
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(2)))
+			Expect(revisions(generator)).To(HaveLen(2))
+
+			By("Returning to the first policies")
+			enforce(generator, "web")
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(3)))
+			revs := revisions(generator)
+			Expect(revs).To(HaveLen(2))
+			Expect(revs[0].Revision).To(Equal(int64(2)))
+			Expect(revs[1].Revision).To(Equal(int64(3)))
+			Expect(revs[1].Labels).To(Equal(generatorLabels(generator)))
+			Expect(metav1.IsControlledBy(&revs[1], generator)).To(BeTrue())
+		})
+
+		It("should prune revisions beyond revisionHistoryLimit", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-revision-limit")
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.RevisionHistoryLimit = ptr.To(int32(1))
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			for _, app := range []string{"web", "api", "db"} {
+				enforce(generator, app)
+			}
+
+			revs := revisions(generator)
+			Expect(revs).To(HaveLen(2))
+			Expect(revs[0].Revision).To(Equal(int64(2)))
+			Expect(revs[1].Revision).To(Equal(int64(3)))
+		})
+
+		It("should roll back to and pin an earlier revision", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-rollback")
+			generator.Spec.Mode = policy.ModeEnforcing
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			enforce(generator, "web")
+			enforce(generator, "api")
+			Expect(enforcedApp(generator)).To(Equal("api"))
+			recordedEvents(recorder)
+
+			By("Rolling back to the first revision")
+			generator.Spec.RollbackTo = ptr.To(int64(1))
+			enforce(generator, "api")
+			Expect(enforcedApp(generator)).To(Equal("web"))
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(1)))
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("RolledBack")))
+			ready := meta.FindStatusCondition(generator.Status.Conditions, policy.ConditionReady)
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Reason).To(Equal(policy.ReasonRolledBack))
+
+			By("Keeping the revision pinned while the spec changes")
+			enforce(generator, "db")
+			Expect(enforcedApp(generator)).To(Equal("web"))
+			Expect(revisions(generator)).To(HaveLen(2))
+
+			By("Clearing rollbackTo")
+			generator.Spec.RollbackTo = nil
+			enforce(generator, "db")
+			Expect(enforcedApp(generator)).To(Equal("db"))
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(3)))
+		})
+
+		It("should leave discovered node addresses out of revisions and resolve them on rollback", func() {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: namespace + "-revision-node"}}
+			Expect(k8sClient.Create(ctx, node)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+			}()
+			setNodeAddress := func(address string) {
+				node.Status.Addresses = []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: address}}
+				Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+			}
+			nodePeers := func(generator *securityv1.NetworkPolicyGenerator) []string {
+				np := &networkingv1.NetworkPolicy{}
+				Expect(k8sClient.Get(ctx, types.NamespacedName{
+					Name: generator.Name + "-generated", Namespace: namespace,
+				}, np)).To(Succeed())
+				var cidrs []string
+				for _, rule := range np.Spec.Ingress {
+					for _, peer := range rule.From {
+						if peer.IPBlock != nil && strings.HasPrefix(peer.IPBlock.CIDR, "192.168.20.") {
+							cidrs = append(cidrs, peer.IPBlock.CIDR)
+						}
+					}
+				}
+				return cidrs
+			}
+
+			generator := createBasicGenerator(namespace, generatorName+"-revision-nodes")
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.AllowNodeTraffic = true
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			setNodeAddress("192.168.20.5")
+			enforce(generator, "web")
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(1)))
+			Expect(nodePeers(generator)).To(Equal([]string{"192.168.20.5/32"}))
+
+			By("Changing the node's address")
+			setNodeAddress("192.168.20.6")
+			enforce(generator, "web")
+			Expect(nodePeers(generator)).To(Equal([]string{"192.168.20.6/32"}))
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(1)))
+			Expect(revisions(generator)).To(HaveLen(1))
+
+			enforce(generator, "api")
+			Expect(generator.Status.CurrentRevision).To(Equal(int64(2)))
+
+			By("Rolling back after the node changed again")
+			setNodeAddress("192.168.20.7")
+			generator.Spec.RollbackTo = ptr.To(int64(1))
+			enforce(generator, "api")
+			Expect(enforcedApp(generator)).To(Equal("web"))
+			Expect(nodePeers(generator)).To(Equal([]string{"192.168.20.7/32"}))
+			Expect(generator.Status.NodeCIDRs).To(Equal([]string{"192.168.20.7/32"}))
+		})
+
+		It("should fail to roll back to a missing revision", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-rollback-missing")
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.RollbackTo = ptr.To(int64(5))
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			_, err := revisionReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).To(MatchError(ContainSubstring("policy revision 5 not found")))
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("RollbackFailed")))
+		})
+	})
+
+	Context("Drift Detection", func() {
+		var (
+			recorder        *record.FakeRecorder
+			driftReconciler *NetworkPolicyGeneratorReconciler
+		)
+
+		BeforeEach(func() {
+			recorder = record.NewFakeRecorder(100)
+			driftReconciler = &NetworkPolicyGeneratorReconciler{
+				Client:    k8sClient,
+				Scheme:    k8sClient.Scheme(),
+				Generator: policy.NewGenerator(),
+				Validator: policy.NewValidator(),
+				Recorder:  recorder,
+			}
+		})
+
+		// applyAndEdit enforces the generator, then narrows the generated
+		// policy to ingress by hand
+		applyAndEdit := func(generator *securityv1.NetworkPolicyGenerator) *networkingv1.NetworkPolicy {
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+			_, err := driftReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.AppliedPolicies).To(ContainElement(HaveField("Generation", Not(BeZero()))))
+
+			np := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{
+				Name: generator.Name + "-generated", Namespace: namespace,
+			}, np)).To(Succeed())
+			np.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+			Expect(k8sClient.Update(ctx, np)).To(Succeed())
+			recordedEvents(recorder)
+			return np
+		}
+
+		It("should revert a hand-edited policy", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-drift-enforce")
+			generator.Spec.Mode = policy.ModeEnforcing
+			np := applyAndEdit(generator)
+
+			_, err := driftReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("DriftCorrected")))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(np), np)).To(Succeed())
+			Expect(np.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+		})
+
+		It("should only report drift with driftPolicy report", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-drift-report")
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.DriftPolicy = policy.DriftPolicyReport
+			np := applyAndEdit(generator)
+
+			_, err := driftReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("DriftDetected")))
+			Expect(generator.Status.AppliedPolicies).To(ContainElement(HaveField("ReportedDrift", np.ResourceVersion)))
+
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(np), np)).To(Succeed())
+			Expect(np.Spec.PolicyTypes).To(Equal([]networkingv1.PolicyType{networkingv1.PolicyTypeIngress}))
+
+			By("not reporting the same drift again")
+			_, err = driftReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recordedEvents(recorder)).NotTo(ContainElement(ContainSubstring("DriftDetected")))
+
+			By("reporting a further edit")
+			np.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}
+			Expect(k8sClient.Update(ctx, np)).To(Succeed())
+			_, err = driftReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("DriftDetected")))
+			Expect(generator.Status.AppliedPolicies).To(ContainElement(HaveField("ReportedDrift", np.ResourceVersion)))
+		})
+
+		It("should restore a deleted policy", func() {
+			generator := createBasicGenerator(namespace, generatorName+"-drift-delete")
+			generator.Spec.Mode = policy.ModeEnforcing
+			np := applyAndEdit(generator)
+			Expect(k8sClient.Delete(ctx, np)).To(Succeed())
+
+			_, err := driftReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(recordedEvents(recorder)).To(ContainElement(ContainSubstring("DriftCorrected")))
+			Expect(generator.Status.PolicyDiff[0].Action).To(Equal(policy.DiffActionCreated))
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(np), np)).To(Succeed())
+		})
+
+		It("should map labeled policies to their generator", func() {
+			longName := generatorName + "-" + strings.Repeat("x", 70)
+			for _, name := range []string{generatorName + "-drift-map", longName} {
+				generator := createBasicGenerator(namespace, name)
+				Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+				obj := &metav1.PartialObjectMetadata{}
+				obj.SetNamespace("elsewhere")
+				obj.SetLabels(ownerLabels(generator, policy.EngineKubernetes))
+				Expect(driftReconciler.ownerGenerators(ctx, obj)).To(ConsistOf(reconcile.Request{
+					NamespacedName: types.NamespacedName{Name: name, Namespace: namespace},
+				}))
+			}
+
+			annotated := &metav1.PartialObjectMetadata{}
+			annotated.SetAnnotations(map[string]string{policy.AnnotationOwner: namespace + "/" + longName})
+			Expect(driftReconciler.ownerGenerators(ctx, annotated)).To(ConsistOf(reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: longName, Namespace: namespace},
+			}))
+
+			Expect(driftReconciler.ownerGenerators(ctx, &metav1.PartialObjectMetadata{})).To(BeEmpty())
+		})
+
+		It("should only pass spec changes and deletions of labeled policies", func() {
+			labeled := &metav1.PartialObjectMetadata{}
+			labeled.SetLabels(map[string]string{policy.LabelGeneratorName: generatorName})
+			labeled.SetGeneration(1)
+			edited := labeled.DeepCopy()
+			edited.SetGeneration(2)
+			unlabeled := &metav1.PartialObjectMetadata{}
+
+			p := driftPredicate()
+			Expect(p.Create(event.CreateEvent{Object: labeled})).To(BeFalse())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: labeled, ObjectNew: labeled})).To(BeFalse())
+			Expect(p.Update(event.UpdateEvent{ObjectOld: labeled, ObjectNew: edited})).To(BeTrue())
+			Expect(p.Delete(event.DeleteEvent{Object: labeled})).To(BeTrue())
+			Expect(p.Delete(event.DeleteEvent{Object: unlabeled})).To(BeFalse())
 		})
 	})
 
@@ -977,6 +1535,29 @@ var _ = Describe("Mode Handlers", func() {
 		})
 	})
 
+	Context("AntreaGVK helper", func() {
+		It("should return correct GVK", func() {
+			gvk := gvkForEngine(policy.EngineAntrea)
+			Expect(gvk.Group).To(Equal(policy.AntreaGroup))
+			Expect(gvk.Version).To(Equal(policy.AntreaVersion))
+			Expect(gvk.Kind).To(Equal(policy.AntreaKind))
+		})
+
+		It("should prefer the object's own kind for cluster-scoped policies", func() {
+			obj := &policy.AntreaClusterNetworkPolicy{
+				TypeMeta: metav1.TypeMeta{APIVersion: policy.AntreaAPIVersion, Kind: policy.AntreaClusterKind},
+			}
+			gvk := gvkForObject(obj, policy.EngineAntrea)
+			Expect(gvk.Group).To(Equal(policy.AntreaGroup))
+			Expect(gvk.Kind).To(Equal(policy.AntreaClusterKind))
+		})
+
+		It("should fall back to the engine GVK for objects without TypeMeta", func() {
+			gvk := gvkForObject(&networkingv1.NetworkPolicy{}, policy.EngineKubernetes)
+			Expect(gvk).To(Equal(networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy")))
+		})
+	})
+
 	Context("Calico Enforcing Mode", func() {
 		It("should attempt calico policy generation", func() {
 			generator := &securityv1.NetworkPolicyGenerator{
@@ -1020,9 +1601,9 @@ var _ = Describe("Mode Handlers", func() {
 			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
 
 			mockCl := &mockClient{
-				Client:     k8sClient,
-				getError:   apierrors.NewNotFound(schema.GroupResource{Group: policy.CalicoGroup, Resource: resourceNetworkPolicies}, ""),
-				noopCreate: true,
+				Client:    k8sClient,
+				getError:  apierrors.NewNotFound(schema.GroupResource{Group: policy.CalicoGroup, Resource: resourceNetworkPolicies}, ""),
+				noopApply: true,
 			}
 			calicoReconciler := &NetworkPolicyGeneratorReconciler{
 				Client:    mockCl,
@@ -1230,6 +1811,46 @@ var _ = Describe("Mode Handlers", func() {
 		})
 	})
 
+	Context("deleteNetworkPolicies with Antrea engine", func() {
+		It("should delete the cluster-scoped policy once", func() {
+			generator := &securityv1.NetworkPolicyGenerator{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      generatorName + "-del-antrea",
+					Namespace: namespace,
+				},
+				Spec: securityv1.NetworkPolicyGeneratorSpec{
+					Mode:         policy.ModeEnforcing,
+					PolicyEngine: policy.EngineAntrea,
+					Antrea:       &securityv1.AntreaConfig{ClusterScoped: true},
+					Policy: securityv1.PolicyConfig{
+						Type:             policy.PolicyTypeAllow,
+						DeniedNamespaces: []string{"denied-a", "denied-b"},
+					},
+				},
+			}
+
+			mockCl := &mockClient{
+				Client:      k8sClient,
+				deleteError: fmt.Errorf("antrea delete failed"),
+			}
+			antreaReconciler := &NetworkPolicyGeneratorReconciler{
+				Client:    mockCl,
+				Scheme:    k8sClient.Scheme(),
+				Generator: policy.NewGenerator(),
+				Validator: policy.NewValidator(),
+				Recorder:  record.NewFakeRecorder(100),
+			}
+
+			err := antreaReconciler.deleteNetworkPolicies(ctx, generator)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("antrea delete failed"))
+
+			mockCl.deleteError = nil
+			mockCl.noopDelete = true
+			Expect(antreaReconciler.deleteNetworkPolicies(ctx, generator)).To(Succeed())
+		})
+	})
+
 	Context("deleteNetworkPolicies with Cilium engine", func() {
 		It("should delete cilium policies via deleteUnstructuredPolicy", func() {
 			generator := &securityv1.NetworkPolicyGenerator{
@@ -1504,11 +2125,11 @@ var _ = Describe("Mode Handlers", func() {
 			}
 			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
 
-			// Mock: Get returns NotFound, Create fails
+			// Mock: Get returns NotFound, Apply fails
 			mockCl := &mockClient{
-				Client:      k8sClient,
-				getError:    apierrors.NewNotFound(schema.GroupResource{Group: policy.CalicoGroup, Resource: resourceNetworkPolicies}, ""),
-				createError: fmt.Errorf("calico create failed"),
+				Client:     k8sClient,
+				getError:   apierrors.NewNotFound(schema.GroupResource{Group: policy.CalicoGroup, Resource: resourceNetworkPolicies}, ""),
+				applyError: fmt.Errorf("calico create failed"),
 			}
 			calicoReconciler := &NetworkPolicyGeneratorReconciler{
 				Client:    mockCl,
@@ -1579,7 +2200,7 @@ var _ = Describe("Mode Handlers", func() {
 			mockCl := &mockClient{
 				Client:            k8sClient,
 				getError:          apierrors.NewNotFound(schema.GroupResource{Group: policy.CalicoGroup, Resource: resourceNetworkPolicies}, ""),
-				noopCreate:        true,
+				noopApply:         true,
 				statusUpdateError: fmt.Errorf("calico status update failed"),
 			}
 			calicoReconciler := &NetworkPolicyGeneratorReconciler{
@@ -1613,11 +2234,12 @@ var _ = Describe("Mode Handlers", func() {
 			}
 			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
 
-			// Mock: Get returns nil (existing found via noop), Update succeeds (noop)
+			// Mock: Get returns nil (existing found via noop), Apply succeeds (noop)
 			mockCl := &mockClient{
 				Client:     k8sClient,
 				noopGet:    true,
 				noopUpdate: true,
+				noopApply:  true,
 			}
 			calicoReconciler := &NetworkPolicyGeneratorReconciler{
 				Client:    mockCl,
@@ -1742,9 +2364,9 @@ var _ = Describe("Mode Handlers", func() {
 
 			// Mock: Get returns NotFound (no existing cilium policy), Create succeeds (noop)
 			mockCl := &mockClient{
-				Client:     k8sClient,
-				getError:   apierrors.NewNotFound(schema.GroupResource{Group: policy.CiliumGroup, Resource: resourceCiliumNetworkPolicies}, ""),
-				noopCreate: true,
+				Client:    k8sClient,
+				getError:  apierrors.NewNotFound(schema.GroupResource{Group: policy.CiliumGroup, Resource: resourceCiliumNetworkPolicies}, ""),
+				noopApply: true,
 			}
 			ciliumReconciler := &NetworkPolicyGeneratorReconciler{
 				Client:    mockCl,
@@ -1777,11 +2399,12 @@ var _ = Describe("Mode Handlers", func() {
 			}
 			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
 
-			// Mock: Get returns nil (existing found via noop), Update succeeds (noop)
+			// Mock: Get returns nil (existing found via noop), Apply succeeds (noop)
 			mockCl := &mockClient{
 				Client:     k8sClient,
 				noopGet:    true,
 				noopUpdate: true,
+				noopApply:  true,
 			}
 			ciliumReconciler := &NetworkPolicyGeneratorReconciler{
 				Client:    mockCl,
@@ -1814,11 +2437,11 @@ var _ = Describe("Mode Handlers", func() {
 			}
 			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
 
-			// Mock: Get returns NotFound, Create fails
+			// Mock: Get returns NotFound, Apply fails
 			mockCl := &mockClient{
-				Client:      k8sClient,
-				getError:    apierrors.NewNotFound(schema.GroupResource{Group: policy.CiliumGroup, Resource: resourceCiliumNetworkPolicies}, ""),
-				createError: fmt.Errorf("cilium create failed"),
+				Client:     k8sClient,
+				getError:   apierrors.NewNotFound(schema.GroupResource{Group: policy.CiliumGroup, Resource: resourceCiliumNetworkPolicies}, ""),
+				applyError: fmt.Errorf("cilium create failed"),
 			}
 			ciliumReconciler := &NetworkPolicyGeneratorReconciler{
 				Client:    mockCl,
@@ -1891,7 +2514,7 @@ var _ = Describe("Mode Handlers", func() {
 			mockCl := &mockClient{
 				Client:            k8sClient,
 				getError:          apierrors.NewNotFound(schema.GroupResource{Group: policy.CiliumGroup, Resource: resourceCiliumNetworkPolicies}, ""),
-				noopCreate:        true,
+				noopApply:         true,
 				statusUpdateError: fmt.Errorf("cilium status update failed"),
 			}
 			ciliumReconciler := &NetworkPolicyGeneratorReconciler{
@@ -1907,4 +2530,183 @@ var _ = Describe("Mode Handlers", func() {
 			Expect(err.Error()).To(ContainSubstring("cilium status update failed"))
 		})
 	})
+
+	Context("Multiple Policy Engines", func() {
+		newMultiEngineGenerator := func(name string, engines ...string) *securityv1.NetworkPolicyGenerator {
+			return &securityv1.NetworkPolicyGenerator{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+				Spec: securityv1.NetworkPolicyGeneratorSpec{
+					Mode:          policy.ModeEnforcing,
+					PolicyEngines: engines,
+					Duration:      metav1.Duration{Duration: time.Minute},
+					Policy: securityv1.PolicyConfig{
+						Type:              policy.PolicyTypeDeny,
+						AllowedNamespaces: []string{nsOne},
+					},
+				},
+			}
+		}
+
+		It("should generate policies for every engine in dry-run", func() {
+			generator := newMultiEngineGenerator(generatorName+"-multi-dryrun", policy.EngineCalico, policy.EngineCilium)
+			generator.Spec.DryRun = true
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.GeneratedPolicies).To(HaveLen(2))
+			Expect(generator.Status.GeneratedPolicies[0]).To(ContainSubstring(policy.CalicoAPIVersion))
+			Expect(generator.Status.GeneratedPolicies[1]).To(ContainSubstring(policy.CiliumAPIVersion))
+		})
+
+		It("should apply policies and report counts per engine", func() {
+			generator := newMultiEngineGenerator(generatorName+"-multi-apply", policy.EngineKubernetes, policy.EngineCilium)
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			mockCl := &mockClient{
+				Client:    k8sClient,
+				getError:  apierrors.NewNotFound(schema.GroupResource{Group: policy.CiliumGroup, Resource: resourceCiliumNetworkPolicies}, ""),
+				noopApply: true,
+			}
+			multiReconciler := &NetworkPolicyGeneratorReconciler{
+				Client:    mockCl,
+				Scheme:    k8sClient.Scheme(),
+				Generator: policy.NewGenerator(),
+				Validator: policy.NewValidator(),
+				Recorder:  record.NewFakeRecorder(100),
+			}
+
+			_, err := multiReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.AppliedPoliciesCount).To(Equal(2))
+			Expect(generator.Status.EnginePolicies).To(Equal([]securityv1.EnginePolicyCount{
+				{Engine: policy.EngineKubernetes, Count: 1},
+				{Engine: policy.EngineCilium, Count: 1},
+			}))
+			Expect(generator.Status.PolicyDiff).To(HaveLen(2))
+			Expect(generator.Status.PolicyDiff[0].Engine).To(Equal(policy.EngineKubernetes))
+			Expect(generator.Status.PolicyDiff[1].Engine).To(Equal(policy.EngineCilium))
+		})
+
+		It("should delete the policies of an engine removed from the list", func() {
+			generator := newMultiEngineGenerator(generatorName+"-multi-remove", policy.EngineKubernetes)
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+			generator.Status.EnginePolicies = []securityv1.EnginePolicyCount{
+				{Engine: policy.EngineKubernetes, Count: 1},
+				{Engine: policy.EngineCalico, Count: 1},
+			}
+
+			mockCl := &mockClient{
+				Client:      k8sClient,
+				getError:    apierrors.NewNotFound(schema.GroupResource{Group: "networking.k8s.io", Resource: resourceNetworkPolicies}, ""),
+				noopApply:   true,
+				deleteError: fmt.Errorf("calico cleanup failed"),
+			}
+			multiReconciler := &NetworkPolicyGeneratorReconciler{
+				Client:    mockCl,
+				Scheme:    k8sClient.Scheme(),
+				Generator: policy.NewGenerator(),
+				Validator: policy.NewValidator(),
+				Recorder:  record.NewFakeRecorder(100),
+			}
+
+			_, err := multiReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("calico cleanup failed"))
+
+			mockCl.deleteError = nil
+			mockCl.noopDelete = true
+			_, err = multiReconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.EnginePolicies).To(Equal([]securityv1.EnginePolicyCount{
+				{Engine: policy.EngineKubernetes, Count: 1},
+			}))
+		})
+
+		It("should clean up selected and previously applied engines", func() {
+			generator := newMultiEngineGenerator(generatorName+"-multi-cleanup", policy.EngineCilium, policy.EngineCalico)
+			generator.Status.EnginePolicies = []securityv1.EnginePolicyCount{
+				{Engine: policy.EngineCalico, Count: 1},
+				{Engine: policy.EngineKubernetes, Count: 1},
+			}
+
+			Expect(cleanupEngineTypes(generator)).To(Equal([]string{
+				policy.EngineCilium, policy.EngineCalico, policy.EngineKubernetes,
+			}))
+		})
+	})
+
+	Context("External Policy Engine Plugins", func() {
+		It("should apply and delete the objects a plugin emits", func() {
+			name := generatorName + "-plugin"
+			response := fmt.Sprintf(`{"apiVersion":%q,"kind":%q,"objects":[{"apiVersion":"networking.k8s.io/v1",`+
+				`"kind":"NetworkPolicy","metadata":{"name":%q,"namespace":%q},"spec":{"podSelector":{},"policyTypes":["Ingress"]}}]}`,
+				policy.PluginAPIVersion, policy.PluginResponseKind, policy.PolicyName(name), namespace)
+			command := filepath.Join(GinkgoT().TempDir(), "plugin.sh")
+			Expect(os.WriteFile(command, []byte("#!/bin/sh\ncat > /dev/null\necho '"+response+"'\n"), 0o755)).To(Succeed())
+
+			plugins, err := policy.NewPluginRegistry([]policy.PluginConfig{{
+				Name:    pluginName,
+				Command: command,
+				Kinds:   []policy.PluginKind{{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"}},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			reconciler.Plugins = plugins
+
+			generator := &securityv1.NetworkPolicyGenerator{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec: securityv1.NetworkPolicyGeneratorSpec{
+					Mode:         policy.ModeEnforcing,
+					PolicyEngine: pluginName,
+					Duration:     metav1.Duration{Duration: time.Minute},
+					Policy:       securityv1.PolicyConfig{Type: policy.PolicyTypeDeny},
+				},
+			}
+			Expect(k8sClient.Create(ctx, generator)).To(Succeed())
+
+			_, err = reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.EnginePolicies).To(Equal([]securityv1.EnginePolicyCount{
+				{Engine: pluginName, Count: 1},
+			}))
+
+			np := &networkingv1.NetworkPolicy{}
+			key := types.NamespacedName{Name: policy.PolicyName(name), Namespace: namespace}
+			Expect(k8sClient.Get(ctx, key, np)).To(Succeed())
+			Expect(np.OwnerReferences).To(HaveLen(1))
+			Expect(np.OwnerReferences[0].Name).To(Equal(name))
+
+			Expect(reconciler.deleteNetworkPolicies(ctx, generator)).To(Succeed())
+			err = k8sClient.Get(ctx, key, np)
+			Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should reject a generator selecting an unregistered plugin", func() {
+			generator := &securityv1.NetworkPolicyGenerator{
+				ObjectMeta: metav1.ObjectMeta{Name: generatorName + "-no-plugin", Namespace: namespace},
+				Spec: securityv1.NetworkPolicyGeneratorSpec{
+					Mode:         policy.ModeEnforcing,
+					PolicyEngine: pluginName,
+					Policy:       securityv1.PolicyConfig{Type: policy.PolicyTypeDeny},
+				},
+			}
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unsupported policy engine"))
+		})
+
+		It("should skip cleanup for an engine that is no longer registered", func() {
+			generator := &securityv1.NetworkPolicyGenerator{
+				ObjectMeta: metav1.ObjectMeta{Name: generatorName + "-removed-plugin", Namespace: namespace},
+				Spec: securityv1.NetworkPolicyGeneratorSpec{
+					PolicyEngine: pluginName,
+					Policy:       securityv1.PolicyConfig{Type: policy.PolicyTypeDeny},
+				},
+			}
+			Expect(reconciler.deleteEnginePolicies(ctx, generator, pluginName)).To(Succeed())
+		})
+	})
 })
@@ -2,14 +2,20 @@ package controller
 
 import (
 	"context"
+	"net/netip"
+	"slices"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
@@ -65,6 +71,7 @@ var _ = Describe("Namespace Policy Tests", func() {
 				}
 				generator.Spec.Mode = policy.ModeEnforcing
 				generator.Spec.Policy.AllowedNamespaces = allowedNamespaces
+				generator.Spec.Policy.AllowSameNamespace = ptr.To(true)
 				return k8sClient.Update(ctx, generator)
 			}, timeout, interval).Should(Succeed())
 
@@ -87,10 +94,11 @@ var _ = Describe("Namespace Policy Tests", func() {
 
 			By("Verifying namespace selectors")
 			Expect(networkPolicy.Spec.Ingress).To(HaveLen(2))
-			Expect(networkPolicy.Spec.Ingress[0].From).To(HaveLen(2))
+			Expect(networkPolicy.Spec.Ingress[0].From).To(HaveLen(3))
 
-			// Verify namespace selectors for allowed namespaces
-			for i, ns := range allowedNamespaces {
+			// Verify namespace selectors for allowed namespaces, followed by
+			// the generator's own namespace
+			for i, ns := range append(allowedNamespaces, namespace) {
 				Expect(networkPolicy.Spec.Ingress[0].From[i].NamespaceSelector.MatchLabels["kubernetes.io/metadata.name"]).To(Equal(ns))
 			}
 
@@ -98,5 +106,267 @@ var _ = Describe("Namespace Policy Tests", func() {
 			Expect(networkPolicy.Spec.Ingress[1].Ports).To(HaveLen(1))
 			Expect(networkPolicy.Spec.Ingress[1].From[0].IPBlock.CIDR).To(Equal("0.0.0.0/0"))
 		})
+
+		It("should default allowSameNamespace on a new generator's first reconcile", func() {
+			key := types.NamespacedName{Name: generatorName, Namespace: namespace}
+			generator := &securityv1.NetworkPolicyGenerator{}
+			Expect(k8sClient.Get(ctx, key, generator)).To(Succeed())
+			Expect(generator.Spec.Policy.AllowSameNamespace).To(BeNil())
+
+			_, err := reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, generator)).To(Succeed())
+			Expect(generator.Spec.Policy.AllowSameNamespace).To(Equal(ptr.To(true)))
+
+			By("Leaving the field unset on a generator that was reconciled before it existed")
+			stored := createBasicGenerator(namespace, generatorName+"-stored")
+			stored.Finalizers = []string{finalizerName}
+			Expect(k8sClient.Create(ctx, stored)).To(Succeed())
+			storedKey := types.NamespacedName{Name: stored.Name, Namespace: namespace}
+
+			_, err = reconciler.Reconcile(ctx, reconcile.Request{NamespacedName: storedKey})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, storedKey, stored)).To(Succeed())
+			Expect(stored.Spec.Policy.AllowSameNamespace).To(BeNil())
+		})
+
+		It("should follow the namespaces deniedNamespaceSelector matches", func() {
+			By("Creating a namespace carrying the selected label")
+			selectorLabel := map[string]string{"npg-test/denied-by": namespace}
+			selected := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: namespace + "-selected", Labels: selectorLabel},
+			}
+			Expect(k8sClient.Create(ctx, selected)).To(Succeed())
+
+			By("Switching the generator to an allow type with a denied namespace selector")
+			generator := &securityv1.NetworkPolicyGenerator{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName, Namespace: namespace}, generator)).To(Succeed())
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.Policy = securityv1.PolicyConfig{
+				Type:                    policy.PolicyTypeAllow,
+				DeniedNamespaceSelector: &metav1.LabelSelector{MatchLabels: selectorLabel},
+			}
+			Expect(k8sClient.Update(ctx, generator)).To(Succeed())
+
+			Expect(reconciler.selectorGeneratorsForNamespace(ctx, selected)).To(ContainElement(reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: generatorName, Namespace: namespace},
+			}))
+
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.MatchedNamespaces).To(Equal([]string{selected.Name}))
+
+			policyKey := types.NamespacedName{Name: generatorName + "-generated", Namespace: selected.Name}
+			Expect(k8sClient.Get(ctx, policyKey, &networkingv1.NetworkPolicy{})).To(Succeed())
+
+			By("Removing the label and reconciling again")
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: selected.Name}, selected)).To(Succeed())
+			selected.Labels = nil
+			Expect(k8sClient.Update(ctx, selected)).To(Succeed())
+
+			_, err = reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.MatchedNamespaces).To(BeEmpty())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, policyKey, &networkingv1.NetworkPolicy{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+		})
+
+		It("should allow ingress from the nodes with allowNodeTraffic", func() {
+			By("Creating a node with an address and a pod CIDR")
+			node := &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: namespace + "-node"},
+				Spec:       corev1.NodeSpec{PodCIDRs: []string{"10.244.1.0/24"}},
+			}
+			Expect(k8sClient.Create(ctx, node)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, node)).To(Succeed())
+			}()
+			node.Status.Addresses = []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "192.168.10.5"},
+				{Type: corev1.NodeHostName, Address: "worker-1"},
+			}
+			Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+
+			By("Enabling allowNodeTraffic on the generator")
+			generator := &securityv1.NetworkPolicyGenerator{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName, Namespace: namespace}, generator)).To(Succeed())
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.AllowNodeTraffic = true
+			Expect(k8sClient.Update(ctx, generator)).To(Succeed())
+
+			Expect(reconciler.nodeTrafficGenerators(ctx, node)).To(ContainElement(reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: generatorName, Namespace: namespace},
+			}))
+
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.NodeCIDRs).To(ContainElement("192.168.10.5/32"))
+
+			networkPolicy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName + "-generated", Namespace: namespace}, networkPolicy)).To(Succeed())
+			var nodeCIDRs []string
+			for _, rule := range networkPolicy.Spec.Ingress {
+				var cidrs []string
+				for _, peer := range rule.From {
+					if peer.IPBlock != nil {
+						cidrs = append(cidrs, peer.IPBlock.CIDR)
+					}
+				}
+				if slices.Contains(cidrs, "192.168.10.5/32") {
+					nodeCIDRs = cidrs
+				}
+			}
+			Expect(nodeCIDRs).NotTo(BeEmpty())
+
+			By("Not allowing peers in the node's pod range")
+			podIP := netip.MustParseAddr("10.244.1.7")
+			for _, cidr := range append(nodeCIDRs, generator.Status.NodeCIDRs...) {
+				Expect(netip.MustParsePrefix(cidr).Contains(podIP)).To(BeFalse(), "node CIDR %s covers a pod", cidr)
+			}
+		})
+
+		It("should allow egress to the API server with allowAPIServer", func() {
+			By("Creating an EndpointSlice for the kubernetes service")
+			endpointSlice := &discoveryv1.EndpointSlice{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      namespace + "-apiserver",
+					Namespace: metav1.NamespaceDefault,
+					Labels:    map[string]string{discoveryv1.LabelServiceName: apiServerServiceName},
+				},
+				AddressType: discoveryv1.AddressTypeIPv4,
+				Endpoints: []discoveryv1.Endpoint{
+					{Addresses: []string{"172.18.0.2"}},
+					{Addresses: []string{"172.18.0.3"}, Conditions: discoveryv1.EndpointConditions{Ready: ptr.To(false)}},
+				},
+				Ports: []discoveryv1.EndpointPort{{Name: ptr.To("https"), Port: ptr.To[int32](6443)}},
+			}
+			Expect(k8sClient.Create(ctx, endpointSlice)).To(Succeed())
+			defer func() {
+				Expect(k8sClient.Delete(ctx, endpointSlice)).To(Succeed())
+			}()
+			Expect(isAPIServerEndpointSlice(endpointSlice)).To(BeTrue())
+
+			By("Enabling allowAPIServer on the generator")
+			generator := &securityv1.NetworkPolicyGenerator{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName, Namespace: namespace}, generator)).To(Succeed())
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.AllowAPIServer = true
+			Expect(k8sClient.Update(ctx, generator)).To(Succeed())
+
+			Expect(reconciler.apiServerGenerators(ctx, endpointSlice)).To(ContainElement(reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: generatorName, Namespace: namespace},
+			}))
+
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.APIServerCIDRs).To(ContainElement("172.18.0.2/32"))
+			Expect(generator.Status.APIServerCIDRs).NotTo(ContainElement("172.18.0.3/32"))
+			Expect(generator.Status.APIServerPorts).To(ContainElement(int32(6443)))
+
+			networkPolicy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName + "-generated", Namespace: namespace}, networkPolicy)).To(Succeed())
+			var apiServerCIDRs []string
+			for _, rule := range networkPolicy.Spec.Egress {
+				for _, peer := range rule.To {
+					if peer.IPBlock != nil {
+						apiServerCIDRs = append(apiServerCIDRs, peer.IPBlock.CIDR)
+					}
+				}
+			}
+			Expect(apiServerCIDRs).To(ContainElement("172.18.0.2/32"))
+		})
+
+		It("should garbage-collect the policies the spec no longer yields", func() {
+			By("Creating a denied namespace and enforcing against it")
+			denied := namespace + "-denied"
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: denied}})).To(Succeed())
+
+			generator := &securityv1.NetworkPolicyGenerator{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName, Namespace: namespace}, generator)).To(Succeed())
+			generator.Spec.Mode = policy.ModeEnforcing
+			generator.Spec.Policy = securityv1.PolicyConfig{
+				Type:             policy.PolicyTypeAllow,
+				DeniedNamespaces: []string{denied},
+			}
+			Expect(k8sClient.Update(ctx, generator)).To(Succeed())
+
+			_, err := reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(generator.Status.AppliedPolicies).To(ContainElement(And(
+				HaveField("Engine", policy.EngineKubernetes),
+				HaveField("APIVersion", "networking.k8s.io/v1"),
+				HaveField("Kind", "NetworkPolicy"),
+				HaveField("Namespace", denied),
+				HaveField("Name", generatorName+"-generated"),
+			)))
+
+			policyKey := types.NamespacedName{Name: generatorName + "-generated", Namespace: denied}
+			networkPolicy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, policyKey, networkPolicy)).To(Succeed())
+			Expect(networkPolicy.Labels).To(HaveKeyWithValue(policy.LabelGeneratorName, generatorName))
+			Expect(networkPolicy.Labels).To(HaveKeyWithValue(policy.LabelGeneratorNamespace, namespace))
+			Expect(networkPolicy.Labels).To(HaveKeyWithValue(policy.LabelEngine, policy.EngineKubernetes))
+			Expect(networkPolicy.Annotations).To(HaveKeyWithValue(policy.AnnotationOwner, namespace+"/"+generatorName))
+			Expect(networkPolicy.OwnerReferences).To(BeEmpty(), "owner references cannot cross namespaces")
+
+			By("Dropping the denied namespace from the spec")
+			generator.Spec.Policy.DeniedNamespaces = nil
+			_, err = reconciler.handleEnforcingMode(ctx, generator)
+			Expect(err).NotTo(HaveOccurred())
+
+			Eventually(func() bool {
+				err := k8sClient.Get(ctx, policyKey, &networkingv1.NetworkPolicy{})
+				return apierrors.IsNotFound(err)
+			}, timeout, interval).Should(BeTrue())
+			Expect(generator.Status.AppliedPolicies).NotTo(ContainElement(
+				HaveField("Namespace", denied),
+			))
+		})
+
+		It("should migrate cross-namespace owner references to owner labels", func() {
+			generator := &securityv1.NetworkPolicyGenerator{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName, Namespace: namespace}, generator)).To(Succeed())
+
+			By("Creating policies the way earlier releases owned them")
+			other := namespace + "-legacy"
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: other}})).To(Succeed())
+			for _, ns := range []string{namespace, other} {
+				Expect(k8sClient.Create(ctx, &networkingv1.NetworkPolicy{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            generatorName + "-generated",
+						Namespace:       ns,
+						OwnerReferences: []metav1.OwnerReference{ownerReference(generator)},
+					},
+				})).To(Succeed())
+			}
+
+			Expect(reconciler.migrateOwnership(ctx)).To(Succeed())
+
+			By("Replacing the reference across namespaces")
+			migrated := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName + "-generated", Namespace: other}, migrated)).To(Succeed())
+			Expect(migrated.OwnerReferences).To(BeEmpty())
+			Expect(migrated.Labels).To(HaveKeyWithValue(policy.LabelGeneratorName, generatorName))
+			Expect(migrated.Labels).To(HaveKeyWithValue(policy.LabelEngine, policy.EngineKubernetes))
+			Expect(migrated.Annotations).To(HaveKeyWithValue(policy.AnnotationOwner, namespace+"/"+generatorName))
+
+			By("Keeping the reference in the generator's namespace")
+			kept := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: generatorName + "-generated", Namespace: namespace}, kept)).To(Succeed())
+			Expect(kept.OwnerReferences).To(HaveLen(1))
+		})
+
+		It("should keep long owner label values valid and unique", func() {
+			long := strings.Repeat("a", 70)
+			value := ownerLabelValue(long)
+			Expect(value).To(HaveLen(63))
+			Expect(value).NotTo(Equal(ownerLabelValue(long + "b")))
+			Expect(ownerLabelValue(generatorName)).To(Equal(generatorName))
+		})
 	})
 })
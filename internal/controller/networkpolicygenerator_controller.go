@@ -3,18 +3,33 @@ package controller
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
-	networkingv1 "k8s.io/api/networking/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 	"github.com/somaz94/network-policy-generator/internal/policy"
@@ -28,6 +43,22 @@ type NetworkPolicyGeneratorReconciler struct {
 	Generator *policy.Generator
 	Validator *policy.Validator
 	Recorder  record.EventRecorder
+
+	// EngineDetector resolves policyEngine "auto". When nil, "auto" resolves
+	// to kubernetes and engine CRDs are not watched.
+	EngineDetector *EngineDetector
+
+	// Plugins holds the external policy engines loaded from the operator
+	// config. When nil, only the built-in engines are available.
+	Plugins *policy.PluginRegistry
+
+	// DefaultIPFamilies applies to generators that set no spec.ipFamilies.
+	// When empty, the cluster's IP families apply.
+	DefaultIPFamilies []string
+
+	// policyWatches tracks the drift watches on generated policy kinds, set
+	// up by SetupWithManager
+	policyWatches *policyKindWatches
 }
 
 // NewReconciler creates a new NetworkPolicyGeneratorReconciler
@@ -47,14 +78,42 @@ func NewReconciler(c client.Client, scheme *runtime.Scheme, recorder record.Even
 // +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=cilium.io,resources=ciliumnetworkpolicies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=crd.projectcalico.org,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=crd.antrea.io,resources=networkpolicies;clusternetworkpolicies,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+// +kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
 // +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
 
 const (
 	finalizerName = "security.policy.io/finalizer"
+
+	// apiServerServiceName is the service in the default namespace whose
+	// EndpointSlices list the Kubernetes API server endpoints
+	apiServerServiceName = "kubernetes"
 )
 
+// APIServerEndpointSliceCache limits the manager's EndpointSlice cache to the
+// API server's slices, the only ones the controller reads
+func APIServerEndpointSliceCache() cache.ByObject {
+	return cache.ByObject{
+		Namespaces: map[string]cache.Config{metav1.NamespaceDefault: {}},
+		Label:      labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: apiServerServiceName}),
+	}
+}
+
+// APIServerServiceCache limits the manager's Service cache to the API
+// server's Service, the only one the controller reads
+func APIServerServiceCache() cache.ByObject {
+	return cache.ByObject{
+		Namespaces: map[string]cache.Config{metav1.NamespaceDefault: {}},
+		Field:      fields.OneTermEqualSelector("metadata.name", apiServerServiceName),
+	}
+}
+
 func (r *NetworkPolicyGeneratorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 	log.Info("Starting reconciliation", "namespacedName", req.NamespacedName)
@@ -78,10 +137,12 @@ func (r *NetworkPolicyGeneratorReconciler) Reconcile(ctx context.Context, req ct
 		return r.handleDeletion(ctx, generator)
 	}
 
-	// Add finalizer if it doesn't exist
+	// Add finalizer if it doesn't exist. Only a generator that was never
+	// reconciled lacks it, so this is also where it gets its create defaults.
 	if !controllerutil.ContainsFinalizer(generator, finalizerName) {
 		log.Info("Adding finalizer", "name", generator.Name, "namespace", generator.Namespace)
 		controllerutil.AddFinalizer(generator, finalizerName)
+		defaultNewGenerator(generator)
 		if err := r.Update(ctx, generator); err != nil {
 			log.Error(err, "failed to add finalizer")
 			return ctrl.Result{}, err
@@ -104,7 +165,9 @@ func (r *NetworkPolicyGeneratorReconciler) Reconcile(ctx context.Context, req ct
 		result, err = r.handleEnforcingMode(ctx, generator)
 	default:
 		log.Error(nil, "Invalid mode specified", "mode", generator.Spec.Mode, "name", generator.Name)
-		return ctrl.Result{}, fmt.Errorf("invalid mode: %s", generator.Spec.Mode)
+		err = fmt.Errorf("invalid mode: %s", generator.Spec.Mode)
+		r.recordFailure(ctx, req.NamespacedName, err)
+		return ctrl.Result{}, err
 	}
 
 	// Record metrics
@@ -114,6 +177,7 @@ func (r *NetworkPolicyGeneratorReconciler) Reconcile(ctx context.Context, req ct
 	if err != nil {
 		ReconcileTotal.WithLabelValues("error").Inc()
 		log.Error(err, "failed to handle mode", "mode", generator.Spec.Mode, "name", generator.Name)
+		r.recordFailure(ctx, req.NamespacedName, err)
 		return ctrl.Result{}, err
 	}
 	ReconcileTotal.WithLabelValues("success").Inc()
@@ -127,6 +191,16 @@ func (r *NetworkPolicyGeneratorReconciler) Reconcile(ctx context.Context, req ct
 	return result, nil
 }
 
+// defaultNewGenerator records the defaults the defaulting webhook sets on
+// create, so a new generator gets them when webhooks are disabled too.
+// Generators stored before a default existed were already reconciled, and
+// keep the field unset.
+func defaultNewGenerator(generator *securityv1.NetworkPolicyGenerator) {
+	if generator.Spec.Policy.AllowSameNamespace == nil {
+		generator.Spec.Policy.AllowSameNamespace = ptr.To(true)
+	}
+}
+
 // syncPhase updates the status phase to match the spec mode
 func (r *NetworkPolicyGeneratorReconciler) syncPhase(ctx context.Context, generator *securityv1.NetworkPolicyGenerator) error {
 	log := log.FromContext(ctx)
@@ -167,7 +241,9 @@ func (r *NetworkPolicyGeneratorReconciler) handleDeletion(ctx context.Context, g
 		}
 		r.Recorder.Event(generator, "Normal", "PoliciesDeleted", "All generated NetworkPolicies deleted")
 		PolicyOperations.WithLabelValues("Deleted").Inc()
-		PoliciesApplied.DeleteLabelValues(generator.Name, generator.Namespace, generator.Spec.PolicyEngine)
+		for _, engineType := range cleanupEngineTypes(generator) {
+			PoliciesApplied.DeleteLabelValues(generator.Name, generator.Namespace, engineType)
+		}
 		controllerutil.RemoveFinalizer(generator, finalizerName)
 		if err := r.Update(ctx, generator); err != nil {
 			log.Error(err, "failed to remove finalizer")
@@ -178,67 +254,287 @@ func (r *NetworkPolicyGeneratorReconciler) handleDeletion(ctx context.Context, g
 	return ctrl.Result{}, nil
 }
 
-// deleteNetworkPolicies deletes all NetworkPolicies created by this generator
+// deleteNetworkPolicies deletes all NetworkPolicies created by this generator,
+// for every engine it selects or has applied policies for. The inventory and
+// owner labels find objects applied for earlier specs; the names computed from
+// the current spec cover objects applied before the labels existed.
 func (r *NetworkPolicyGeneratorReconciler) deleteNetworkPolicies(ctx context.Context, generator *securityv1.NetworkPolicyGenerator) error {
-	log := log.FromContext(ctx)
-
-	var namespacesToClean []string
-	if generator.Spec.Policy.Type == policy.PolicyTypeAllow {
-		namespacesToClean = generator.Spec.Policy.DeniedNamespaces
-	} else {
-		namespacesToClean = []string{generator.Namespace}
+	engineTypes := cleanupEngineTypes(generator)
+	if err := r.collectGarbage(ctx, generator, nil, engineTypes); err != nil {
+		return err
+	}
+	for _, engineType := range engineTypes {
+		if err := r.deleteEnginePolicies(ctx, generator, engineType); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	engineType := generator.Spec.PolicyEngine
-	if engineType == "" {
-		engineType = policy.EngineKubernetes
+// cleanupEngineTypes returns the engines selected by the spec followed by any
+// engine recorded in status.enginePolicies that is no longer selected. "auto"
+// is replaced by the engine it last resolved to.
+func cleanupEngineTypes(generator *securityv1.NetworkPolicyGenerator) []string {
+	var engineTypes []string
+	for _, engineType := range policy.EngineTypes(&generator.Spec) {
+		if engineType == policy.EngineAuto {
+			engineType = generator.Status.ResolvedEngine
+		}
+		if engineType != "" && !slices.Contains(engineTypes, engineType) {
+			engineTypes = append(engineTypes, engineType)
+		}
+	}
+	for _, applied := range generator.Status.EnginePolicies {
+		if !slices.Contains(engineTypes, applied.Engine) {
+			engineTypes = append(engineTypes, applied.Engine)
+		}
 	}
+	return engineTypes
+}
 
-	for _, ns := range namespacesToClean {
-		policyName := policy.PolicyName(generator.Name)
+// deleteEnginePolicies deletes the policies a single engine generated: every
+// namespaced kind of the engine in each target namespace, and every
+// cluster-scoped kind under the cluster policy name
+func (r *NetworkPolicyGeneratorReconciler) deleteEnginePolicies(ctx context.Context, generator *securityv1.NetworkPolicyGenerator, engineType string) error {
+	log := log.FromContext(ctx)
 
-		switch engineType {
-		case policy.EngineKubernetes:
-			np := &networkingv1.NetworkPolicy{
-				ObjectMeta: metav1.ObjectMeta{Name: policyName, Namespace: ns},
-			}
-			if err := r.Delete(ctx, np); err != nil && !apierrors.IsNotFound(err) {
-				log.Error(err, "failed to delete NetworkPolicy", "namespace", ns, "name", policyName)
-				return err
-			}
-		case policy.EngineCilium:
-			if err := r.deleteUnstructuredPolicy(ctx, ns, policyName, gvkForEngine(policy.EngineCilium)); err != nil {
-				log.Error(err, "failed to delete CiliumNetworkPolicy", "namespace", ns, "name", policyName)
+	kinds := r.engineKinds(engineType)
+	if len(kinds) == 0 {
+		// A plugin removed from the operator config: namespaced objects are
+		// still garbage-collected through their owner references.
+		log.Info("Skipping cleanup for unknown policy engine", "engine", engineType)
+		return nil
+	}
+
+	policyName := policy.PolicyName(generator.Name)
+	for _, kind := range kinds {
+		if kind.ClusterScoped {
+			clusterName := policy.ClusterPolicyName(generator.Namespace, generator.Name)
+			if err := r.deleteUnstructuredPolicy(ctx, "", clusterName, kind.GroupVersionKind); err != nil {
+				log.Error(err, "failed to delete policy", "kind", kind.Kind, "name", clusterName)
 				return err
 			}
-		case policy.EngineCalico:
-			if err := r.deleteUnstructuredPolicy(ctx, ns, policyName, gvkForEngine(policy.EngineCalico)); err != nil {
-				log.Error(err, "failed to delete Calico NetworkPolicy", "namespace", ns, "name", policyName)
+			log.Info("Successfully deleted policy", "engine", engineType, "kind", kind.Kind, "name", clusterName)
+			continue
+		}
+
+		for _, ns := range policy.TargetNamespaces(generator) {
+			if err := r.deleteUnstructuredPolicy(ctx, ns, policyName, kind.GroupVersionKind); err != nil {
+				log.Error(err, "failed to delete policy", "kind", kind.Kind, "namespace", ns, "name", policyName)
 				return err
 			}
+			log.Info("Successfully deleted policy", "engine", engineType, "kind", kind.Kind, "namespace", ns, "name", policyName)
 		}
-
-		log.Info("Successfully deleted policy", "engine", engineType, "namespace", ns, "name", policyName)
 	}
 
 	return nil
 }
 
-// deleteUnstructuredPolicy deletes an unstructured policy resource (Cilium/Calico)
+// engineKinds returns the kinds an engine emits, looking up plugins before
+// the built-in engines
+func (r *NetworkPolicyGeneratorReconciler) engineKinds(engineType string) []policy.PolicyKind {
+	if kinds := r.Plugins.Kinds(engineType); kinds != nil {
+		return kinds
+	}
+	return policy.EngineKinds(engineType)
+}
+
+// deleteUnstructuredPolicy deletes a policy resource of any kind. A kind the
+// API server does not serve has no objects to delete.
 func (r *NetworkPolicyGeneratorReconciler) deleteUnstructuredPolicy(ctx context.Context, ns, name string, gvk schema.GroupVersionKind) error {
 	u := &unstructured.Unstructured{}
 	u.SetGroupVersionKind(gvk)
 	u.SetName(name)
 	u.SetNamespace(ns)
-	if err := r.Delete(ctx, u); err != nil && !apierrors.IsNotFound(err) {
+	if err := r.Delete(ctx, u); err != nil && !apierrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
 		return err
 	}
 	return nil
 }
 
-// SetupWithManager sets up the controller with the Manager
+// SetupWithManager sets up the controller with the Manager. Namespace label
+// changes requeue the generators whose deniedNamespaceSelector may match
+// differently, nodes joining, leaving or changing addresses requeue the
+// generators with allowNodeTraffic, and API server endpoint changes requeue
+// the generators with allowAPIServer. Edits to and deletions of generated
+// policies requeue their generator, so drift is caught right away. Changes to
+// the engine CRDs start the drift watches of kinds installed after startup
+// and, with an engine detector, re-run discovery and requeue every generator
+// using policyEngine "auto". Once the manager starts, policies an earlier
+// release owned across namespaces are migrated to owner labels.
 func (r *NetworkPolicyGeneratorReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&securityv1.NetworkPolicyGenerator{}).
-		Complete(r)
+		Watches(&corev1.Namespace{},
+			handler.EnqueueRequestsFromMapFunc(r.selectorGeneratorsForNamespace),
+			builder.WithPredicates(predicate.LabelChangedPredicate{}),
+		).
+		Watches(&corev1.Node{},
+			handler.EnqueueRequestsFromMapFunc(r.nodeTrafficGenerators),
+			builder.WithPredicates(nodeCIDRsChangedPredicate()),
+		).
+		Watches(&discoveryv1.EndpointSlice{},
+			handler.EnqueueRequestsFromMapFunc(r.apiServerGenerators),
+			builder.WithPredicates(predicate.NewPredicateFuncs(isAPIServerEndpointSlice)),
+		)
+	watches := &policyKindWatches{mapper: mgr.GetRESTMapper(), cache: mgr.GetCache()}
+	b, err := r.watchPolicyKinds(b, watches)
+	if err != nil {
+		return err
+	}
+	if err := mgr.Add(manager.RunnableFunc(r.migrateOwnership)); err != nil {
+		return err
+	}
+
+	crd := &metav1.PartialObjectMetadata{}
+	crd.SetGroupVersionKind(apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition"))
+	b = b.Watches(crd,
+		handler.EnqueueRequestsFromMapFunc(r.generatorsForEngineCRD),
+		builder.WithPredicates(predicate.NewPredicateFuncs(func(obj client.Object) bool {
+			_, ok := engineCRDs[obj.GetName()]
+			return ok
+		})),
+	)
+
+	r.policyWatches = watches
+	c, err := b.Build(r)
+	if err != nil {
+		return err
+	}
+	watches.mu.Lock()
+	watches.controller = c
+	watches.mu.Unlock()
+	return nil
+}
+
+// selectorGeneratorsForNamespace returns a request for every allow-type
+// generator with a deniedNamespaceSelector, since a namespace change may add
+// it to or remove it from the matched namespaces
+func (r *NetworkPolicyGeneratorReconciler) selectorGeneratorsForNamespace(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	generators := &securityv1.NetworkPolicyGeneratorList{}
+	if err := r.List(ctx, generators); err != nil {
+		log.Error(err, "failed to list NetworkPolicyGenerators", "namespace", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, g := range generators.Items {
+		if g.Spec.Policy.Type == policy.PolicyTypeAllow && g.Spec.Policy.DeniedNamespaceSelector != nil {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: g.Name, Namespace: g.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
+// nodeCIDRsChangedPredicate passes node creates and deletes, and updates
+// that change the node's addresses
+func nodeCIDRsChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			return !slices.Equal(nodeCIDRs(oldNode), nodeCIDRs(newNode))
+		},
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// nodeTrafficGenerators returns a request for every generator with
+// allowNodeTraffic, since a node change may change its node CIDRs
+func (r *NetworkPolicyGeneratorReconciler) nodeTrafficGenerators(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	generators := &securityv1.NetworkPolicyGeneratorList{}
+	if err := r.List(ctx, generators); err != nil {
+		log.Error(err, "failed to list NetworkPolicyGenerators", "node", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, g := range generators.Items {
+		if g.Spec.AllowNodeTraffic {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: g.Name, Namespace: g.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
+// isAPIServerEndpointSlice reports whether obj is an EndpointSlice of the
+// default/kubernetes service
+func isAPIServerEndpointSlice(obj client.Object) bool {
+	return obj.GetNamespace() == metav1.NamespaceDefault &&
+		obj.GetLabels()[discoveryv1.LabelServiceName] == apiServerServiceName
+}
+
+// apiServerGenerators returns a request for every generator with
+// allowAPIServer, since an endpoint change may change its API server CIDRs
+func (r *NetworkPolicyGeneratorReconciler) apiServerGenerators(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	generators := &securityv1.NetworkPolicyGeneratorList{}
+	if err := r.List(ctx, generators); err != nil {
+		log.Error(err, "failed to list NetworkPolicyGenerators", "endpointSlice", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, g := range generators.Items {
+		if g.Spec.AllowAPIServer {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: g.Name, Namespace: g.Namespace},
+			})
+		}
+	}
+	return requests
+}
+
+// generatorsForEngineCRD watches the policy kinds of a newly installed engine
+// CRD for drift and, with an engine detector, returns the generators using
+// policyEngine "auto"
+func (r *NetworkPolicyGeneratorReconciler) generatorsForEngineCRD(ctx context.Context, obj client.Object) []reconcile.Request {
+	r.watchInstalledPolicyKinds(ctx)
+	if r.EngineDetector == nil {
+		return nil
+	}
+	return r.autoGeneratorsForCRD(ctx, obj)
+}
+
+// autoGeneratorsForCRD refreshes engine discovery after an engine CRD changed
+// and returns a request for every generator using policyEngine "auto"
+func (r *NetworkPolicyGeneratorReconciler) autoGeneratorsForCRD(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := log.FromContext(ctx)
+
+	if err := r.EngineDetector.Refresh(); err != nil {
+		log.Error(err, "failed to refresh policy engine discovery", "crd", obj.GetName())
+		return nil
+	}
+	log.Info("Policy engine CRD changed", "crd", obj.GetName(), "autoEngine", r.EngineDetector.Resolve())
+
+	generators := &securityv1.NetworkPolicyGeneratorList{}
+	if err := r.List(ctx, generators); err != nil {
+		log.Error(err, "failed to list NetworkPolicyGenerators")
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, g := range generators.Items {
+		if g.Spec.PolicyEngine == policy.EngineAuto && len(g.Spec.PolicyEngines) == 0 {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Name: g.Name, Namespace: g.Namespace},
+			})
+		}
+	}
+	return requests
 }
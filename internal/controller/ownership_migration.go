@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+)
+
+// migrateOwnership rewrites policies that earlier releases applied into
+// another namespace than their generator's with an owner reference to it.
+// The Kubernetes garbage collector resolves owner references in the
+// object's own namespace, so it would treat the owner as missing and delete
+// the policy. The reference is replaced with the owner labels and annotation,
+// which the finalizer uses for cleanup. It runs once when the manager starts;
+// failures are logged, and objects that are not rewritten keep their
+// reference until the next start.
+func (r *NetworkPolicyGeneratorReconciler) migrateOwnership(ctx context.Context) error {
+	log := log.FromContext(ctx).WithName("ownership-migration")
+
+	generators := &securityv1.NetworkPolicyGeneratorList{}
+	if err := r.List(ctx, generators); err != nil {
+		log.Error(err, "failed to list NetworkPolicyGenerators")
+		return nil
+	}
+	byUID := make(map[types.UID]*securityv1.NetworkPolicyGenerator, len(generators.Items))
+	for i := range generators.Items {
+		byUID[generators.Items[i].UID] = &generators.Items[i]
+	}
+
+	migrated := 0
+	for _, engineName := range append(slices.Clone(watchedEngines), r.Plugins.Names()...) {
+		for _, kind := range r.engineKinds(engineName) {
+			if kind.ClusterScoped {
+				continue
+			}
+			n, err := r.migrateKind(ctx, kind.GroupVersionKind, engineName, byUID)
+			if err != nil {
+				log.Error(err, "failed to migrate policy ownership", "kind", kind.Kind)
+			}
+			migrated += n
+		}
+	}
+	if migrated > 0 {
+		log.Info("Replaced cross-namespace owner references with owner labels", "policies", migrated)
+	}
+	return nil
+}
+
+// migrateKind rewrites the objects of one kind whose generator owner
+// reference points across namespaces and returns how many it rewrote
+func (r *NetworkPolicyGeneratorReconciler) migrateKind(
+	ctx context.Context,
+	gvk schema.GroupVersionKind,
+	engineName string,
+	byUID map[types.UID]*securityv1.NetworkPolicyGenerator,
+) (int, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := r.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	migrated := 0
+	for i := range list.Items {
+		item := &list.Items[i]
+		refs := item.GetOwnerReferences()
+		idx := slices.IndexFunc(refs, isGeneratorOwnerReference)
+		if idx < 0 {
+			continue
+		}
+		g, ok := byUID[refs[idx].UID]
+		if !ok || g.Namespace == item.GetNamespace() {
+			continue
+		}
+
+		original := item.DeepCopy()
+		item.SetOwnerReferences(slices.Delete(refs, idx, idx+1))
+		setOwnerMetadata(item, g, engineName)
+		if err := r.Patch(ctx, item, client.MergeFrom(original), client.FieldOwner(fieldManager)); err != nil {
+			return migrated, err
+		}
+		migrated++
+	}
+	return migrated, nil
+}
+
+// isGeneratorOwnerReference reports whether ref points at a
+// NetworkPolicyGenerator
+func isGeneratorOwnerReference(ref metav1.OwnerReference) bool {
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	return err == nil && gv.Group == securityv1.GroupVersion.Group && ref.Kind == "NetworkPolicyGenerator"
+}
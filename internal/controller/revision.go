@@ -0,0 +1,217 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+	"github.com/somaz94/network-policy-generator/internal/policy"
+)
+
+// revisionData is the content of a policy revision: the engines that
+// generated the policies and the spec they were generated from, with the
+// template and the default IP families applied. Peers discovered from the
+// cluster, such as node and API server addresses, are left out: they would
+// record a revision on every node change, and are resolved again when a
+// revision is rolled back to.
+type revisionData struct {
+	Engines []string                              `json:"engines"`
+	Spec    securityv1.NetworkPolicyGeneratorSpec `json:"spec"`
+}
+
+// RevisionCache limits the manager's ControllerRevision cache to the
+// revisions of generators; StatefulSets and DaemonSets keep theirs too
+func RevisionCache() cache.ByObject {
+	selector := labels.NewSelector()
+	req, err := labels.NewRequirement(policy.LabelGeneratorName, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+	return cache.ByObject{Label: selector.Add(*req)}
+}
+
+// encodeRevision serializes the policy input of a generator: the engines
+// that generated its policies and the spec fields that shape them. The same
+// input always yields the same bytes, so the content hash identifies a
+// revision.
+func encodeRevision(generated []enginePolicies, spec *securityv1.NetworkPolicyGeneratorSpec) ([]byte, error) {
+	data := revisionData{Engines: []string{}, Spec: *spec.DeepCopy()}
+	for _, g := range generated {
+		data.Engines = append(data.Engines, g.engineName)
+	}
+	// The mode, dry-run, drift handling and revision settings do not change
+	// the policies, and the engines are recorded as resolved
+	data.Spec.Mode, data.Spec.Duration = "", metav1.Duration{}
+	data.Spec.DryRun, data.Spec.DriftPolicy = false, ""
+	data.Spec.RevisionHistoryLimit, data.Spec.RollbackTo = nil, nil
+	data.Spec.PolicyEngine, data.Spec.PolicyEngines = "", nil
+	data.Spec.TemplateName = ""
+	return json.Marshal(data)
+}
+
+// decodeRevision restores the policy input of a revision
+func decodeRevision(raw []byte) (*revisionData, error) {
+	data := &revisionData{}
+	if err := json.Unmarshal(raw, data); err != nil {
+		return nil, fmt.Errorf("failed to decode revision: %w", err)
+	}
+	return data, nil
+}
+
+// revisionName names the ControllerRevision of a generator's revision by its
+// content hash
+func revisionName(g *securityv1.NetworkPolicyGenerator, raw []byte) string {
+	sum := sha256.Sum256(raw)
+	hash := hex.EncodeToString(sum[:])[:10]
+	name := g.Name
+	if maxLen := validation.DNS1123SubdomainMaxLength - len(hash) - 1; len(name) > maxLen {
+		name = name[:maxLen]
+	}
+	return name + "-" + hash
+}
+
+// listRevisions returns the generator's revisions, oldest first
+func (r *NetworkPolicyGeneratorReconciler) listRevisions(
+	ctx context.Context, g *securityv1.NetworkPolicyGenerator,
+) ([]appsv1.ControllerRevision, error) {
+	list := &appsv1.ControllerRevisionList{}
+	if err := r.List(ctx, list, client.InNamespace(g.Namespace), client.MatchingLabels(generatorLabels(g))); err != nil {
+		return nil, fmt.Errorf("failed to list policy revisions: %w", err)
+	}
+	revisions := slices.DeleteFunc(list.Items, func(rev appsv1.ControllerRevision) bool {
+		return !metav1.IsControlledBy(&rev, g)
+	})
+	slices.SortFunc(revisions, func(a, b appsv1.ControllerRevision) int { return cmp.Compare(a.Revision, b.Revision) })
+	return revisions, nil
+}
+
+// recordRevision stores the policy input of the applied policies as the
+// generator's current revision and prunes the revisions beyond spec.revisionHistoryLimit. Policies
+// equal to an earlier revision reuse it under the next revision number, the
+// way Deployments renumber the ReplicaSet they roll back to.
+func (r *NetworkPolicyGeneratorReconciler) recordRevision(
+	ctx context.Context, g *securityv1.NetworkPolicyGenerator, generated []enginePolicies,
+) error {
+	raw, err := encodeRevision(generated, &g.Spec)
+	if err != nil {
+		return err
+	}
+	revisions, err := r.listRevisions(ctx, g)
+	if err != nil {
+		return err
+	}
+	next := int64(1)
+	if len(revisions) > 0 {
+		next = revisions[len(revisions)-1].Revision + 1
+	}
+
+	name := revisionName(g, raw)
+	i := slices.IndexFunc(revisions, func(rev appsv1.ControllerRevision) bool { return rev.Name == name })
+	switch {
+	case i >= 0 && i == len(revisions)-1:
+	case i >= 0:
+		rev := revisions[i]
+		rev.Revision = next
+		if err := r.Update(ctx, &rev); err != nil {
+			return fmt.Errorf("failed to renumber policy revision %s: %w", name, err)
+		}
+		revisions = append(slices.Delete(revisions, i, i+1), rev)
+	default:
+		rev := appsv1.ControllerRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       g.Namespace,
+				Labels:          generatorLabels(g),
+				OwnerReferences: []metav1.OwnerReference{ownerReference(g)},
+			},
+			Data:     runtime.RawExtension{Raw: raw},
+			Revision: next,
+		}
+		if err := r.Create(ctx, &rev); err != nil {
+			return fmt.Errorf("failed to create policy revision %s: %w", name, err)
+		}
+		r.Recorder.Eventf(g, "Normal", "RevisionCreated", "Recorded policy revision %d", next)
+		revisions = append(revisions, rev)
+	}
+
+	g.Status.CurrentRevision = revisions[len(revisions)-1].Revision
+	return r.pruneRevisions(ctx, g, revisions[:len(revisions)-1])
+}
+
+// pruneRevisions deletes the oldest of the earlier revisions beyond
+// spec.revisionHistoryLimit
+func (r *NetworkPolicyGeneratorReconciler) pruneRevisions(
+	ctx context.Context, g *securityv1.NetworkPolicyGenerator, earlier []appsv1.ControllerRevision,
+) error {
+	limit := policy.DefaultRevisionHistoryLimit
+	if g.Spec.RevisionHistoryLimit != nil {
+		limit = int(*g.Spec.RevisionHistoryLimit)
+	}
+	for i := 0; i < len(earlier)-limit; i++ {
+		if err := r.Delete(ctx, &earlier[i]); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete policy revision %s: %w", earlier[i].Name, err)
+		}
+		log.FromContext(ctx).Info("Pruned policy revision", "revision", earlier[i].Revision)
+	}
+	return nil
+}
+
+// rollBackSpec replaces the spec of a generator whose spec.rollbackTo pins a
+// revision with the spec and engines recorded in it. Only the in-memory copy
+// changes, the way templates are applied; the mode, dry-run, drift and
+// revision settings stay as they are. The peers discovered from the cluster
+// are then resolved against the restored spec, as on any reconcile.
+func (r *NetworkPolicyGeneratorReconciler) rollBackSpec(
+	ctx context.Context, g *securityv1.NetworkPolicyGenerator,
+) error {
+	number := g.Spec.RollbackTo
+	if number == nil {
+		return nil
+	}
+	data, err := r.loadRevision(ctx, g, *number)
+	if err != nil {
+		r.Recorder.Eventf(g, "Warning", "RollbackFailed",
+			"Failed to roll back to revision %d: %v", *number, err)
+		log.FromContext(ctx).Error(err, "failed to load policy revision", "revision", *number)
+		return err
+	}
+
+	spec := data.Spec
+	spec.Mode, spec.Duration = g.Spec.Mode, g.Spec.Duration
+	spec.DryRun, spec.DriftPolicy = g.Spec.DryRun, g.Spec.DriftPolicy
+	spec.RevisionHistoryLimit, spec.RollbackTo = g.Spec.RevisionHistoryLimit, number
+	spec.PolicyEngines = data.Engines
+	g.Spec = spec
+	return nil
+}
+
+// loadRevision returns the policy input of the generator's revision number,
+// for spec.rollbackTo
+func (r *NetworkPolicyGeneratorReconciler) loadRevision(
+	ctx context.Context, g *securityv1.NetworkPolicyGenerator, number int64,
+) (*revisionData, error) {
+	revisions, err := r.listRevisions(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(revisions, func(rev appsv1.ControllerRevision) bool { return rev.Revision == number })
+	if i < 0 {
+		return nil, fmt.Errorf("policy revision %d not found", number)
+	}
+	return decodeRevision(revisions[i].Data.Raw)
+}
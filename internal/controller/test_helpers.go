@@ -23,6 +23,8 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
@@ -71,7 +73,7 @@ func createBasicGenerator(namespace, name string) *securityv1.NetworkPolicyGener
 					Direction: policy.DirectionIngress,
 				},
 				{
-					Type:      policy.PolicyTypeDeny,
+					Type:      policy.PolicyTypeAllow,
 					Port:      25,
 					Protocol:  policy.ProtocolTCP,
 					Direction: policy.DirectionEgress,
@@ -81,15 +83,24 @@ func createBasicGenerator(namespace, name string) *securityv1.NetworkPolicyGener
 	}
 }
 
+// recordedEvents drains the events a fake recorder has collected so far
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	return events
+}
+
 type mockClient struct {
 	client.Client
 	statusUpdateError error
 	deleteError       error
 	updateError       error
-	createError       error
+	applyError        error
 	getError          error
 	noopGet           bool
-	noopCreate        bool
+	noopApply         bool
 	noopUpdate        bool
 	noopDelete        bool
 	noopStatusUpdate  bool
@@ -133,14 +144,14 @@ func (m *mockClient) Update(ctx context.Context, obj client.Object, opts ...clie
 	return m.Client.Update(ctx, obj, opts...)
 }
 
-func (m *mockClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
-	if m.createError != nil {
-		return m.createError
+func (m *mockClient) Apply(ctx context.Context, obj runtime.ApplyConfiguration, opts ...client.ApplyOption) error {
+	if m.applyError != nil {
+		return m.applyError
 	}
-	if m.noopCreate {
+	if m.noopApply {
 		return nil
 	}
-	return m.Client.Create(ctx, obj, opts...)
+	return m.Client.Apply(ctx, obj, opts...)
 }
 
 type mockStatusWriter struct {
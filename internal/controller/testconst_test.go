@@ -12,6 +12,8 @@ const (
 
 	namedPortHTTP = "http"
 
+	pluginName = "acme"
+
 	// Plural resource names as they appear in a GroupResource.
 	resourceNetworkPolicies       = "networkpolicies"
 	resourceCiliumNetworkPolicies = "ciliumnetworkpolicies"
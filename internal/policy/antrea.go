@@ -0,0 +1,417 @@
+package policy
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+)
+
+// AntreaEngine generates Antrea-native NetworkPolicy and ClusterNetworkPolicy resources.
+// Unlike Kubernetes NetworkPolicy, Antrea-native policies do not isolate pods
+// implicitly: rules are evaluated in order and every rule carries an explicit
+// action, so deny-type generators end each rule list with the default action.
+type AntreaEngine struct{}
+
+// NewAntreaEngine creates a new Antrea policy engine
+func NewAntreaEngine() *AntreaEngine {
+	return &AntreaEngine{}
+}
+
+// EngineName returns "antrea"
+func (e *AntreaEngine) EngineName() string {
+	return EngineAntrea
+}
+
+// antreaSettings is the resolved form of spec.antrea with defaults applied
+type antreaSettings struct {
+	tier          string
+	priority      float64
+	defaultAction string
+	clusterScoped bool
+}
+
+// resolveAntreaSettings applies engine defaults to an optional AntreaConfig
+func resolveAntreaSettings(cfg *securityv1.AntreaConfig) antreaSettings {
+	settings := antreaSettings{
+		tier:          AntreaDefaultTier,
+		priority:      AntreaDefaultPriority,
+		defaultAction: AntreaDefaultRuleAction,
+	}
+	if cfg == nil {
+		return settings
+	}
+	if cfg.Tier != "" {
+		settings.tier = cfg.Tier
+	}
+	if cfg.Priority > 0 {
+		settings.priority = float64(cfg.Priority)
+	}
+	if cfg.DefaultAction != "" {
+		settings.defaultAction = cfg.DefaultAction
+	}
+	settings.clusterScoped = cfg.ClusterScoped
+	return settings
+}
+
+// GeneratePolicies generates Antrea NetworkPolicy objects, or a single
+// ClusterNetworkPolicy when spec.antrea.clusterScoped is set
+func (e *AntreaEngine) GeneratePolicies(generator *securityv1.NetworkPolicyGenerator) ([]runtime.Object, error) {
+	if err := rejectL7Rules(EngineAntrea, generator.Spec.GlobalRules); err != nil {
+		return nil, err
+	}
+	if err := rejectNotICMPRules(EngineAntrea, generator.Spec.GlobalRules); err != nil {
+		return nil, err
+	}
+	for i, rule := range generator.Spec.GlobalRules {
+		if rule.Protocol == ProtocolICMPv6 {
+			return nil, fmt.Errorf("global rule %d: ICMPv6 rules are not supported by the antrea policy engine, whose icmp protocol match cannot select ICMPv6; use the cilium or calico engine", i)
+		}
+		if rule.Protocol == ProtocolICMP && rule.ICMP == nil {
+			return nil, fmt.Errorf("global rule %d: ICMP rules require icmp", i)
+		}
+	}
+
+	settings := resolveAntreaSettings(generator.Spec.Antrea)
+
+	podSelector := PodLabelSelector(generator)
+
+	// A ClusterNetworkPolicy applies to the denied namespaces through a
+	// namespace selector, so deniedNamespaceSelector is rendered natively
+	// there instead of through the namespaces it matched.
+	namespaces := []string{generator.Namespace}
+	var nsSelector *metav1.LabelSelector
+	if generator.Spec.Policy.Type == PolicyTypeAllow {
+		namespaces = generator.Spec.Policy.DeniedNamespaces
+		nsSelector = generator.Spec.Policy.DeniedNamespaceSelector
+	}
+	targetNamespaces := TargetNamespaces(generator)
+	if settings.clusterScoped && len(namespaces) == 0 && nsSelector == nil ||
+		!settings.clusterScoped && len(targetNamespaces) == 0 {
+		return nil, nil
+	}
+
+	spec := &AntreaPolicySpec{
+		Tier:     settings.tier,
+		Priority: settings.priority,
+	}
+	dnsRules := dnsEgressRulesAntrea(&generator.Spec, settings.clusterScoped)
+	if generator.Spec.Policy.Type == PolicyTypeAllow {
+		e.applyDeniedNamespaceRules(spec, generator.Spec.Policy.DeniedNamespaces, nsSelector, dnsRules, settings.defaultAction)
+	} else {
+		e.applyAllowedNamespaceRules(spec, AllowedNamespaces(generator), generator.Spec.Policy.AllowedNamespaceSelector, dnsRules)
+	}
+
+	e.applyGlobalRules(spec, generator.Spec.GlobalRules, generator.Spec.IPFamilies, settings)
+	e.applyCIDRRules(spec, generator.Spec.CIDRRules, settings.defaultAction)
+	e.applyFQDNRules(spec, generator.Spec.FQDNRules)
+
+	// Allow kubelet probes and other node-originated traffic
+	if cidrs := NodeCIDRs(generator); len(cidrs) > 0 {
+		peers := make([]AntreaPeer, len(cidrs))
+		for i, cidr := range cidrs {
+			peers[i] = AntreaPeer{IPBlock: &AntreaIPBlock{CIDR: cidr}}
+		}
+		spec.Ingress = append(spec.Ingress, AntreaRule{Action: AntreaActionAllow, From: peers})
+	}
+
+	// Allow egress to the Kubernetes API server
+	if cidrs, ports := APIServerEndpoints(generator); len(cidrs) > 0 {
+		rule := AntreaRule{Action: AntreaActionAllow}
+		for _, cidr := range cidrs {
+			rule.To = append(rule.To, AntreaPeer{IPBlock: &AntreaIPBlock{CIDR: cidr}})
+		}
+		for _, port := range ports {
+			rule.Ports = append(rule.Ports, AntreaPort{Protocol: ProtocolTCP, Port: ptr.To(intstr.FromInt32(port))})
+		}
+		spec.Egress = append(spec.Egress, rule)
+	}
+
+	// Antrea has no implicit isolation, so deny-type policies close each
+	// direction with a catch-all rule carrying the default action.
+	if generator.Spec.Policy.Type != PolicyTypeAllow {
+		spec.Ingress = append(spec.Ingress, AntreaRule{Action: settings.defaultAction})
+		spec.Egress = append(spec.Egress, AntreaRule{Action: settings.defaultAction})
+	}
+
+	// Without rules in a direction, Antrea leaves that direction untouched
+	if !EnforcesDirection(&generator.Spec, DirectionIngress) {
+		spec.Ingress = nil
+	}
+	if !EnforcesDirection(&generator.Spec, DirectionEgress) {
+		spec.Egress = nil
+	}
+
+	if settings.clusterScoped {
+		if len(namespaces) > 0 {
+			spec.AppliedTo = append(spec.AppliedTo, AntreaAppliedTo{
+				PodSelector:       podSelector,
+				NamespaceSelector: antreaNamespaceSetSelector(namespaces),
+			})
+		}
+		if nsSelector != nil {
+			spec.AppliedTo = append(spec.AppliedTo, AntreaAppliedTo{
+				PodSelector:       podSelector.DeepCopy(),
+				NamespaceSelector: nsSelector.DeepCopy(),
+			})
+		}
+		return []runtime.Object{&AntreaClusterNetworkPolicy{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: AntreaAPIVersion,
+				Kind:       AntreaClusterKind,
+			},
+			// Cluster-scoped objects cannot be owned by a namespaced generator;
+			// cleanup relies on the generator finalizer instead.
+			ObjectMeta: metav1.ObjectMeta{
+				Name: ClusterPolicyName(generator.Namespace, generator.Name),
+			},
+			Spec: spec,
+		}}, nil
+	}
+
+	spec.AppliedTo = []AntreaAppliedTo{{PodSelector: podSelector}}
+	policies := make([]runtime.Object, 0, len(targetNamespaces))
+	for _, ns := range targetNamespaces {
+		policies = append(policies, &AntreaNetworkPolicy{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: AntreaAPIVersion,
+				Kind:       AntreaKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      PolicyName(generator.Name),
+				Namespace: ns,
+				OwnerReferences: []metav1.OwnerReference{
+					{
+						APIVersion: generator.APIVersion,
+						Kind:       generator.Kind,
+						Name:       generator.Name,
+						UID:        generator.UID,
+						Controller: ptr.To(true),
+					},
+				},
+			},
+			Spec: spec.DeepCopy(),
+		})
+	}
+
+	return policies, nil
+}
+
+// applyAllowedNamespaceRules adds the deny-type rules: allow the listed and
+// selected namespaces in both directions, plus the DNS egress rules
+func (e *AntreaEngine) applyAllowedNamespaceRules(spec *AntreaPolicySpec, namespaces []string, selector *metav1.LabelSelector, dnsRules []AntreaRule) {
+	peers := buildAntreaNamespacePeers(namespaces)
+	if selector != nil {
+		peers = append(peers, AntreaPeer{NamespaceSelector: selector.DeepCopy()})
+	}
+	if len(peers) > 0 {
+		spec.Ingress = append(spec.Ingress, AntreaRule{Action: AntreaActionAllow, From: peers})
+		spec.Egress = append(spec.Egress, AntreaRule{Action: AntreaActionAllow, To: deepCopyAntreaPeers(peers)})
+	}
+	spec.Egress = append(spec.Egress, dnsRules...)
+}
+
+// applyDeniedNamespaceRules adds the allow-type rules: keep DNS working, then
+// block the denied and selected namespaces in both directions with the
+// default action
+func (e *AntreaEngine) applyDeniedNamespaceRules(spec *AntreaPolicySpec, namespaces []string, selector *metav1.LabelSelector, dnsRules []AntreaRule, action string) {
+	var peers []AntreaPeer
+	if len(namespaces) > 0 {
+		peers = append(peers, AntreaPeer{NamespaceSelector: antreaNamespaceSetSelector(namespaces)})
+	}
+	if selector != nil {
+		peers = append(peers, AntreaPeer{NamespaceSelector: selector.DeepCopy()})
+	}
+	if len(peers) == 0 {
+		return
+	}
+	spec.Ingress = append(spec.Ingress, AntreaRule{Action: action, From: peers})
+	spec.Egress = append(spec.Egress, dnsRules...)
+	spec.Egress = append(spec.Egress, AntreaRule{Action: action, To: deepCopyAntreaPeers(peers)})
+}
+
+// applyGlobalRules adds global rules to the Antrea policy spec. Allow rules
+// without from/to peers are open to every address of the generator's IP
+// families. Deny rules carry the default action, match every peer unless
+// from/to is set, and are placed before every other rule of their direction,
+// since the first matching rule wins.
+func (e *AntreaEngine) applyGlobalRules(spec *AntreaPolicySpec, globalRules []securityv1.GlobalRule, ipFamilies []string, settings antreaSettings) {
+	var denyIngress, denyEgress []AntreaRule
+	for _, rule := range globalRules {
+		antreaRule := AntreaRule{Action: AntreaActionAllow}
+		if IsICMPProtocol(rule.Protocol) {
+			antreaRule.Protocols = []AntreaProtocol{{ICMP: antreaICMPProtocol(rule.ICMP)}}
+		} else {
+			port := globalRulePort(rule)
+			antreaRule.Ports = []AntreaPort{{Protocol: rule.Protocol, Port: &port, EndPort: globalRuleEndPort(rule)}}
+		}
+		deny := rule.Type == PolicyTypeDeny
+		if deny {
+			antreaRule.Action = settings.defaultAction
+		}
+
+		switch rule.Direction {
+		case DirectionIngress:
+			antreaRule.From = antreaGlobalRulePeers(rule.From, ipFamilies, deny, settings.clusterScoped)
+			if deny {
+				denyIngress = append(denyIngress, antreaRule)
+			} else {
+				spec.Ingress = append(spec.Ingress, antreaRule)
+			}
+		case DirectionEgress:
+			antreaRule.To = antreaGlobalRulePeers(rule.To, ipFamilies, deny, settings.clusterScoped)
+			if deny {
+				denyEgress = append(denyEgress, antreaRule)
+			} else {
+				spec.Egress = append(spec.Egress, antreaRule)
+			}
+		}
+	}
+	if len(denyIngress) > 0 {
+		spec.Ingress = append(denyIngress, spec.Ingress...)
+	}
+	if len(denyEgress) > 0 {
+		spec.Egress = append(denyEgress, spec.Egress...)
+	}
+}
+
+// antreaICMPProtocol converts a GlobalRule ICMP match into an Antrea icmp
+// protocol match
+func antreaICMPProtocol(match *securityv1.ICMPMatch) *AntreaICMPProtocol {
+	icmp := &AntreaICMPProtocol{ICMPType: ptr.To(match.Type)}
+	if match.Code != nil {
+		icmp.ICMPCode = ptr.To(*match.Code)
+	}
+	return icmp
+}
+
+// antreaGlobalRulePeers converts the from/to peers of a global rule into
+// Antrea peers. Without peers, allow rules match every address of the IP
+// families and deny rules match everything. A ClusterNetworkPolicy peer
+// without a namespace selector would match pods in every namespace, so
+// pod-only peers are limited to the applied-to pods' own namespace there.
+func antreaGlobalRulePeers(peers []securityv1.RulePeer, ipFamilies []string, deny, clusterScoped bool) []AntreaPeer {
+	if len(peers) == 0 {
+		if deny {
+			return nil
+		}
+		cidrs := AllTrafficCIDRs(ipFamilies)
+		result := make([]AntreaPeer, len(cidrs))
+		for i, cidr := range cidrs {
+			result[i] = AntreaPeer{IPBlock: &AntreaIPBlock{CIDR: cidr}}
+		}
+		return result
+	}
+
+	result := make([]AntreaPeer, len(peers))
+	for i, peer := range peers {
+		if peer.CIDR != "" {
+			result[i] = AntreaPeer{IPBlock: &AntreaIPBlock{CIDR: peer.CIDR}}
+			continue
+		}
+		result[i] = AntreaPeer{NamespaceSelector: peerNamespaceSelector(peer)}
+		if len(peer.PodSelector) > 0 {
+			result[i].PodSelector = &metav1.LabelSelector{MatchLabels: peer.PodSelector}
+		}
+		if result[i].NamespaceSelector == nil && clusterScoped {
+			result[i].Namespaces = &AntreaPeerNamespaces{Match: AntreaNamespaceMatchSelf}
+		}
+	}
+	return result
+}
+
+// applyCIDRRules adds CIDR-based rules to the Antrea policy spec. Antrea
+// ipBlocks have no "except" list, so each excluded range becomes a rule with
+// the default action placed directly before the allow rule.
+func (e *AntreaEngine) applyCIDRRules(spec *AntreaPolicySpec, cidrRules []securityv1.CIDRRule, exceptAction string) {
+	for _, rule := range cidrRules {
+		rules := make([]AntreaRule, 0, len(rule.Except)+1)
+		for _, except := range rule.Except {
+			rules = append(rules, antreaCIDRRule(rule.Direction, except, exceptAction))
+		}
+		rules = append(rules, antreaCIDRRule(rule.Direction, rule.CIDR, AntreaActionAllow))
+
+		switch rule.Direction {
+		case DirectionIngress:
+			spec.Ingress = append(spec.Ingress, rules...)
+		case DirectionEgress:
+			spec.Egress = append(spec.Egress, rules...)
+		}
+	}
+}
+
+// applyFQDNRules adds fqdn egress rules to the Antrea policy spec
+func (e *AntreaEngine) applyFQDNRules(spec *AntreaPolicySpec, fqdnRules []securityv1.FQDNRule) {
+	for _, rule := range fqdnRules {
+		antreaRule := AntreaRule{
+			Action: AntreaActionAllow,
+			To:     []AntreaPeer{{FQDN: fqdnRuleName(rule)}},
+		}
+		for _, port := range rule.Ports {
+			antreaRule.Ports = append(antreaRule.Ports, AntreaPort{
+				Protocol: fqdnPortProtocol(port),
+				Port:     ptr.To(intstr.FromInt32(port.Port)),
+			})
+		}
+		spec.Egress = append(spec.Egress, antreaRule)
+	}
+}
+
+// antreaCIDRRule builds a rule matching a single CIDR in the given direction
+func antreaCIDRRule(direction, cidr, action string) AntreaRule {
+	peers := []AntreaPeer{{IPBlock: &AntreaIPBlock{CIDR: cidr}}}
+	if direction == DirectionIngress {
+		return AntreaRule{Action: action, From: peers}
+	}
+	return AntreaRule{Action: action, To: peers}
+}
+
+// buildAntreaNamespacePeers creates one namespace peer per namespace
+func buildAntreaNamespacePeers(namespaces []string) []AntreaPeer {
+	peers := make([]AntreaPeer, len(namespaces))
+	for i, ns := range namespaces {
+		peers[i] = AntreaPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{LabelK8sNamespace: ns},
+			},
+		}
+	}
+	return peers
+}
+
+// antreaNamespaceSetSelector creates a namespace selector matching any of the namespaces
+func antreaNamespaceSetSelector(namespaces []string) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      LabelK8sNamespace,
+			Operator: metav1.LabelSelectorOpIn,
+			Values:   append([]string(nil), namespaces...),
+		}},
+	}
+}
+
+// dnsEgressRulesAntrea creates the Antrea egress rule allowing DNS resolution
+// to the destinations spec.dns selects. Mode "none" yields no rule.
+func dnsEgressRulesAntrea(spec *securityv1.NetworkPolicyGeneratorSpec, clusterScoped bool) []AntreaRule {
+	peers := DNSPeers(spec)
+	if len(peers) == 0 {
+		return nil
+	}
+	protocols := DNSProtocols(spec)
+	ports := make([]AntreaPort, len(protocols))
+	for i, protocol := range protocols {
+		ports[i] = AntreaPort{Protocol: protocol, Port: ptr.To(intstr.FromInt32(DNSPort))}
+	}
+	return []AntreaRule{{
+		Action: AntreaActionAllow,
+		Ports:  ports,
+		To:     antreaGlobalRulePeers(peers, spec.IPFamilies, false, clusterScoped),
+	}}
+}
+
+// Ensure AntreaEngine implements PolicyEngine (compile-time check)
+var _ PolicyEngine = (*AntreaEngine)(nil)
@@ -0,0 +1,759 @@
+package policy
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+)
+
+func TestAntreaEngine(t *testing.T) {
+	engine := NewAntreaEngine()
+
+	t.Run("EngineName", func(t *testing.T) {
+		assert.Equal(t, EngineAntrea, engine.EngineName())
+	})
+
+	t.Run("Generate Basic Deny Policy", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+				UID:       types.UID("test-uid"),
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*AntreaNetworkPolicy)
+		assert.Equal(t, "test-policy-generated", policy.Name)
+		assert.Equal(t, nsTest, policy.Namespace)
+		assert.Equal(t, AntreaAPIVersion, policy.APIVersion)
+		assert.Equal(t, AntreaKind, policy.Kind)
+		require.Len(t, policy.OwnerReferences, 1)
+		assert.Equal(t, AntreaDefaultTier, policy.Spec.Tier)
+		assert.Equal(t, AntreaDefaultPriority, policy.Spec.Priority)
+		require.Len(t, policy.Spec.AppliedTo, 1)
+		assert.Empty(t, policy.Spec.AppliedTo[0].PodSelector.MatchLabels)
+
+		// Deny all: catch-all drop on ingress, DNS allow then catch-all drop on egress
+		require.Len(t, policy.Spec.Ingress, 1)
+		assert.Equal(t, AntreaActionDrop, policy.Spec.Ingress[0].Action)
+		assert.Empty(t, policy.Spec.Ingress[0].From)
+		require.Len(t, policy.Spec.Egress, 2)
+		assert.Equal(t, AntreaActionAllow, policy.Spec.Egress[0].Action)
+		assert.Equal(t, "kube-dns", policy.Spec.Egress[0].To[0].PodSelector.MatchLabels["k8s-app"])
+		assert.Equal(t, AntreaActionDrop, policy.Spec.Egress[1].Action)
+	})
+
+	t.Run("Generate Deny Type with Allowed Namespaces and Settings", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Antrea: &securityv1.AntreaConfig{
+					Tier:          "securityops",
+					Priority:      10,
+					DefaultAction: AntreaActionReject,
+				},
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1, nsAllowed2},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*AntreaNetworkPolicy)
+		assert.Equal(t, "securityops", policy.Spec.Tier)
+		assert.Equal(t, float64(10), policy.Spec.Priority)
+
+		require.Len(t, policy.Spec.Ingress, 2)
+		assert.Equal(t, AntreaActionAllow, policy.Spec.Ingress[0].Action)
+		require.Len(t, policy.Spec.Ingress[0].From, 2)
+		assert.Equal(t, nsAllowed1, policy.Spec.Ingress[0].From[0].NamespaceSelector.MatchLabels[LabelK8sNamespace])
+		assert.Equal(t, AntreaActionReject, policy.Spec.Ingress[1].Action)
+
+		// namespace allow + DNS + catch-all
+		require.Len(t, policy.Spec.Egress, 3)
+		assert.Equal(t, AntreaActionReject, policy.Spec.Egress[2].Action)
+	})
+
+	t.Run("Generate Allow Type with Denied Namespaces", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type:             PolicyTypeAllow,
+					DeniedNamespaces: []string{"denied-ns1", "denied-ns2"},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 2)
+
+		for i, obj := range objects {
+			policy := obj.(*AntreaNetworkPolicy)
+			assert.Equal(t, spec.Spec.Policy.DeniedNamespaces[i], policy.Namespace)
+			require.Len(t, policy.Spec.Ingress, 1)
+			assert.Equal(t, AntreaActionDrop, policy.Spec.Ingress[0].Action)
+			assert.Equal(t, []string{"denied-ns1", "denied-ns2"},
+				policy.Spec.Ingress[0].From[0].NamespaceSelector.MatchExpressions[0].Values)
+			// DNS allow precedes the drop so denying kube-system keeps DNS working
+			require.Len(t, policy.Spec.Egress, 2)
+			assert.Equal(t, AntreaActionAllow, policy.Spec.Egress[0].Action)
+			assert.Equal(t, AntreaActionDrop, policy.Spec.Egress[1].Action)
+		}
+	})
+
+	t.Run("Allow Type with No Denied Namespaces", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeAllow,
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		assert.Empty(t, objects)
+	})
+
+	t.Run("Generate Cluster Scoped Policy", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+				UID:       types.UID("test-uid"),
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Antrea:       &securityv1.AntreaConfig{ClusterScoped: true},
+				Policy: securityv1.PolicyConfig{
+					Type:             PolicyTypeAllow,
+					DeniedNamespaces: []string{"denied-ns1", "denied-ns2"},
+					PodSelector:      map[string]string{labelApp: labelValueWeb},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*AntreaClusterNetworkPolicy)
+		assert.Equal(t, AntreaClusterKind, policy.Kind)
+		assert.Equal(t, "test-namespace-test-policy-generated", policy.Name)
+		assert.Empty(t, policy.Namespace)
+		assert.Empty(t, policy.OwnerReferences)
+		require.Len(t, policy.Spec.AppliedTo, 1)
+		assert.Equal(t, labelValueWeb, policy.Spec.AppliedTo[0].PodSelector.MatchLabels[labelApp])
+		assert.Equal(t, []string{"denied-ns1", "denied-ns2"},
+			policy.Spec.AppliedTo[0].NamespaceSelector.MatchExpressions[0].Values)
+	})
+
+	t.Run("Generate Policy with Namespace Selectors", func(t *testing.T) {
+		selector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type:                    PolicyTypeAllow,
+					DeniedNamespaceSelector: selector,
+				},
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{MatchedNamespaces: []string{"dev-a"}},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*AntreaNetworkPolicy)
+		assert.Equal(t, "dev-a", policy.Namespace)
+		assert.Equal(t, []AntreaPeer{{NamespaceSelector: selector}}, policy.Spec.Ingress[0].From)
+		assert.Equal(t, []AntreaPeer{{NamespaceSelector: selector}}, policy.Spec.Egress[1].To)
+
+		// A cluster-scoped policy applies to the selected namespaces natively
+		spec.Spec.Antrea = &securityv1.AntreaConfig{ClusterScoped: true}
+		spec.Status.MatchedNamespaces = nil
+		objects, err = engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		cluster := objects[0].(*AntreaClusterNetworkPolicy)
+		require.Len(t, cluster.Spec.AppliedTo, 1)
+		assert.Equal(t, selector, cluster.Spec.AppliedTo[0].NamespaceSelector)
+
+		spec.Spec.Antrea = nil
+		spec.Spec.Policy = securityv1.PolicyConfig{
+			Type:                     PolicyTypeDeny,
+			AllowedNamespaces:        []string{nsOne},
+			AllowedNamespaceSelector: selector,
+		}
+		objects, err = engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy = objects[0].(*AntreaNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress[0].From, 2)
+		assert.Equal(t, selector, policy.Spec.Ingress[0].From[1].NamespaceSelector)
+	})
+
+	t.Run("Deny Type Allows Same Namespace", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type:               PolicyTypeDeny,
+					AllowedNamespaces:  []string{nsAllowed1},
+					AllowSameNamespace: ptr.To(true),
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*AntreaNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress[0].From, 2)
+		assert.Equal(t, nsTest, policy.Spec.Ingress[0].From[1].NamespaceSelector.MatchLabels[LabelK8sNamespace])
+	})
+
+	t.Run("Generate Ingress-Only Policy", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				PolicyTypes:  []string{DirectionIngress},
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress},
+					{Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*AntreaNetworkPolicy)
+		// The catch-all drop closing ingress stays; egress has no rules at all
+		require.NotEmpty(t, policy.Spec.Ingress)
+		assert.Equal(t, AntreaActionDrop, policy.Spec.Ingress[len(policy.Spec.Ingress)-1].Action)
+		assert.Nil(t, policy.Spec.Egress)
+	})
+
+	t.Run("Allow Node Traffic", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				AllowNodeTraffic: true,
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{
+				NodeCIDRs: []string{"10.244.1.0/24", cidrHost192},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*AntreaNetworkPolicy)
+		// node allow + catch-all
+		require.Len(t, policy.Spec.Ingress, 2)
+		assert.Equal(t, AntreaActionAllow, policy.Spec.Ingress[0].Action)
+		require.Len(t, policy.Spec.Ingress[0].From, 2)
+		assert.Equal(t, cidrHost192, policy.Spec.Ingress[0].From[1].IPBlock.CIDR)
+	})
+
+	t.Run("Allow API Server", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				AllowAPIServer: true,
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{
+				APIServerCIDRs: []string{cidrHost192},
+				APIServerPorts: []int32{6443},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*AntreaNetworkPolicy)
+		// DNS + API server + catch-all
+		require.Len(t, policy.Spec.Egress, 3)
+		apiServer := policy.Spec.Egress[1]
+		assert.Equal(t, AntreaActionAllow, apiServer.Action)
+		assert.Equal(t, cidrHost192, apiServer.To[0].IPBlock.CIDR)
+		assert.Equal(t, int32(6443), apiServer.Ports[0].Port.IntVal)
+	})
+
+	t.Run("Generate Policy with Custom DNS", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				DNS: &securityv1.DNSConfig{
+					Mode: DNSModeCustom,
+					To: []securityv1.RulePeer{
+						{CIDR: cidrNodeLocal},
+						{Namespaces: []string{nsAllowed2}, PodSelector: map[string]string{labelApp: "dns"}},
+					},
+					Protocols: []string{ProtocolUDP},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*AntreaNetworkPolicy)
+		// namespace egress + DNS + catch-all
+		require.Len(t, policy.Spec.Egress, 3)
+		dns := policy.Spec.Egress[1]
+		require.Len(t, dns.Ports, 1)
+		assert.Equal(t, ProtocolUDP, dns.Ports[0].Protocol)
+		require.Len(t, dns.To, 2)
+		assert.Equal(t, cidrNodeLocal, dns.To[0].IPBlock.CIDR)
+		assert.Equal(t, []string{nsAllowed2}, dns.To[1].NamespaceSelector.MatchExpressions[0].Values)
+		assert.Equal(t, "dns", dns.To[1].PodSelector.MatchLabels[labelApp])
+
+		spec.Spec.DNS = &securityv1.DNSConfig{Mode: DNSModeNone}
+		objects, err = engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		assert.Len(t, objects[0].(*AntreaNetworkPolicy).Spec.Egress, 2)
+	})
+
+	t.Run("Generate Policy with Pod Label Selector", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+					PodLabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{labelTier: labelValueFrontend},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: labelApp, Operator: metav1.LabelSelectorOpIn, Values: []string{"api", "worker"}},
+							{Key: "batch", Operator: metav1.LabelSelectorOpDoesNotExist},
+						},
+					},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*AntreaNetworkPolicy)
+		require.Len(t, policy.Spec.AppliedTo, 1)
+		assert.Equal(t, spec.Spec.Policy.PodLabelSelector, policy.Spec.AppliedTo[0].PodSelector)
+	})
+
+	t.Run("Generate Policy with Global Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress},
+					{Type: PolicyTypeAllow, NamedPort: namedPortHTTP, Protocol: ProtocolTCP, Direction: DirectionEgress},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		policy := objects[0].(*AntreaNetworkPolicy)
+		// global ingress rule is ordered before the catch-all
+		require.Len(t, policy.Spec.Ingress, 2)
+		assert.Equal(t, AntreaActionAllow, policy.Spec.Ingress[0].Action)
+		assert.Equal(t, int32(80), policy.Spec.Ingress[0].Ports[0].Port.IntVal)
+		assert.Equal(t, CIDRAllTraffic, policy.Spec.Ingress[0].From[0].IPBlock.CIDR)
+		assert.Equal(t, AntreaActionDrop, policy.Spec.Ingress[1].Action)
+
+		// DNS + global egress + catch-all
+		require.Len(t, policy.Spec.Egress, 3)
+		assert.Equal(t, namedPortHTTP, policy.Spec.Egress[1].Ports[0].Port.StrVal)
+	})
+
+	t.Run("Generate Policy with ICMP Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Protocol: ProtocolICMP, Direction: DirectionIngress, ICMP: &securityv1.ICMPMatch{Type: 8}},
+					{Type: PolicyTypeDeny, Protocol: ProtocolICMP, Direction: DirectionIngress, ICMP: &securityv1.ICMPMatch{Type: 3, Code: ptr.To(int32(4))}},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		policy := objects[0].(*AntreaNetworkPolicy)
+		// deny rule, allow rule, catch-all
+		require.Len(t, policy.Spec.Ingress, 3)
+		deny := policy.Spec.Ingress[0]
+		assert.Equal(t, AntreaActionDrop, deny.Action)
+		assert.Empty(t, deny.Ports)
+		assert.Equal(t, []AntreaProtocol{{ICMP: &AntreaICMPProtocol{ICMPType: ptr.To(int32(3)), ICMPCode: ptr.To(int32(4))}}}, deny.Protocols)
+
+		allow := policy.Spec.Ingress[1]
+		assert.Equal(t, AntreaActionAllow, allow.Action)
+		assert.Equal(t, []AntreaProtocol{{ICMP: &AntreaICMPProtocol{ICMPType: ptr.To(int32(8))}}}, allow.Protocols)
+		assert.Equal(t, CIDRAllTraffic, allow.From[0].IPBlock.CIDR)
+
+		data, err := json.Marshal(allow)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"protocols":[{"icmp":{"icmpType":8}}]`)
+	})
+
+	t.Run("Generate Dual-Stack Global Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				IPFamilies: []string{IPFamilyIPv4, IPFamilyIPv6},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		// DNS + global egress + catch-all
+		egress := objects[0].(*AntreaNetworkPolicy).Spec.Egress
+		require.Len(t, egress, 3)
+		require.Len(t, egress[1].To, 2)
+		assert.Equal(t, CIDRAllTraffic, egress[1].To[0].IPBlock.CIDR)
+		assert.Equal(t, CIDRAllTrafficIPv6, egress[1].To[1].IPBlock.CIDR)
+	})
+
+	t.Run("Global Rules with Peers", func(t *testing.T) {
+		newSpec := func(clusterScoped bool) *securityv1.NetworkPolicyGenerator {
+			return &securityv1.NetworkPolicyGenerator{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      nameTestPolicy,
+					Namespace: nsTest,
+				},
+				Spec: securityv1.NetworkPolicyGeneratorSpec{
+					PolicyEngine: EngineAntrea,
+					Antrea:       &securityv1.AntreaConfig{ClusterScoped: clusterScoped},
+					Policy: securityv1.PolicyConfig{
+						Type: PolicyTypeDeny,
+					},
+					GlobalRules: []securityv1.GlobalRule{
+						{
+							Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 5432,
+							From: []securityv1.RulePeer{
+								{PodSelector: map[string]string{labelApp: labelValueWeb}},
+								{Namespaces: []string{nsOne}},
+							},
+						},
+						{
+							Type: PolicyTypeDeny, Direction: DirectionEgress, Protocol: ProtocolTCP, Port: 25,
+							To: []securityv1.RulePeer{{CIDR: cidr10Slash8}},
+						},
+					},
+				},
+			}
+		}
+
+		objects, err := engine.GeneratePolicies(newSpec(false))
+		require.NoError(t, err)
+		policySpec := objects[0].(*AntreaNetworkPolicy).Spec
+
+		// global ingress + catch-all
+		require.Len(t, policySpec.Ingress, 2)
+		from := policySpec.Ingress[0].From
+		require.Len(t, from, 2)
+		assert.Equal(t, labelValueWeb, from[0].PodSelector.MatchLabels[labelApp])
+		assert.Nil(t, from[0].NamespaceSelector)
+		assert.Nil(t, from[0].Namespaces)
+		assert.Equal(t, []string{nsOne}, from[1].NamespaceSelector.MatchExpressions[0].Values)
+
+		// The deny rule only matches its peer
+		assert.Equal(t, AntreaDefaultRuleAction, policySpec.Egress[0].Action)
+		assert.Equal(t, []AntreaPeer{{IPBlock: &AntreaIPBlock{CIDR: cidr10Slash8}}}, policySpec.Egress[0].To)
+
+		// A pod-only peer of a ClusterNetworkPolicy stays in the pods' own namespace
+		objects, err = engine.GeneratePolicies(newSpec(true))
+		require.NoError(t, err)
+		from = objects[0].(*AntreaClusterNetworkPolicy).Spec.Ingress[0].From
+		assert.Equal(t, &AntreaPeerNamespaces{Match: AntreaNamespaceMatchSelf}, from[0].Namespaces)
+		assert.Nil(t, from[1].Namespaces)
+	})
+
+	t.Run("Generate Policy with Port Range", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Port: 10000, EndPort: 20000, Protocol: ProtocolUDP, Direction: DirectionIngress},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		port := objects[0].(*AntreaNetworkPolicy).Spec.Ingress[0].Ports[0]
+		assert.Equal(t, int32(10000), port.Port.IntVal)
+		require.NotNil(t, port.EndPort)
+		assert.Equal(t, int32(20000), *port.EndPort)
+	})
+
+	t.Run("Generate Policy with CIDR Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				CIDRRules: []securityv1.CIDRRule{
+					{CIDR: cidr10Slash8, Except: []string{"10.1.0.0/16"}, Direction: DirectionEgress},
+					{CIDR: cidr192Slash24, Direction: DirectionIngress},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		policy := objects[0].(*AntreaNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress, 2)
+		assert.Equal(t, cidr192Slash24, policy.Spec.Ingress[0].From[0].IPBlock.CIDR)
+
+		// DNS + except drop + CIDR allow + catch-all
+		require.Len(t, policy.Spec.Egress, 4)
+		assert.Equal(t, AntreaActionDrop, policy.Spec.Egress[1].Action)
+		assert.Equal(t, "10.1.0.0/16", policy.Spec.Egress[1].To[0].IPBlock.CIDR)
+		assert.Equal(t, AntreaActionAllow, policy.Spec.Egress[2].Action)
+		assert.Equal(t, cidr10Slash8, policy.Spec.Egress[2].To[0].IPBlock.CIDR)
+	})
+
+	t.Run("Generate Policy with FQDN Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				FQDNRules: []securityv1.FQDNRule{
+					{MatchPattern: fqdnWildcard, Ports: []securityv1.FQDNPort{{Port: 443}}},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		policy := objects[0].(*AntreaNetworkPolicy)
+		// DNS + FQDN allow + catch-all
+		require.Len(t, policy.Spec.Egress, 3)
+		fqdn := policy.Spec.Egress[1]
+		assert.Equal(t, AntreaActionAllow, fqdn.Action)
+		assert.Equal(t, fqdnWildcard, fqdn.To[0].FQDN)
+		require.Len(t, fqdn.Ports, 1)
+		assert.Equal(t, ProtocolTCP, fqdn.Ports[0].Protocol)
+		assert.Equal(t, int32(443), fqdn.Ports[0].Port.IntVal)
+		assert.Equal(t, AntreaDefaultRuleAction, policy.Spec.Egress[2].Action)
+	})
+
+	t.Run("Deny Global Rules Use The Default Action First", func(t *testing.T) {
+		generator := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineAntrea,
+				Antrea:       &securityv1.AntreaConfig{DefaultAction: AntreaActionReject},
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 80},
+					{Type: PolicyTypeDeny, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 22},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(generator)
+		require.NoError(t, err)
+		spec := objects[0].(*AntreaNetworkPolicy).Spec
+
+		// deny 22 + allowed namespace + allow 80 + catch-all
+		require.Len(t, spec.Ingress, 4)
+		assert.Equal(t, AntreaActionReject, spec.Ingress[0].Action)
+		assert.Empty(t, spec.Ingress[0].From)
+		assert.Equal(t, int32(22), spec.Ingress[0].Ports[0].Port.IntVal)
+		assert.Equal(t, AntreaActionAllow, spec.Ingress[1].Action)
+		assert.Equal(t, AntreaActionAllow, spec.Ingress[2].Action)
+		assert.Equal(t, int32(80), spec.Ingress[2].Ports[0].Port.IntVal)
+	})
+}
+
+func TestAntreaNetworkPolicyDeepCopy(t *testing.T) {
+	original := &AntreaNetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: nameTest, Namespace: nsDefault},
+		Spec: &AntreaPolicySpec{
+			Tier:     AntreaDefaultTier,
+			Priority: AntreaDefaultPriority,
+			AppliedTo: []AntreaAppliedTo{{
+				PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labelApp: labelValueWeb}},
+			}},
+			Ingress: []AntreaRule{{
+				Action:    AntreaActionAllow,
+				Protocols: []AntreaProtocol{{ICMP: &AntreaICMPProtocol{ICMPType: ptr.To(int32(8))}}},
+				From:      []AntreaPeer{{IPBlock: &AntreaIPBlock{CIDR: cidr10Slash8}}},
+			}},
+			Egress: dnsEgressRulesAntrea(&securityv1.NetworkPolicyGeneratorSpec{}, false),
+		},
+	}
+
+	copied := original.DeepCopyObject().(*AntreaNetworkPolicy)
+	copied.Spec.AppliedTo[0].PodSelector.MatchLabels[labelApp] = labelValueFrontend
+	copied.Spec.Ingress[0].From[0].IPBlock.CIDR = cidr192Slash24
+	copied.Spec.Egress[0].Ports[0].Port.IntVal = 5353
+	*copied.Spec.Ingress[0].Protocols[0].ICMP.ICMPType = 0
+
+	assert.Equal(t, labelValueWeb, original.Spec.AppliedTo[0].PodSelector.MatchLabels[labelApp])
+	assert.Equal(t, cidr10Slash8, original.Spec.Ingress[0].From[0].IPBlock.CIDR)
+	assert.Equal(t, int32(DNSPort), original.Spec.Egress[0].Ports[0].Port.IntVal)
+	assert.Equal(t, int32(8), *original.Spec.Ingress[0].Protocols[0].ICMP.ICMPType)
+
+	cluster := &AntreaClusterNetworkPolicy{Spec: original.Spec.DeepCopy()}
+	clusterCopy := cluster.DeepCopyObject().(*AntreaClusterNetworkPolicy)
+	clusterCopy.Spec.Tier = "baseline"
+	assert.Equal(t, AntreaDefaultTier, cluster.Spec.Tier)
+}
+
+func TestAntreaDeepCopyNil(t *testing.T) {
+	var p *AntreaNetworkPolicy
+	assert.Nil(t, p.DeepCopyObject())
+
+	var c *AntreaClusterNetworkPolicy
+	assert.Nil(t, c.DeepCopyObject())
+
+	var s *AntreaPolicySpec
+	assert.Nil(t, s.DeepCopy())
+
+	var r *AntreaRule
+	assert.Nil(t, r.DeepCopy())
+
+	var proto *AntreaProtocol
+	assert.Nil(t, proto.DeepCopy())
+
+	var icmp *AntreaICMPProtocol
+	assert.Nil(t, icmp.DeepCopy())
+}
@@ -0,0 +1,325 @@
+package policy
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// AntreaNetworkPolicy is a minimal representation of crd.antrea.io/v1beta1 NetworkPolicy
+// We define this locally to avoid importing the full Antrea dependency
+type AntreaNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec *AntreaPolicySpec `json:"spec,omitempty"`
+}
+
+// AntreaClusterNetworkPolicy is a minimal representation of crd.antrea.io/v1beta1 ClusterNetworkPolicy
+type AntreaClusterNetworkPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec *AntreaPolicySpec `json:"spec,omitempty"`
+}
+
+// AntreaPolicySpec is the specification shared by Antrea NetworkPolicy and ClusterNetworkPolicy
+type AntreaPolicySpec struct {
+	// Tier is the name of the tier this policy belongs to
+	// +optional
+	Tier string `json:"tier,omitempty"`
+
+	// Priority orders the policy within its tier. Lower priority is evaluated first.
+	Priority float64 `json:"priority"`
+
+	// AppliedTo selects the workloads this policy applies to
+	AppliedTo []AntreaAppliedTo `json:"appliedTo"`
+
+	// Ingress is an ordered list of ingress rules; the first match wins
+	// +optional
+	Ingress []AntreaRule `json:"ingress,omitempty"`
+
+	// Egress is an ordered list of egress rules; the first match wins
+	// +optional
+	Egress []AntreaRule `json:"egress,omitempty"`
+}
+
+// AntreaAppliedTo selects the workloads a policy is enforced on
+type AntreaAppliedTo struct {
+	// PodSelector selects pods
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// NamespaceSelector selects namespaces (ClusterNetworkPolicy only)
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// AntreaRule defines a single Antrea policy rule with an explicit action
+type AntreaRule struct {
+	// Action specifies the action to take: Allow, Drop, Reject, Pass
+	Action string `json:"action"`
+
+	// Ports is a list of destination ports; empty matches all ports
+	// +optional
+	Ports []AntreaPort `json:"ports,omitempty"`
+
+	// Protocols matches protocols without ports, such as ICMP; it cannot be
+	// combined with Ports
+	// +optional
+	Protocols []AntreaProtocol `json:"protocols,omitempty"`
+
+	// From is the list of ingress sources; empty matches all sources
+	// +optional
+	From []AntreaPeer `json:"from,omitempty"`
+
+	// To is the list of egress destinations; empty matches all destinations
+	// +optional
+	To []AntreaPeer `json:"to,omitempty"`
+}
+
+// AntreaPort describes a port or port range to match
+type AntreaPort struct {
+	// Protocol is the L4 protocol (TCP, UDP, SCTP)
+	// +optional
+	Protocol string `json:"protocol,omitempty"`
+
+	// Port is a numeric or named port
+	// +optional
+	Port *intstr.IntOrString `json:"port,omitempty"`
+
+	// EndPort is the last port of a range starting at Port
+	// +optional
+	EndPort *int32 `json:"endPort,omitempty"`
+}
+
+// AntreaProtocol matches traffic of a protocol without ports
+type AntreaProtocol struct {
+	// ICMP matches ICMP messages by type and code
+	// +optional
+	ICMP *AntreaICMPProtocol `json:"icmp,omitempty"`
+}
+
+// AntreaICMPProtocol matches an ICMP type and, optionally, a code; empty
+// matches every ICMP message
+type AntreaICMPProtocol struct {
+	// ICMPType is the ICMP type
+	// +optional
+	ICMPType *int32 `json:"icmpType,omitempty"`
+
+	// ICMPCode is the ICMP code; it requires ICMPType
+	// +optional
+	ICMPCode *int32 `json:"icmpCode,omitempty"`
+}
+
+// AntreaPeer describes a traffic peer
+type AntreaPeer struct {
+	// PodSelector selects pods
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+
+	// NamespaceSelector selects namespaces
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Namespaces restricts the peer to namespaces relative to the applied-to
+	// pods (ClusterNetworkPolicy only)
+	// +optional
+	Namespaces *AntreaPeerNamespaces `json:"namespaces,omitempty"`
+
+	// IPBlock matches an IP range
+	// +optional
+	IPBlock *AntreaIPBlock `json:"ipBlock,omitempty"`
+
+	// FQDN matches a DNS name, optionally with "*" wildcards (egress only)
+	// +optional
+	FQDN string `json:"fqdn,omitempty"`
+}
+
+// AntreaPeerNamespaces selects peer namespaces relative to the applied-to pods
+type AntreaPeerNamespaces struct {
+	// Match is "Self" to select the namespace of the applied-to pods
+	Match string `json:"match,omitempty"`
+}
+
+// AntreaIPBlock describes a CIDR. Antrea has no "except" field; exclusions
+// are expressed as separate higher-ordered rules instead.
+type AntreaIPBlock struct {
+	// CIDR is the IP range
+	CIDR string `json:"cidr"`
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *AntreaNetworkPolicy) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies all properties into another AntreaNetworkPolicy
+func (in *AntreaNetworkPolicy) DeepCopyInto(out *AntreaNetworkPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec != nil {
+		out.Spec = in.Spec.DeepCopy()
+	}
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *AntreaClusterNetworkPolicy) DeepCopyObject() runtime.Object {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaClusterNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies all properties into another AntreaClusterNetworkPolicy
+func (in *AntreaClusterNetworkPolicy) DeepCopyInto(out *AntreaClusterNetworkPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	if in.Spec != nil {
+		out.Spec = in.Spec.DeepCopy()
+	}
+}
+
+// DeepCopy creates a deep copy of AntreaPolicySpec
+func (in *AntreaPolicySpec) DeepCopy() *AntreaPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaPolicySpec)
+	out.Tier = in.Tier
+	out.Priority = in.Priority
+	if in.AppliedTo != nil {
+		out.AppliedTo = make([]AntreaAppliedTo, len(in.AppliedTo))
+		for i, a := range in.AppliedTo {
+			out.AppliedTo[i] = AntreaAppliedTo{
+				PodSelector:       a.PodSelector.DeepCopy(),
+				NamespaceSelector: a.NamespaceSelector.DeepCopy(),
+			}
+		}
+	}
+	if in.Ingress != nil {
+		out.Ingress = make([]AntreaRule, len(in.Ingress))
+		for i, r := range in.Ingress {
+			out.Ingress[i] = *r.DeepCopy()
+		}
+	}
+	if in.Egress != nil {
+		out.Egress = make([]AntreaRule, len(in.Egress))
+		for i, r := range in.Egress {
+			out.Egress[i] = *r.DeepCopy()
+		}
+	}
+	return out
+}
+
+// DeepCopy creates a deep copy of AntreaRule
+func (in *AntreaRule) DeepCopy() *AntreaRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaRule)
+	out.Action = in.Action
+	if in.Ports != nil {
+		out.Ports = make([]AntreaPort, len(in.Ports))
+		for i, p := range in.Ports {
+			out.Ports[i] = *p.DeepCopy()
+		}
+	}
+	if in.Protocols != nil {
+		out.Protocols = make([]AntreaProtocol, len(in.Protocols))
+		for i, p := range in.Protocols {
+			out.Protocols[i] = *p.DeepCopy()
+		}
+	}
+	out.From = deepCopyAntreaPeers(in.From)
+	out.To = deepCopyAntreaPeers(in.To)
+	return out
+}
+
+// DeepCopy creates a deep copy of AntreaPort
+func (in *AntreaPort) DeepCopy() *AntreaPort {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaPort)
+	out.Protocol = in.Protocol
+	if in.Port != nil {
+		port := *in.Port
+		out.Port = &port
+	}
+	if in.EndPort != nil {
+		endPort := *in.EndPort
+		out.EndPort = &endPort
+	}
+	return out
+}
+
+// DeepCopy creates a deep copy of AntreaProtocol
+func (in *AntreaProtocol) DeepCopy() *AntreaProtocol {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaProtocol)
+	if in.ICMP != nil {
+		out.ICMP = in.ICMP.DeepCopy()
+	}
+	return out
+}
+
+// DeepCopy creates a deep copy of AntreaICMPProtocol
+func (in *AntreaICMPProtocol) DeepCopy() *AntreaICMPProtocol {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaICMPProtocol)
+	if in.ICMPType != nil {
+		icmpType := *in.ICMPType
+		out.ICMPType = &icmpType
+	}
+	if in.ICMPCode != nil {
+		code := *in.ICMPCode
+		out.ICMPCode = &code
+	}
+	return out
+}
+
+// DeepCopy creates a deep copy of AntreaPeer
+func (in *AntreaPeer) DeepCopy() *AntreaPeer {
+	if in == nil {
+		return nil
+	}
+	out := new(AntreaPeer)
+	out.FQDN = in.FQDN
+	out.PodSelector = in.PodSelector.DeepCopy()
+	out.NamespaceSelector = in.NamespaceSelector.DeepCopy()
+	if in.Namespaces != nil {
+		namespaces := *in.Namespaces
+		out.Namespaces = &namespaces
+	}
+	if in.IPBlock != nil {
+		ipBlock := *in.IPBlock
+		out.IPBlock = &ipBlock
+	}
+	return out
+}
+
+// deepCopyAntreaPeers deep-copies a peer list, preserving nil
+func deepCopyAntreaPeers(in []AntreaPeer) []AntreaPeer {
+	if in == nil {
+		return nil
+	}
+	out := make([]AntreaPeer, len(in))
+	for i, p := range in {
+		out[i] = *p.DeepCopy()
+	}
+	return out
+}
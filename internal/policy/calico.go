@@ -2,6 +2,8 @@ package policy
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -27,10 +29,20 @@ func (e *CalicoEngine) EngineName() string {
 
 // GeneratePolicies generates CalicoNetworkPolicy objects
 func (e *CalicoEngine) GeneratePolicies(generator *securityv1.NetworkPolicyGenerator) ([]runtime.Object, error) {
+	if err := rejectL7Rules(EngineCalico, generator.Spec.GlobalRules); err != nil {
+		return nil, err
+	}
+
 	order := CalicoDefaultOrder
-	selector := "all()"
-	if len(generator.Spec.Policy.PodSelector) > 0 {
-		selector = buildCalicoSelector(generator.Spec.Policy.PodSelector)
+	selector := calicoLabelSelector(PodLabelSelector(generator))
+	ingress := EnforcesDirection(&generator.Spec, DirectionIngress)
+	egress := EnforcesDirection(&generator.Spec, DirectionEgress)
+	var types []string
+	if ingress {
+		types = append(types, CalicoTypeIngress)
+	}
+	if egress {
+		types = append(types, CalicoTypeEgress)
 	}
 
 	basePolicy := &CalicoNetworkPolicy{
@@ -53,7 +65,7 @@ func (e *CalicoEngine) GeneratePolicies(generator *securityv1.NetworkPolicyGener
 		Spec: &CalicoNetworkPolicySpec{
 			Order:    &order,
 			Selector: selector,
-			Types:    []string{"Ingress", "Egress"},
+			Types:    types,
 		},
 	}
 
@@ -67,11 +79,50 @@ func (e *CalicoEngine) GeneratePolicies(generator *securityv1.NetworkPolicyGener
 	// Add DNS egress rule to all policies
 	for _, obj := range policies {
 		calicoPolicy := obj.(*CalicoNetworkPolicy)
-		calicoPolicy.Spec.Egress = append(calicoPolicy.Spec.Egress, dnsEgressRuleCalico())
+		calicoPolicy.Spec.Egress = append(calicoPolicy.Spec.Egress, dnsEgressRulesCalico(&generator.Spec)...)
 	}
 
-	e.applyGlobalRules(policies, generator.Spec.GlobalRules)
+	e.applyGlobalRules(policies, generator.Spec.GlobalRules, generator.Spec.IPFamilies)
 	e.applyCIDRRules(policies, generator.Spec.CIDRRules)
+	e.applyFQDNRules(policies, generator.Spec.FQDNRules)
+
+	// Allow kubelet probes and other node-originated traffic
+	if cidrs := NodeCIDRs(generator); len(cidrs) > 0 {
+		for _, obj := range policies {
+			calicoPolicy := obj.(*CalicoNetworkPolicy)
+			calicoPolicy.Spec.Ingress = append(calicoPolicy.Spec.Ingress, CalicoRule{
+				Action: CalicoActionAllow,
+				Source: &CalicoEntityRule{Nets: slices.Clone(cidrs)},
+			})
+		}
+	}
+
+	// Allow egress to the Kubernetes API server
+	if cidrs, ports := APIServerEndpoints(generator); len(cidrs) > 0 {
+		for _, obj := range policies {
+			calicoPolicy := obj.(*CalicoNetworkPolicy)
+			destination := &CalicoEntityRule{Nets: slices.Clone(cidrs)}
+			for _, port := range ports {
+				destination.Ports = append(destination.Ports, int(port))
+			}
+			calicoPolicy.Spec.Egress = append(calicoPolicy.Spec.Egress, CalicoRule{
+				Action:      CalicoActionAllow,
+				Protocol:    ProtocolTCP,
+				Destination: destination,
+			})
+		}
+	}
+
+	// Drop the rules of directions spec.policyTypes does not enforce
+	for _, obj := range policies {
+		calicoPolicy := obj.(*CalicoNetworkPolicy)
+		if !ingress {
+			calicoPolicy.Spec.Ingress = nil
+		}
+		if !egress {
+			calicoPolicy.Spec.Egress = nil
+		}
+	}
 
 	return policies, nil
 }
@@ -81,16 +132,15 @@ func (e *CalicoEngine) generateDenyPolicies(basePolicy *CalicoNetworkPolicy, gen
 	policy := basePolicy.DeepCopyObject().(*CalicoNetworkPolicy)
 	policy.Namespace = generator.Namespace
 
-	if len(generator.Spec.Policy.AllowedNamespaces) > 0 {
-		nsSelector := buildCalicoNamespaceSelector(generator.Spec.Policy.AllowedNamespaces)
-		policy.Spec.Ingress = []CalicoRule{{
+	for _, nsSelector := range calicoNamespaceSelectors(AllowedNamespaces(generator), generator.Spec.Policy.AllowedNamespaceSelector) {
+		policy.Spec.Ingress = append(policy.Spec.Ingress, CalicoRule{
 			Action: CalicoActionAllow,
 			Source: &CalicoEntityRule{NamespaceSelector: nsSelector},
-		}}
-		policy.Spec.Egress = []CalicoRule{{
+		})
+		policy.Spec.Egress = append(policy.Spec.Egress, CalicoRule{
 			Action:      CalicoActionAllow,
 			Destination: &CalicoEntityRule{NamespaceSelector: nsSelector},
-		}}
+		})
 	}
 
 	return []runtime.Object{policy}
@@ -100,23 +150,21 @@ func (e *CalicoEngine) generateDenyPolicies(basePolicy *CalicoNetworkPolicy, gen
 func (e *CalicoEngine) generateAllowPolicies(basePolicy *CalicoNetworkPolicy, generator *securityv1.NetworkPolicyGenerator) []runtime.Object {
 	var policies []runtime.Object
 
-	if len(generator.Spec.Policy.DeniedNamespaces) == 0 {
-		return policies
-	}
-
-	for _, ns := range generator.Spec.Policy.DeniedNamespaces {
+	nsSelectors := calicoNamespaceSelectors(generator.Spec.Policy.DeniedNamespaces, generator.Spec.Policy.DeniedNamespaceSelector)
+	for _, ns := range TargetNamespaces(generator) {
 		policy := basePolicy.DeepCopyObject().(*CalicoNetworkPolicy)
 		policy.Namespace = ns
 
-		nsSelector := buildCalicoNamespaceSelector(generator.Spec.Policy.DeniedNamespaces)
-		policy.Spec.Ingress = []CalicoRule{{
-			Action: CalicoActionDeny,
-			Source: &CalicoEntityRule{NamespaceSelector: nsSelector},
-		}}
-		policy.Spec.Egress = []CalicoRule{{
-			Action:      CalicoActionDeny,
-			Destination: &CalicoEntityRule{NamespaceSelector: nsSelector},
-		}}
+		for _, nsSelector := range nsSelectors {
+			policy.Spec.Ingress = append(policy.Spec.Ingress, CalicoRule{
+				Action: CalicoActionDeny,
+				Source: &CalicoEntityRule{NamespaceSelector: nsSelector},
+			})
+			policy.Spec.Egress = append(policy.Spec.Egress, CalicoRule{
+				Action:      CalicoActionDeny,
+				Destination: &CalicoEntityRule{NamespaceSelector: nsSelector},
+			})
+		}
 
 		policies = append(policies, policy)
 	}
@@ -124,31 +172,141 @@ func (e *CalicoEngine) generateAllowPolicies(basePolicy *CalicoNetworkPolicy, ge
 	return policies
 }
 
-// applyGlobalRules adds global rules to all Calico policies
-func (e *CalicoEngine) applyGlobalRules(policies []runtime.Object, globalRules []securityv1.GlobalRule) {
+// calicoNamespaceSelectors returns the Calico namespace selectors for a list
+// of namespace names and an optional label selector, one expression each
+func calicoNamespaceSelectors(namespaces []string, selector *metav1.LabelSelector) []string {
+	var selectors []string
+	if len(namespaces) > 0 {
+		selectors = append(selectors, buildCalicoNamespaceSelector(namespaces))
+	}
+	if selector != nil {
+		selectors = append(selectors, calicoLabelSelector(selector))
+	}
+	return selectors
+}
+
+// applyGlobalRules adds global rules to all Calico policies. Rules without
+// from/to peers set no source or destination nets, so they match any address
+// of the IP families in ipFamilies: a single family sets the rule's
+// ipVersion, both leave it unset. Each peer becomes its own rule, since a
+// Calico entity ANDs its selectors and nets. Calico evaluates rules in order,
+// so deny rules are placed before every other rule of their direction and
+// allow rules after them.
+func (e *CalicoEngine) applyGlobalRules(policies []runtime.Object, globalRules []securityv1.GlobalRule, ipFamilies []string) {
 	if globalRules == nil {
 		return
 	}
 
 	for _, obj := range policies {
 		calicoPolicy := obj.(*CalicoNetworkPolicy)
+		var denyIngress, denyEgress []CalicoRule
 		for _, rule := range globalRules {
-			port := calicoGlobalRulePort(rule)
-			calicoRule := CalicoRule{
-				Action:   CalicoActionAllow,
-				Protocol: rule.Protocol,
-			}
-
+			var rules []CalicoRule
+			ipVersion := calicoIPVersion(rule, ipFamilies)
 			switch rule.Direction {
 			case DirectionIngress:
-				calicoRule.Destination = &CalicoEntityRule{Ports: []interface{}{port}}
-				calicoPolicy.Spec.Ingress = append(calicoPolicy.Spec.Ingress, calicoRule)
+				for _, peer := range calicoGlobalRulePeers(rule.From) {
+					calicoRule := newCalicoGlobalRule(rule)
+					calicoRule.Source = peer
+					if peer == nil {
+						calicoRule.IPVersion = ipVersion
+					}
+					rules = append(rules, calicoRule)
+				}
 			case DirectionEgress:
-				calicoRule.Destination = &CalicoEntityRule{Ports: []interface{}{port}}
-				calicoPolicy.Spec.Egress = append(calicoPolicy.Spec.Egress, calicoRule)
+				for _, peer := range calicoGlobalRulePeers(rule.To) {
+					calicoRule := newCalicoGlobalRule(rule)
+					if peer != nil {
+						if calicoRule.Destination != nil {
+							peer.Ports = calicoRule.Destination.Ports
+						}
+						calicoRule.Destination = peer
+					} else {
+						calicoRule.IPVersion = ipVersion
+					}
+					rules = append(rules, calicoRule)
+				}
+			}
+			deny := rule.Type == PolicyTypeDeny
+
+			switch {
+			case rule.Direction == DirectionIngress && deny:
+				denyIngress = append(denyIngress, rules...)
+			case rule.Direction == DirectionIngress:
+				calicoPolicy.Spec.Ingress = append(calicoPolicy.Spec.Ingress, rules...)
+			case rule.Direction == DirectionEgress && deny:
+				denyEgress = append(denyEgress, rules...)
+			case rule.Direction == DirectionEgress:
+				calicoPolicy.Spec.Egress = append(calicoPolicy.Spec.Egress, rules...)
 			}
 		}
+		if len(denyIngress) > 0 {
+			calicoPolicy.Spec.Ingress = append(denyIngress, calicoPolicy.Spec.Ingress...)
+		}
+		if len(denyEgress) > 0 {
+			calicoPolicy.Spec.Egress = append(denyEgress, calicoPolicy.Spec.Egress...)
+		}
+	}
+}
+
+// newCalicoGlobalRule converts a GlobalRule into a Calico rule matching its
+// action, protocol and port or ICMP type, but no peer
+func newCalicoGlobalRule(rule securityv1.GlobalRule) CalicoRule {
+	calicoRule := CalicoRule{
+		Action:   CalicoActionAllow,
+		Protocol: rule.Protocol,
+	}
+	if rule.Type == PolicyTypeDeny {
+		calicoRule.Action = CalicoActionDeny
 	}
+	if IsICMPProtocol(rule.Protocol) {
+		calicoRule.ICMP = calicoICMPMatch(rule.ICMP)
+		calicoRule.NotICMP = calicoICMPMatch(rule.NotICMP)
+	} else {
+		calicoRule.Destination = &CalicoEntityRule{Ports: []interface{}{calicoGlobalRulePort(rule)}}
+	}
+	return calicoRule
+}
+
+// calicoIPVersion returns the ipVersion of a global rule that matches any
+// address: 4 or 6 when ipFamilies selects a single family, nil when it
+// selects both or none. ICMP rules get none, their protocol already implies
+// the family.
+func calicoIPVersion(rule securityv1.GlobalRule, ipFamilies []string) *int {
+	if len(ipFamilies) != 1 || IsICMPProtocol(rule.Protocol) {
+		return nil
+	}
+	ipVersion := CalicoIPVersion4
+	if ipFamilies[0] == IPFamilyIPv6 {
+		ipVersion = CalicoIPVersion6
+	}
+	return &ipVersion
+}
+
+// calicoGlobalRulePeers converts the from/to peers of a global rule into
+// Calico entity rules. Without peers it returns a single nil entity, which
+// matches every endpoint and address.
+func calicoGlobalRulePeers(peers []securityv1.RulePeer) []*CalicoEntityRule {
+	if len(peers) == 0 {
+		return []*CalicoEntityRule{nil}
+	}
+	entities := make([]*CalicoEntityRule, len(peers))
+	for i, peer := range peers {
+		entity := &CalicoEntityRule{}
+		switch {
+		case peer.CIDR != "":
+			entity.Nets = []string{peer.CIDR}
+		case len(peer.Namespaces) > 0:
+			entity.NamespaceSelector = buildCalicoNamespaceSelector(peer.Namespaces)
+		case len(peer.NamespaceSelector) > 0:
+			entity.NamespaceSelector = buildCalicoSelector(peer.NamespaceSelector)
+		}
+		if len(peer.PodSelector) > 0 {
+			entity.Selector = buildCalicoSelector(peer.PodSelector)
+		}
+		entities[i] = entity
+	}
+	return entities
 }
 
 // applyCIDRRules adds CIDR-based rules to all Calico policies
@@ -181,11 +339,83 @@ func (e *CalicoEngine) applyCIDRRules(policies []runtime.Object, cidrRules []sec
 	}
 }
 
-// buildCalicoSelector converts a label map to a Calico selector expression
+// applyFQDNRules adds domain-based egress rules to all Calico policies
+func (e *CalicoEngine) applyFQDNRules(policies []runtime.Object, fqdnRules []securityv1.FQDNRule) {
+	for _, obj := range policies {
+		calicoPolicy := obj.(*CalicoNetworkPolicy)
+		for _, rule := range fqdnRules {
+			calicoPolicy.Spec.Egress = append(calicoPolicy.Spec.Egress, calicoFQDNRules(rule)...)
+		}
+	}
+}
+
+// calicoFQDNRules converts an FQDN rule into Calico egress rules. A Calico rule
+// matches a single protocol, so ports are grouped into one rule per protocol.
+func calicoFQDNRules(rule securityv1.FQDNRule) []CalicoRule {
+	domain := fqdnRuleName(rule)
+	if len(rule.Ports) == 0 {
+		return []CalicoRule{{
+			Action:      CalicoActionAllow,
+			Destination: &CalicoEntityRule{Domains: []string{domain}},
+		}}
+	}
+
+	var rules []CalicoRule
+	byProtocol := make(map[string]int)
+	for _, port := range rule.Ports {
+		protocol := fqdnPortProtocol(port)
+		idx, ok := byProtocol[protocol]
+		if !ok {
+			idx = len(rules)
+			byProtocol[protocol] = idx
+			rules = append(rules, CalicoRule{
+				Action:      CalicoActionAllow,
+				Protocol:    protocol,
+				Destination: &CalicoEntityRule{Domains: []string{domain}},
+			})
+		}
+		rules[idx].Destination.Ports = append(rules[idx].Destination.Ports, strconv.Itoa(int(port.Port)))
+	}
+	return rules
+}
+
+// buildCalicoSelector converts a label map to a Calico selector expression.
+// Keys are sorted so the selector is stable across reconciles.
 func buildCalicoSelector(labels map[string]string) string {
-	parts := make([]string, 0, len(labels))
-	for k, v := range labels {
-		parts = append(parts, fmt.Sprintf("%s == '%s'", k, v))
+	keys := slices.Sorted(maps.Keys(labels))
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s == '%s'", k, labels[k]))
+	}
+	return strings.Join(parts, " && ")
+}
+
+// calicoLabelSelector converts a Kubernetes label selector to a Calico
+// selector expression. An empty selector matches everything.
+func calicoLabelSelector(selector *metav1.LabelSelector) string {
+	parts := make([]string, 0, len(selector.MatchLabels)+len(selector.MatchExpressions))
+	if len(selector.MatchLabels) > 0 {
+		parts = append(parts, buildCalicoSelector(selector.MatchLabels))
+	}
+	for _, expr := range selector.MatchExpressions {
+		quoted := make([]string, len(expr.Values))
+		for i, v := range expr.Values {
+			quoted[i] = fmt.Sprintf("'%s'", v)
+		}
+		values := strings.Join(quoted, ", ")
+		switch expr.Operator {
+		case metav1.LabelSelectorOpIn:
+			parts = append(parts, fmt.Sprintf("%s in { %s }", expr.Key, values))
+		case metav1.LabelSelectorOpNotIn:
+			parts = append(parts, fmt.Sprintf("%s not in { %s }", expr.Key, values))
+		case metav1.LabelSelectorOpExists:
+			parts = append(parts, fmt.Sprintf("has(%s)", expr.Key))
+		case metav1.LabelSelectorOpDoesNotExist:
+			parts = append(parts, fmt.Sprintf("!has(%s)", expr.Key))
+		}
+	}
+	if len(parts) == 0 {
+		return "all()"
 	}
 	return strings.Join(parts, " && ")
 }
@@ -202,25 +432,52 @@ func buildCalicoNamespaceSelector(namespaces []string) string {
 	return fmt.Sprintf("projectcalico.org/name in { %s }", strings.Join(quoted, ", "))
 }
 
-// calicoGlobalRulePort returns the port value for a GlobalRule
+// calicoICMPMatch converts a GlobalRule icmp or notICMP match. A nil icmp
+// selects every ICMP message of the rule's protocol, a nil notICMP excludes
+// none.
+func calicoICMPMatch(match *securityv1.ICMPMatch) *CalicoICMPMatch {
+	if match == nil {
+		return nil
+	}
+	icmp := &CalicoICMPMatch{Type: ptr.To(match.Type)}
+	if match.Code != nil {
+		icmp.Code = ptr.To(*match.Code)
+	}
+	return icmp
+}
+
+// calicoGlobalRulePort returns the port value for a GlobalRule. Port ranges
+// use Calico's "start:end" form.
 func calicoGlobalRulePort(rule securityv1.GlobalRule) interface{} {
 	if rule.NamedPort != "" {
 		return rule.NamedPort
 	}
+	if rule.EndPort != 0 {
+		return fmt.Sprintf("%d:%d", rule.Port, rule.EndPort)
+	}
 	return strconv.Itoa(int(rule.Port))
 }
 
-// dnsEgressRuleCalico creates a Calico egress rule allowing DNS resolution
-func dnsEgressRuleCalico() CalicoRule {
-	return CalicoRule{
-		Action:   CalicoActionAllow,
-		Protocol: ProtocolUDP,
-		Destination: &CalicoEntityRule{
-			NamespaceSelector: "projectcalico.org/name == 'kube-system'",
-			Selector:          "k8s-app == 'kube-dns'",
-			Ports:             []interface{}{DNSPort},
-		},
+// dnsEgressRulesCalico creates the Calico egress rules allowing DNS
+// resolution to the destinations spec.dns selects. A Calico rule matches a
+// single protocol, so each destination gets one rule per protocol.
+func dnsEgressRulesCalico(spec *securityv1.NetworkPolicyGeneratorSpec) []CalicoRule {
+	peers := DNSPeers(spec)
+	if len(peers) == 0 {
+		return nil
+	}
+	var rules []CalicoRule
+	for _, protocol := range DNSProtocols(spec) {
+		for _, entity := range calicoGlobalRulePeers(peers) {
+			entity.Ports = []interface{}{DNSPort}
+			rules = append(rules, CalicoRule{
+				Action:      CalicoActionAllow,
+				Protocol:    protocol,
+				Destination: entity,
+			})
+		}
 	}
+	return rules
 }
 
 // Ensure CalicoEngine implements PolicyEngine (compile-time check)
@@ -1,12 +1,14 @@
 package policy
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 )
@@ -47,9 +49,12 @@ func TestCalicoEngine(t *testing.T) {
 		assert.Contains(t, policy.Spec.Types, "Egress")
 		// Deny all: no ingress rules, only DNS egress
 		assert.Empty(t, policy.Spec.Ingress)
-		require.Len(t, policy.Spec.Egress, 1) // DNS only
+		require.Len(t, policy.Spec.Egress, 2) // DNS over UDP and TCP only
 		assert.Equal(t, CalicoActionAllow, policy.Spec.Egress[0].Action)
 		assert.Equal(t, ProtocolUDP, policy.Spec.Egress[0].Protocol)
+		assert.Equal(t, ProtocolTCP, policy.Spec.Egress[1].Protocol)
+		assert.Equal(t, "projectcalico.org/name == 'kube-system'", policy.Spec.Egress[1].Destination.NamespaceSelector)
+		assert.Equal(t, "k8s-app == 'kube-dns'", policy.Spec.Egress[1].Destination.Selector)
 	})
 
 	t.Run("Generate Deny Type with Allowed Namespaces", func(t *testing.T) {
@@ -77,8 +82,8 @@ func TestCalicoEngine(t *testing.T) {
 		assert.Equal(t, CalicoActionAllow, policy.Spec.Ingress[0].Action)
 		assert.Contains(t, policy.Spec.Ingress[0].Source.NamespaceSelector, nsAllowed1)
 		assert.Contains(t, policy.Spec.Ingress[0].Source.NamespaceSelector, nsAllowed2)
-		// 1 namespace egress + 1 DNS egress
-		require.Len(t, policy.Spec.Egress, 2)
+		// 1 namespace egress + 2 DNS egress
+		require.Len(t, policy.Spec.Egress, 3)
 	})
 
 	t.Run("Generate Allow Type with Denied Namespaces", func(t *testing.T) {
@@ -109,6 +114,47 @@ func TestCalicoEngine(t *testing.T) {
 		}
 	})
 
+	t.Run("Generate Policy with Namespace Selectors", func(t *testing.T) {
+		selector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type:                    PolicyTypeAllow,
+					DeniedNamespaces:        []string{nsOne},
+					DeniedNamespaceSelector: selector,
+				},
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{MatchedNamespaces: []string{"dev-a"}},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 2)
+		policy := objects[1].(*CalicoNetworkPolicy)
+		assert.Equal(t, "dev-a", policy.Namespace)
+		require.Len(t, policy.Spec.Ingress, 2)
+		assert.Equal(t, "projectcalico.org/name == 'ns1'", policy.Spec.Ingress[0].Source.NamespaceSelector)
+		assert.Equal(t, CalicoActionDeny, policy.Spec.Ingress[1].Action)
+		assert.Equal(t, "env == 'dev'", policy.Spec.Ingress[1].Source.NamespaceSelector)
+		require.GreaterOrEqual(t, len(policy.Spec.Egress), 2)
+		assert.Equal(t, "env == 'dev'", policy.Spec.Egress[1].Destination.NamespaceSelector)
+
+		// A deny type with only a selector still allows the selected namespaces
+		spec.Spec.Policy = securityv1.PolicyConfig{Type: PolicyTypeDeny, AllowedNamespaceSelector: selector}
+		objects, err = engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy = objects[0].(*CalicoNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress, 1)
+		assert.Equal(t, CalicoActionAllow, policy.Spec.Ingress[0].Action)
+		assert.Equal(t, "env == 'dev'", policy.Spec.Ingress[0].Source.NamespaceSelector)
+	})
+
 	t.Run("Allow Type with No Denied Namespaces", func(t *testing.T) {
 		spec := &securityv1.NetworkPolicyGenerator{
 			ObjectMeta: metav1.ObjectMeta{
@@ -156,8 +202,8 @@ func TestCalicoEngine(t *testing.T) {
 		assert.Equal(t, CalicoActionAllow, policy.Spec.Ingress[0].Action)
 		assert.Equal(t, ProtocolTCP, policy.Spec.Ingress[0].Protocol)
 
-		// 1 DNS egress + 1 global egress
-		require.Len(t, policy.Spec.Egress, 2)
+		// 2 DNS egress + 1 global egress
+		require.Len(t, policy.Spec.Egress, 3)
 	})
 
 	t.Run("Generate Policy with CIDR Rules", func(t *testing.T) {
@@ -183,9 +229,9 @@ func TestCalicoEngine(t *testing.T) {
 		require.Len(t, objects, 1)
 
 		policy := objects[0].(*CalicoNetworkPolicy)
-		// 1 DNS egress + 1 CIDR egress
-		require.Len(t, policy.Spec.Egress, 2)
-		assert.Contains(t, policy.Spec.Egress[1].Destination.Nets, cidr10Slash8)
+		// 2 DNS egress + 1 CIDR egress
+		require.Len(t, policy.Spec.Egress, 3)
+		assert.Contains(t, policy.Spec.Egress[2].Destination.Nets, cidr10Slash8)
 
 		// 1 CIDR ingress
 		require.Len(t, policy.Spec.Ingress, 1)
@@ -193,6 +239,191 @@ func TestCalicoEngine(t *testing.T) {
 		assert.Contains(t, policy.Spec.Ingress[0].Source.NotNets, cidrHost192)
 	})
 
+	t.Run("Deny Type Allows Same Namespace", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type:               PolicyTypeDeny,
+					AllowedNamespaces:  []string{nsAllowed1},
+					AllowSameNamespace: ptr.To(true),
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CalicoNetworkPolicy)
+		assert.Equal(t, "projectcalico.org/name in { 'allowed-ns1', 'test-namespace' }", policy.Spec.Ingress[0].Source.NamespaceSelector)
+	})
+
+	t.Run("Generate Ingress-Only Policy", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				PolicyTypes:  []string{DirectionIngress},
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress},
+					{Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CalicoNetworkPolicy)
+		assert.Equal(t, []string{CalicoTypeIngress}, policy.Spec.Types)
+		assert.Len(t, policy.Spec.Ingress, 2)
+		assert.Nil(t, policy.Spec.Egress)
+	})
+
+	t.Run("Allow Node Traffic", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				AllowNodeTraffic: true,
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{
+				NodeCIDRs: []string{"10.244.1.0/24", cidrHost192},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CalicoNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress, 1)
+		assert.Equal(t, CalicoActionAllow, policy.Spec.Ingress[0].Action)
+		assert.Equal(t, []string{"10.244.1.0/24", cidrHost192}, policy.Spec.Ingress[0].Source.Nets)
+		assert.Nil(t, policy.Spec.Ingress[0].Destination)
+	})
+
+	t.Run("Allow API Server", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				AllowAPIServer: true,
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{
+				APIServerCIDRs: []string{cidrHost192},
+				APIServerPorts: []int32{6443},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CalicoNetworkPolicy)
+		// 2 DNS + API server
+		require.Len(t, policy.Spec.Egress, 3)
+		apiServer := policy.Spec.Egress[2]
+		assert.Equal(t, ProtocolTCP, apiServer.Protocol)
+		assert.Equal(t, &CalicoEntityRule{
+			Nets:  []string{cidrHost192},
+			Ports: []interface{}{6443},
+		}, apiServer.Destination)
+	})
+
+	t.Run("Generate Policy with Custom DNS", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				DNS: &securityv1.DNSConfig{
+					Mode: DNSModeCustom,
+					To: []securityv1.RulePeer{
+						{CIDR: cidrNodeLocal},
+						{Namespaces: []string{nsAllowed2}, PodSelector: map[string]string{labelApp: "dns"}},
+					},
+					Protocols: []string{ProtocolUDP},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*CalicoNetworkPolicy)
+		// namespace egress + one UDP rule per resolver
+		require.Len(t, policy.Spec.Egress, 3)
+		assert.Equal(t, ProtocolUDP, policy.Spec.Egress[1].Protocol)
+		assert.Equal(t, &CalicoEntityRule{
+			Nets:  []string{cidrNodeLocal},
+			Ports: []interface{}{DNSPort},
+		}, policy.Spec.Egress[1].Destination)
+		assert.Equal(t, "projectcalico.org/name == 'allowed-ns2'", policy.Spec.Egress[2].Destination.NamespaceSelector)
+		assert.Equal(t, "app == 'dns'", policy.Spec.Egress[2].Destination.Selector)
+
+		spec.Spec.DNS = &securityv1.DNSConfig{Mode: DNSModeNone}
+		objects, err = engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		assert.Len(t, objects[0].(*CalicoNetworkPolicy).Spec.Egress, 1)
+	})
+
+	t.Run("Generate Policy with Pod Label Selector", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+					PodLabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{labelTier: labelValueFrontend},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: labelApp, Operator: metav1.LabelSelectorOpIn, Values: []string{"api", "worker"}},
+							{Key: "batch", Operator: metav1.LabelSelectorOpDoesNotExist},
+						},
+					},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CalicoNetworkPolicy)
+		assert.Equal(t, "tier == 'frontend' && app in { 'api', 'worker' } && !has(batch)", policy.Spec.Selector)
+	})
+
 	t.Run("Generate Policy with Pod Selector", func(t *testing.T) {
 		spec := &securityv1.NetworkPolicyGenerator{
 			ObjectMeta: metav1.ObjectMeta{
@@ -245,6 +476,263 @@ func TestCalicoEngine(t *testing.T) {
 		require.Len(t, policy.Spec.Ingress, 1)
 		assert.Equal(t, namedPortHTTP, policy.Spec.Ingress[0].Destination.Ports[0])
 	})
+
+	t.Run("Generate Policy with ICMP Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolICMP, ICMP: &securityv1.ICMPMatch{Type: 8, Code: ptr.To(int32(0))}},
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolICMPv6, ICMP: &securityv1.ICMPMatch{Type: 128}},
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolICMP, NotICMP: &securityv1.ICMPMatch{Type: 5}},
+					{Type: PolicyTypeDeny, Direction: DirectionEgress, Protocol: ProtocolICMP, ICMP: &securityv1.ICMPMatch{Type: 3}, NotICMP: &securityv1.ICMPMatch{Type: 3, Code: ptr.To(int32(4))}},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		policy := objects[0].(*CalicoNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress, 3)
+		assert.Equal(t, ProtocolICMP, policy.Spec.Ingress[0].Protocol)
+		assert.Nil(t, policy.Spec.Ingress[0].Destination)
+		assert.Equal(t, &CalicoICMPMatch{Type: ptr.To(int32(8)), Code: ptr.To(int32(0))}, policy.Spec.Ingress[0].ICMP)
+		assert.Equal(t, ProtocolICMPv6, policy.Spec.Ingress[1].Protocol)
+		assert.Equal(t, &CalicoICMPMatch{Type: ptr.To(int32(128))}, policy.Spec.Ingress[1].ICMP)
+		assert.Nil(t, policy.Spec.Ingress[1].NotICMP)
+
+		assert.Nil(t, policy.Spec.Ingress[2].ICMP)
+		assert.Equal(t, &CalicoICMPMatch{Type: ptr.To(int32(5))}, policy.Spec.Ingress[2].NotICMP)
+
+		deny := policy.Spec.Egress[0]
+		assert.Equal(t, CalicoActionDeny, deny.Action)
+		assert.Equal(t, &CalicoICMPMatch{Type: ptr.To(int32(3))}, deny.ICMP)
+		assert.Equal(t, &CalicoICMPMatch{Type: ptr.To(int32(3)), Code: ptr.To(int32(4))}, deny.NotICMP)
+
+		data, err := json.Marshal(policy.Spec.Ingress[2])
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"notICMP":{"type":5}`)
+	})
+
+	t.Run("Generate Policy with Port Range", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 30000, EndPort: 32767},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		policy := objects[0].(*CalicoNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress, 1)
+		assert.Equal(t, []interface{}{"30000:32767"}, policy.Spec.Ingress[0].Destination.Ports)
+	})
+
+	t.Run("Generate Policy with FQDN Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				FQDNRules: []securityv1.FQDNRule{
+					{MatchName: fqdnAPI, Ports: []securityv1.FQDNPort{
+						{Port: 443, Protocol: ProtocolTCP},
+						{Port: 8443},
+						{Port: 443, Protocol: ProtocolUDP},
+					}},
+					{MatchPattern: fqdnWildcard},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*CalicoNetworkPolicy)
+		// 2 DNS + TCP rule + UDP rule + port-less wildcard rule
+		require.Len(t, policy.Spec.Egress, 5)
+
+		tcp := policy.Spec.Egress[2]
+		assert.Equal(t, ProtocolTCP, tcp.Protocol)
+		assert.Equal(t, []string{fqdnAPI}, tcp.Destination.Domains)
+		assert.Equal(t, []interface{}{"443", "8443"}, tcp.Destination.Ports)
+
+		udp := policy.Spec.Egress[3]
+		assert.Equal(t, ProtocolUDP, udp.Protocol)
+		assert.Equal(t, []interface{}{"443"}, udp.Destination.Ports)
+
+		wildcard := policy.Spec.Egress[4]
+		assert.Empty(t, wildcard.Protocol)
+		assert.Equal(t, []string{fqdnWildcard}, wildcard.Destination.Domains)
+		assert.Empty(t, wildcard.Destination.Ports)
+	})
+
+	t.Run("Deny Global Rules Are Ordered First", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 80},
+					{Type: PolicyTypeDeny, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 22},
+					{Type: PolicyTypeDeny, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 23},
+					{Type: PolicyTypeDeny, Direction: DirectionEgress, Protocol: ProtocolUDP, Port: 123},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		policy := objects[0].(*CalicoNetworkPolicy)
+
+		// deny 22 + deny 23 + allowed namespace + allow 80
+		require.Len(t, policy.Spec.Ingress, 4)
+		assert.Equal(t, CalicoActionDeny, policy.Spec.Ingress[0].Action)
+		assert.Equal(t, []interface{}{"22"}, policy.Spec.Ingress[0].Destination.Ports)
+		assert.Equal(t, CalicoActionDeny, policy.Spec.Ingress[1].Action)
+		assert.Equal(t, []interface{}{"23"}, policy.Spec.Ingress[1].Destination.Ports)
+		assert.Nil(t, policy.Spec.Ingress[0].Source)
+		assert.Equal(t, CalicoActionAllow, policy.Spec.Ingress[2].Action)
+		assert.Equal(t, CalicoActionAllow, policy.Spec.Ingress[3].Action)
+
+		// deny 123 comes before the namespace and DNS allows
+		require.Len(t, policy.Spec.Egress, 4)
+		assert.Equal(t, CalicoActionDeny, policy.Spec.Egress[0].Action)
+		assert.Equal(t, ProtocolUDP, policy.Spec.Egress[0].Protocol)
+	})
+
+	t.Run("Global Rules Follow a Single IP Family", func(t *testing.T) {
+		generator := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 80},
+					{Type: PolicyTypeDeny, Direction: DirectionEgress, Protocol: ProtocolUDP, Port: 123},
+					{
+						Type: PolicyTypeAllow, Direction: DirectionEgress, Protocol: ProtocolTCP, Port: 443,
+						To: []securityv1.RulePeer{{CIDR: cidr10Slash8}},
+					},
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolICMP, ICMP: &securityv1.ICMPMatch{Type: 8}},
+				},
+			},
+		}
+		ipVersions := func(ipFamilies ...string) (ingress, egress []*int) {
+			generator.Spec.IPFamilies = ipFamilies
+			objects, err := engine.GeneratePolicies(generator)
+			require.NoError(t, err)
+			policy := objects[0].(*CalicoNetworkPolicy)
+			for _, rule := range policy.Spec.Ingress {
+				ingress = append(ingress, rule.IPVersion)
+			}
+			// The DNS rules select the cluster DNS pods and carry no ipVersion
+			for _, rule := range policy.Spec.Egress {
+				egress = append(egress, rule.IPVersion)
+			}
+			return ingress, egress
+		}
+
+		// Port 80 and ICMP ingress; deny 123, DNS over UDP and TCP, the 443 CIDR egress
+		ingress, egress := ipVersions(IPFamilyIPv4)
+		assert.Equal(t, []*int{ptr.To(4), nil}, ingress)
+		assert.Equal(t, []*int{ptr.To(4), nil, nil, nil}, egress)
+
+		ingress, egress = ipVersions(IPFamilyIPv6)
+		assert.Equal(t, []*int{ptr.To(6), nil}, ingress)
+		assert.Equal(t, []*int{ptr.To(6), nil, nil, nil}, egress)
+
+		// Both families: rules without nets already match both
+		ingress, egress = ipVersions(IPFamilyIPv4, IPFamilyIPv6)
+		assert.Equal(t, []*int{nil, nil}, ingress)
+		assert.Equal(t, []*int{nil, nil, nil, nil}, egress)
+	})
+
+	t.Run("Global Rules with Peers", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCalico,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{
+						Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 5432,
+						From: []securityv1.RulePeer{
+							{Namespaces: []string{nsOne}, PodSelector: map[string]string{labelApp: labelValueWeb}},
+							{NamespaceSelector: map[string]string{labelTier: labelValueFrontend}},
+						},
+					},
+					{
+						Type: PolicyTypeAllow, Direction: DirectionEgress, Protocol: ProtocolTCP, Port: 443,
+						To: []securityv1.RulePeer{{CIDR: cidr10Slash8}},
+					},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		policy := objects[0].(*CalicoNetworkPolicy)
+
+		require.Len(t, policy.Spec.Ingress, 2)
+		assert.Equal(t, &CalicoEntityRule{
+			NamespaceSelector: "projectcalico.org/name == 'ns1'",
+			Selector:          "app == 'web'",
+		}, policy.Spec.Ingress[0].Source)
+		assert.Equal(t, []interface{}{"5432"}, policy.Spec.Ingress[0].Destination.Ports)
+		assert.Equal(t, "tier == 'frontend'", policy.Spec.Ingress[1].Source.NamespaceSelector)
+		assert.Equal(t, []interface{}{"5432"}, policy.Spec.Ingress[1].Destination.Ports)
+
+		// DNS + global egress; the CIDR and port share the destination
+		require.Len(t, policy.Spec.Egress, 3)
+		assert.Nil(t, policy.Spec.Egress[2].Source)
+		assert.Equal(t, &CalicoEntityRule{
+			Nets:  []string{cidr10Slash8},
+			Ports: []interface{}{"443"},
+		}, policy.Spec.Egress[2].Destination)
+	})
 }
 
 func TestCalicoNetworkPolicyDeepCopy(t *testing.T) {
@@ -265,6 +753,11 @@ func TestCalicoNetworkPolicyDeepCopy(t *testing.T) {
 			Ingress: []CalicoRule{{
 				Action: CalicoActionAllow,
 				Source: &CalicoEntityRule{Nets: []string{cidr10Slash8}},
+			}, {
+				Action:   CalicoActionAllow,
+				Protocol: ProtocolICMP,
+				ICMP:     &CalicoICMPMatch{Type: ptr.To(int32(8))},
+				NotICMP:  &CalicoICMPMatch{Type: ptr.To(int32(8)), Code: ptr.To(int32(1))},
 			}},
 			Egress: []CalicoRule{{
 				Action:      CalicoActionAllow,
@@ -283,6 +776,12 @@ func TestCalicoNetworkPolicyDeepCopy(t *testing.T) {
 
 	copied.Spec.Ingress[0].Action = CalicoActionDeny
 	assert.Equal(t, CalicoActionAllow, original.Spec.Ingress[0].Action)
+
+	*copied.Spec.Ingress[1].ICMP.Type = 0
+	assert.Equal(t, int32(8), *original.Spec.Ingress[1].ICMP.Type)
+
+	*copied.Spec.Ingress[1].NotICMP.Code = 0
+	assert.Equal(t, int32(1), *original.Spec.Ingress[1].NotICMP.Code)
 }
 
 func TestCalicoDeepCopyNil(t *testing.T) {
@@ -297,6 +796,9 @@ func TestCalicoDeepCopyNil(t *testing.T) {
 
 	var entity *CalicoEntityRule
 	assert.Nil(t, entity.DeepCopy())
+
+	var icmp *CalicoICMPMatch
+	assert.Nil(t, icmp.DeepCopy())
 }
 
 func TestBuildCalicoSelector(t *testing.T) {
@@ -304,6 +806,11 @@ func TestBuildCalicoSelector(t *testing.T) {
 		result := buildCalicoSelector(map[string]string{labelApp: labelValueWeb})
 		assert.Equal(t, "app == 'web'", result)
 	})
+
+	t.Run("multiple labels are sorted", func(t *testing.T) {
+		result := buildCalicoSelector(map[string]string{labelTier: labelValueFrontend, labelApp: labelValueWeb})
+		assert.Equal(t, "app == 'web' && tier == 'frontend'", result)
+	})
 }
 
 func TestBuildCalicoNamespaceSelector(t *testing.T) {
@@ -319,3 +826,24 @@ func TestBuildCalicoNamespaceSelector(t *testing.T) {
 		assert.Contains(t, result, "'ns2'")
 	})
 }
+
+func TestCalicoLabelSelector(t *testing.T) {
+	t.Run("empty selector matches everything", func(t *testing.T) {
+		assert.Equal(t, "all()", calicoLabelSelector(&metav1.LabelSelector{}))
+	})
+
+	t.Run("labels and expressions", func(t *testing.T) {
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{labelTier: labelValueFrontend, labelApp: labelValueWeb},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"dev", "qa"}},
+				{Key: "zone", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"a"}},
+				{Key: "team", Operator: metav1.LabelSelectorOpExists},
+				{Key: "legacy", Operator: metav1.LabelSelectorOpDoesNotExist},
+			},
+		}
+		assert.Equal(t,
+			"app == 'web' && tier == 'frontend' && env in { 'dev', 'qa' } && zone not in { 'a' } && has(team) && !has(legacy)",
+			calicoLabelSelector(selector))
+	})
+}
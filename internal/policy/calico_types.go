@@ -46,6 +46,18 @@ type CalicoRule struct {
 	// +optional
 	Protocol string `json:"protocol,omitempty"`
 
+	// IPVersion limits the rule to IPv4 (4) or IPv6 (6) traffic
+	// +optional
+	IPVersion *int `json:"ipVersion,omitempty"`
+
+	// ICMP matches ICMP messages by type and code when protocol is ICMP or ICMPv6
+	// +optional
+	ICMP *CalicoICMPMatch `json:"icmp,omitempty"`
+
+	// NotICMP excludes ICMP messages by type and code from the rule
+	// +optional
+	NotICMP *CalicoICMPMatch `json:"notICMP,omitempty"`
+
 	// Source is the source endpoint match criteria
 	// +optional
 	Source *CalicoEntityRule `json:"source,omitempty"`
@@ -55,6 +67,17 @@ type CalicoRule struct {
 	Destination *CalicoEntityRule `json:"destination,omitempty"`
 }
 
+// CalicoICMPMatch matches an ICMP type and, optionally, a code
+type CalicoICMPMatch struct {
+	// Type is the ICMP type
+	// +optional
+	Type *int32 `json:"type,omitempty"`
+
+	// Code is the ICMP code; it requires Type
+	// +optional
+	Code *int32 `json:"code,omitempty"`
+}
+
 // CalicoEntityRule defines match criteria for an endpoint
 type CalicoEntityRule struct {
 	// Selector is a label selector for endpoints
@@ -73,6 +96,10 @@ type CalicoEntityRule struct {
 	// +optional
 	NotNets []string `json:"notNets,omitempty"`
 
+	// Domains is a list of DNS names, optionally with "*" wildcards (egress destinations only)
+	// +optional
+	Domains []string `json:"domains,omitempty"`
+
 	// Ports is a list of ports or port ranges
 	// +optional
 	Ports []interface{} `json:"ports,omitempty"`
@@ -136,6 +163,16 @@ func (in *CalicoRule) DeepCopy() *CalicoRule {
 	out := new(CalicoRule)
 	out.Action = in.Action
 	out.Protocol = in.Protocol
+	if in.IPVersion != nil {
+		ipVersion := *in.IPVersion
+		out.IPVersion = &ipVersion
+	}
+	if in.ICMP != nil {
+		out.ICMP = in.ICMP.DeepCopy()
+	}
+	if in.NotICMP != nil {
+		out.NotICMP = in.NotICMP.DeepCopy()
+	}
 	if in.Source != nil {
 		out.Source = in.Source.DeepCopy()
 	}
@@ -145,6 +182,23 @@ func (in *CalicoRule) DeepCopy() *CalicoRule {
 	return out
 }
 
+// DeepCopy creates a deep copy of CalicoICMPMatch
+func (in *CalicoICMPMatch) DeepCopy() *CalicoICMPMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(CalicoICMPMatch)
+	if in.Type != nil {
+		icmpType := *in.Type
+		out.Type = &icmpType
+	}
+	if in.Code != nil {
+		code := *in.Code
+		out.Code = &code
+	}
+	return out
+}
+
 // DeepCopy creates a deep copy of CalicoEntityRule
 func (in *CalicoEntityRule) DeepCopy() *CalicoEntityRule {
 	if in == nil {
@@ -161,6 +215,10 @@ func (in *CalicoEntityRule) DeepCopy() *CalicoEntityRule {
 		out.NotNets = make([]string, len(in.NotNets))
 		copy(out.NotNets, in.NotNets)
 	}
+	if in.Domains != nil {
+		out.Domains = make([]string, len(in.Domains))
+		copy(out.Domains, in.Domains)
+	}
 	if in.Ports != nil {
 		out.Ports = make([]interface{}, len(in.Ports))
 		copy(out.Ports, in.Ports)
@@ -1,6 +1,8 @@
 package policy
 
 import (
+	"fmt"
+	"slices"
 	"strconv"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -25,11 +27,27 @@ func (e *CiliumEngine) EngineName() string {
 
 // GeneratePolicies generates CiliumNetworkPolicy objects
 func (e *CiliumEngine) GeneratePolicies(generator *securityv1.NetworkPolicyGenerator) ([]runtime.Object, error) {
+	if err := rejectNotICMPRules(EngineCilium, generator.Spec.GlobalRules); err != nil {
+		return nil, err
+	}
+	for i, rule := range generator.Spec.GlobalRules {
+		if rule.Type == PolicyTypeDeny && len(rule.HTTP) > 0 {
+			return nil, fmt.Errorf("global rule %d: http rules cannot be combined with type deny: Cilium deny rules only match L3/L4", i)
+		}
+		if IsICMPProtocol(rule.Protocol) && rule.ICMP == nil {
+			return nil, fmt.Errorf("global rule %d: %s rules require icmp", i, rule.Protocol)
+		}
+		if rule.ICMP != nil && rule.ICMP.Code != nil {
+			return nil, fmt.Errorf("global rule %d: icmp code is not supported by the cilium policy engine, which only matches ICMP types", i)
+		}
+	}
+
 	var policies []runtime.Object
 
-	endpointSelector := &CiliumEndpointSelector{}
-	if len(generator.Spec.Policy.PodSelector) > 0 {
-		endpointSelector.MatchLabels = generator.Spec.Policy.PodSelector
+	podSelector := PodLabelSelector(generator)
+	endpointSelector := &CiliumEndpointSelector{
+		MatchLabels:      podSelector.MatchLabels,
+		MatchExpressions: podSelector.MatchExpressions,
 	}
 
 	basePolicy := &CiliumNetworkPolicy{
@@ -62,6 +80,49 @@ func (e *CiliumEngine) GeneratePolicies(generator *securityv1.NetworkPolicyGener
 
 	e.applyGlobalRules(policies, generator.Spec.GlobalRules)
 	e.applyCIDRRules(policies, generator.Spec.CIDRRules)
+	e.applyFQDNRules(policies, generator.Spec.FQDNRules)
+
+	// Cilium identifies node traffic by entity, so no node CIDRs are needed
+	if generator.Spec.AllowNodeTraffic {
+		for _, obj := range policies {
+			ciliumPolicy := obj.(*CiliumNetworkPolicy)
+			ciliumPolicy.Spec.Ingress = append(ciliumPolicy.Spec.Ingress, CiliumIngressRule{
+				FromEntities: []string{EntityHost, EntityRemoteNode},
+			})
+		}
+	}
+
+	// The kube-apiserver entity follows the API server without its CIDRs;
+	// only the ports come from the discovered endpoints
+	if generator.Spec.AllowAPIServer {
+		_, ports := APIServerEndpoints(generator)
+		for _, obj := range policies {
+			ciliumPolicy := obj.(*CiliumNetworkPolicy)
+			rule := CiliumEgressRule{ToEntities: []string{EntityAPIServer}}
+			if len(ports) > 0 {
+				portRule := CiliumPortRule{}
+				for _, port := range ports {
+					portRule.Ports = append(portRule.Ports, CiliumPort{Port: strconv.Itoa(int(port)), Protocol: ProtocolTCP})
+				}
+				rule.ToPorts = []CiliumPortRule{portRule}
+			}
+			ciliumPolicy.Spec.Egress = append(ciliumPolicy.Spec.Egress, rule)
+		}
+	}
+
+	// Cilium only enforces a direction the policy has rules for, so dropping
+	// a direction's allow and deny rules leaves its traffic untouched
+	for _, obj := range policies {
+		ciliumPolicy := obj.(*CiliumNetworkPolicy)
+		if !EnforcesDirection(&generator.Spec, DirectionIngress) {
+			ciliumPolicy.Spec.Ingress = nil
+			ciliumPolicy.Spec.IngressDeny = nil
+		}
+		if !EnforcesDirection(&generator.Spec, DirectionEgress) {
+			ciliumPolicy.Spec.Egress = nil
+			ciliumPolicy.Spec.EgressDeny = nil
+		}
+	}
 
 	return policies, nil
 }
@@ -70,18 +131,17 @@ func (e *CiliumEngine) GeneratePolicies(generator *securityv1.NetworkPolicyGener
 func (e *CiliumEngine) generateAllowPolicies(basePolicy *CiliumNetworkPolicy, generator *securityv1.NetworkPolicyGenerator) []runtime.Object {
 	var policies []runtime.Object
 
-	if len(generator.Spec.Policy.DeniedNamespaces) == 0 {
-		return policies
-	}
-
-	for _, ns := range generator.Spec.Policy.DeniedNamespaces {
+	for _, ns := range TargetNamespaces(generator) {
 		policy := basePolicy.DeepCopyObject().(*CiliumNetworkPolicy)
 		policy.Namespace = ns
 
 		selectors := buildCiliumNamespaceSelectors(generator.Spec.Policy.DeniedNamespaces)
+		if sel := generator.Spec.Policy.DeniedNamespaceSelector; sel != nil {
+			selectors = append(selectors, ciliumNamespaceLabelSelector(sel))
+		}
 		policy.Spec.IngressDeny = []CiliumIngressRule{{FromEndpoints: selectors}}
 		policy.Spec.EgressDeny = []CiliumEgressRule{{ToEndpoints: selectors}}
-		policy.Spec.Egress = append(policy.Spec.Egress, dnsEgressRuleCilium())
+		policy.Spec.Egress = append(policy.Spec.Egress, dnsEgressRulesCilium(&generator.Spec)...)
 
 		policies = append(policies, policy)
 	}
@@ -94,13 +154,17 @@ func (e *CiliumEngine) generateDenyPolicies(basePolicy *CiliumNetworkPolicy, gen
 	policy := basePolicy.DeepCopyObject().(*CiliumNetworkPolicy)
 	policy.Namespace = generator.Namespace
 
-	if len(generator.Spec.Policy.AllowedNamespaces) > 0 {
-		selectors := buildCiliumNamespaceSelectors(generator.Spec.Policy.AllowedNamespaces)
+	allowed := AllowedNamespaces(generator)
+	if len(allowed) > 0 || generator.Spec.Policy.AllowedNamespaceSelector != nil {
+		selectors := buildCiliumNamespaceSelectors(allowed)
+		if sel := generator.Spec.Policy.AllowedNamespaceSelector; sel != nil {
+			selectors = append(selectors, ciliumNamespaceLabelSelector(sel))
+		}
 		policy.Spec.Ingress = []CiliumIngressRule{{FromEndpoints: selectors}}
 		policy.Spec.Egress = []CiliumEgressRule{{ToEndpoints: selectors}}
 	}
 
-	policy.Spec.Egress = append(policy.Spec.Egress, dnsEgressRuleCilium())
+	policy.Spec.Egress = append(policy.Spec.Egress, dnsEgressRulesCilium(&generator.Spec)...)
 
 	return []runtime.Object{policy}
 }
@@ -113,7 +177,13 @@ func ciliumGlobalRulePort(rule securityv1.GlobalRule) string {
 	return strconv.Itoa(int(rule.Port))
 }
 
-// applyGlobalRules adds global rules to all policies
+// applyGlobalRules adds global rules to all policies. Allow rules without
+// from/to peers use the "world" entity, which covers both IP families, so
+// spec.ipFamilies needs no handling here. Deny rules become ingressDeny /
+// egressDeny rules, which Cilium evaluates before any allow rule, against
+// every peer unless from/to is set. ICMP rules match on icmps instead of
+// toPorts. Cilium does not allow mixing endpoint and CIDR selectors in one
+// rule, so each peer gets its own rule.
 func (e *CiliumEngine) applyGlobalRules(policies []runtime.Object, globalRules []securityv1.GlobalRule) {
 	if globalRules == nil {
 		return
@@ -122,28 +192,168 @@ func (e *CiliumEngine) applyGlobalRules(policies []runtime.Object, globalRules [
 	for _, obj := range policies {
 		ciliumPolicy := obj.(*CiliumNetworkPolicy)
 		for _, rule := range globalRules {
-			portRule := CiliumPortRule{
-				Ports: []CiliumPort{{
-					Port:     ciliumGlobalRulePort(rule),
-					Protocol: rule.Protocol,
-				}},
+			var toPorts []CiliumPortRule
+			var icmps []CiliumICMPRule
+			if rule.ICMP != nil {
+				icmps = []CiliumICMPRule{ciliumICMPRule(rule)}
+			} else {
+				toPorts = []CiliumPortRule{{
+					Ports: []CiliumPort{{
+						Port:     ciliumGlobalRulePort(rule),
+						EndPort:  rule.EndPort,
+						Protocol: rule.Protocol,
+					}},
+					Rules: ciliumHTTPRules(rule.HTTP),
+				}}
 			}
+			entity := EntityWorld
+			if rule.Type == PolicyTypeDeny {
+				entity = EntityAll
+			}
+
 			switch rule.Direction {
 			case DirectionIngress:
-				ciliumPolicy.Spec.Ingress = append(ciliumPolicy.Spec.Ingress, CiliumIngressRule{
-					FromEntities: []string{EntityWorld},
-					ToPorts:      []CiliumPortRule{portRule},
-				})
+				var ingress []CiliumIngressRule
+				for _, peer := range ciliumGlobalRulePeers(rule.From, entity) {
+					ingress = append(ingress, CiliumIngressRule{
+						FromEndpoints: peer.endpoints,
+						FromEntities:  peer.entities,
+						FromCIDR:      peer.cidrs,
+						ToPorts:       toPorts,
+						ICMPs:         icmps,
+					})
+				}
+				if rule.Type == PolicyTypeDeny {
+					ciliumPolicy.Spec.IngressDeny = append(ciliumPolicy.Spec.IngressDeny, ingress...)
+				} else {
+					ciliumPolicy.Spec.Ingress = append(ciliumPolicy.Spec.Ingress, ingress...)
+				}
 			case DirectionEgress:
-				ciliumPolicy.Spec.Egress = append(ciliumPolicy.Spec.Egress, CiliumEgressRule{
-					ToEntities: []string{EntityWorld},
-					ToPorts:    []CiliumPortRule{portRule},
-				})
+				var egress []CiliumEgressRule
+				for _, peer := range ciliumGlobalRulePeers(rule.To, entity) {
+					egress = append(egress, CiliumEgressRule{
+						ToEndpoints: peer.endpoints,
+						ToEntities:  peer.entities,
+						ToCIDR:      peer.cidrs,
+						ToPorts:     toPorts,
+						ICMPs:       icmps,
+					})
+				}
+				if rule.Type == PolicyTypeDeny {
+					ciliumPolicy.Spec.EgressDeny = append(ciliumPolicy.Spec.EgressDeny, egress...)
+				} else {
+					ciliumPolicy.Spec.Egress = append(ciliumPolicy.Spec.Egress, egress...)
+				}
 			}
 		}
 	}
 }
 
+// ciliumPeer is the L3 part of a Cilium rule: exactly one of its fields is set
+type ciliumPeer struct {
+	endpoints []CiliumEndpointSelector
+	entities  []string
+	cidrs     []string
+}
+
+// ciliumGlobalRulePeers converts the from/to peers of a global rule into
+// Cilium L3 selectors. Without peers the rule matches the given entity.
+func ciliumGlobalRulePeers(peers []securityv1.RulePeer, entity string) []ciliumPeer {
+	if len(peers) == 0 {
+		return []ciliumPeer{{entities: []string{entity}}}
+	}
+	result := make([]ciliumPeer, len(peers))
+	for i, peer := range peers {
+		if peer.CIDR != "" {
+			result[i] = ciliumPeer{cidrs: []string{peer.CIDR}}
+		} else {
+			result[i] = ciliumPeer{endpoints: ciliumPeerSelectors(peer)}
+		}
+	}
+	return result
+}
+
+// ciliumPeerSelectors creates the endpoint selectors of a rule peer, one per
+// listed namespace. A selector without a namespace label matches endpoints
+// in the policy's own namespace.
+func ciliumPeerSelectors(peer securityv1.RulePeer) []CiliumEndpointSelector {
+	var selectors []CiliumEndpointSelector
+	switch {
+	case len(peer.Namespaces) > 0:
+		selectors = buildCiliumNamespaceSelectors(peer.Namespaces)
+	case len(peer.NamespaceSelector) > 0:
+		selectors = []CiliumEndpointSelector{
+			ciliumNamespaceLabelSelector(&metav1.LabelSelector{MatchLabels: peer.NamespaceSelector}),
+		}
+	default:
+		selectors = []CiliumEndpointSelector{{}}
+	}
+	if len(peer.PodSelector) == 0 {
+		return selectors
+	}
+	for i := range selectors {
+		if selectors[i].MatchLabels == nil {
+			selectors[i].MatchLabels = make(map[string]string, len(peer.PodSelector))
+		}
+		for k, v := range peer.PodSelector {
+			selectors[i].MatchLabels[k] = v
+		}
+	}
+	return selectors
+}
+
+// ciliumNamespaceLabelSelector converts a namespace label selector into an
+// endpoint selector on Cilium's namespace labels. The namespace label must
+// exist in the selector, otherwise Cilium limits it to the policy's own
+// namespace.
+func ciliumNamespaceLabelSelector(selector *metav1.LabelSelector) CiliumEndpointSelector {
+	result := CiliumEndpointSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{{
+			Key:      LabelCiliumPodNS,
+			Operator: metav1.LabelSelectorOpExists,
+		}},
+	}
+	if len(selector.MatchLabels) > 0 {
+		result.MatchLabels = make(map[string]string, len(selector.MatchLabels))
+		for k, v := range selector.MatchLabels {
+			result.MatchLabels[LabelCiliumNSLabelPrefix+k] = v
+		}
+	}
+	for _, expr := range selector.MatchExpressions {
+		result.MatchExpressions = append(result.MatchExpressions, metav1.LabelSelectorRequirement{
+			Key:      LabelCiliumNSLabelPrefix + expr.Key,
+			Operator: expr.Operator,
+			Values:   slices.Clone(expr.Values),
+		})
+	}
+	return result
+}
+
+// ciliumICMPRule converts an ICMP or ICMPv6 GlobalRule into a Cilium ICMP match
+func ciliumICMPRule(rule securityv1.GlobalRule) CiliumICMPRule {
+	family := CiliumICMPFamilyIPv4
+	if rule.Protocol == ProtocolICMPv6 {
+		family = CiliumICMPFamilyIPv6
+	}
+	return CiliumICMPRule{Fields: []CiliumICMPField{{Family: family, Type: rule.ICMP.Type}}}
+}
+
+// ciliumHTTPRules converts GlobalRule HTTP matches into Cilium L7 rules
+func ciliumHTTPRules(httpRules []securityv1.HTTPRule) *CiliumL7Rules {
+	if len(httpRules) == 0 {
+		return nil
+	}
+	l7 := &CiliumL7Rules{HTTP: make([]CiliumPortRuleHTTP, len(httpRules))}
+	for i, h := range httpRules {
+		l7.HTTP[i] = CiliumPortRuleHTTP{
+			Path:    h.Path,
+			Method:  h.Method,
+			Headers: append([]string(nil), h.Headers...),
+		}
+	}
+	return l7
+}
+
 // applyCIDRRules adds CIDR-based rules to all Cilium policies
 func (e *CiliumEngine) applyCIDRRules(policies []runtime.Object, cidrRules []securityv1.CIDRRule) {
 	if cidrRules == nil {
@@ -180,21 +390,62 @@ func buildCiliumNamespaceSelectors(namespaces []string) []CiliumEndpointSelector
 	return selectors
 }
 
-// dnsEgressRuleCilium creates a Cilium egress rule allowing DNS resolution
-func dnsEgressRuleCilium() CiliumEgressRule {
-	return CiliumEgressRule{
-		ToEndpoints: []CiliumEndpointSelector{{
-			MatchLabels: map[string]string{
-				LabelCiliumPodNS:  LabelCiliumKubeSystem,
-				LabelCiliumK8sApp: LabelCiliumKubeDNSApp,
-			},
-		}},
-		ToPorts: []CiliumPortRule{{
-			Ports: []CiliumPort{
-				{Port: DNSPortStr, Protocol: ProtocolUDP},
-				{Port: DNSPortStr, Protocol: ProtocolTCP},
-			},
-		}},
+// dnsEgressRulesCilium creates the Cilium egress rules allowing DNS
+// resolution to the destinations spec.dns selects, one per destination.
+// toFQDNs rules only match IPs that Cilium saw resolved through its DNS proxy,
+// so when the generator has FQDN rules each rule also carries an L7 DNS rule
+// that sends every lookup through the proxy.
+func dnsEgressRulesCilium(spec *securityv1.NetworkPolicyGeneratorSpec) []CiliumEgressRule {
+	peers := DNSPeers(spec)
+	if len(peers) == 0 {
+		return nil
+	}
+	protocols := DNSProtocols(spec)
+	ports := make([]CiliumPort, len(protocols))
+	for i, protocol := range protocols {
+		ports[i] = CiliumPort{Port: DNSPortStr, Protocol: protocol}
+	}
+
+	var rules []CiliumEgressRule
+	for _, peer := range ciliumGlobalRulePeers(peers, EntityAll) {
+		rule := CiliumEgressRule{
+			ToEndpoints: peer.endpoints,
+			ToCIDR:      peer.cidrs,
+			ToPorts:     []CiliumPortRule{{Ports: slices.Clone(ports)}},
+		}
+		if len(spec.FQDNRules) > 0 {
+			rule.ToPorts[0].Rules = &CiliumL7Rules{
+				DNS: []CiliumFQDNSelector{{MatchPattern: CiliumDNSMatchAll}},
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// applyFQDNRules adds toFQDNs egress rules to all Cilium policies
+func (e *CiliumEngine) applyFQDNRules(policies []runtime.Object, fqdnRules []securityv1.FQDNRule) {
+	for _, obj := range policies {
+		ciliumPolicy := obj.(*CiliumNetworkPolicy)
+		for _, rule := range fqdnRules {
+			egressRule := CiliumEgressRule{
+				ToFQDNs: []CiliumFQDNSelector{{
+					MatchName:    rule.MatchName,
+					MatchPattern: rule.MatchPattern,
+				}},
+			}
+			if len(rule.Ports) > 0 {
+				ports := make([]CiliumPort, len(rule.Ports))
+				for i, port := range rule.Ports {
+					ports[i] = CiliumPort{
+						Port:     strconv.Itoa(int(port.Port)),
+						Protocol: fqdnPortProtocol(port),
+					}
+				}
+				egressRule.ToPorts = []CiliumPortRule{{Ports: ports}}
+			}
+			ciliumPolicy.Spec.Egress = append(ciliumPolicy.Spec.Egress, egressRule)
+		}
 	}
 }
 
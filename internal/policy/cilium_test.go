@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 )
@@ -45,7 +46,7 @@ func TestCiliumEngine(t *testing.T) {
 		// Deny all: no ingress/egress rules except DNS
 		assert.Empty(t, policy.Spec.Ingress)
 		require.Len(t, policy.Spec.Egress, 1) // DNS only
-		assert.Equal(t, "kube-dns", policy.Spec.Egress[0].ToEndpoints[0].MatchLabels["k8s:k8s-app"])
+		assert.Equal(t, "kube-dns", policy.Spec.Egress[0].ToEndpoints[0].MatchLabels[LabelK8sApp])
 	})
 
 	t.Run("Generate Allow Type with Denied Namespaces", func(t *testing.T) {
@@ -105,6 +106,54 @@ func TestCiliumEngine(t *testing.T) {
 		assert.Len(t, policy.Spec.Egress[0].ToEndpoints, 2)
 	})
 
+	t.Run("Generate Policy with Namespace Selectors", func(t *testing.T) {
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{"env": "dev"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"prod"}},
+			},
+		}
+		expected := CiliumEndpointSelector{
+			MatchLabels: map[string]string{LabelCiliumNSLabelPrefix + "env": "dev"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: LabelCiliumPodNS, Operator: metav1.LabelSelectorOpExists},
+				{Key: LabelCiliumNSLabelPrefix + "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"prod"}},
+			},
+		}
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type:                    PolicyTypeAllow,
+					DeniedNamespaces:        []string{nsOne},
+					DeniedNamespaceSelector: selector,
+				},
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{MatchedNamespaces: []string{"dev-a"}},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 2)
+		policy := objects[1].(*CiliumNetworkPolicy)
+		assert.Equal(t, "dev-a", policy.Namespace)
+		require.Len(t, policy.Spec.IngressDeny[0].FromEndpoints, 2)
+		assert.Equal(t, expected, policy.Spec.IngressDeny[0].FromEndpoints[1])
+		assert.Equal(t, expected, policy.Spec.EgressDeny[0].ToEndpoints[1])
+
+		spec.Spec.Policy = securityv1.PolicyConfig{Type: PolicyTypeDeny, AllowedNamespaceSelector: selector}
+		objects, err = engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy = objects[0].(*CiliumNetworkPolicy)
+		assert.Equal(t, []CiliumEndpointSelector{expected}, policy.Spec.Ingress[0].FromEndpoints)
+		assert.Equal(t, []CiliumEndpointSelector{expected}, policy.Spec.Egress[0].ToEndpoints)
+	})
+
 	t.Run("Generate Policy with Global Rules", func(t *testing.T) {
 		spec := &securityv1.NetworkPolicyGenerator{
 			ObjectMeta: metav1.ObjectMeta{
@@ -165,6 +214,187 @@ func TestCiliumEngine(t *testing.T) {
 		assert.Empty(t, objects)
 	})
 
+	t.Run("Deny Type Allows Same Namespace", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type:               PolicyTypeDeny,
+					AllowedNamespaces:  []string{nsAllowed1},
+					AllowSameNamespace: ptr.To(true),
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CiliumNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress[0].FromEndpoints, 2)
+		assert.Equal(t, nsTest, policy.Spec.Ingress[0].FromEndpoints[1].MatchLabels[LabelCiliumPodNS])
+		assert.Equal(t, nsTest, policy.Spec.Egress[0].ToEndpoints[1].MatchLabels[LabelCiliumPodNS])
+	})
+
+	t.Run("Generate Egress-Only Policy", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				PolicyTypes:  []string{DirectionEgress},
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress},
+					{Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CiliumNetworkPolicy)
+		assert.Nil(t, policy.Spec.Ingress)
+		assert.Nil(t, policy.Spec.IngressDeny)
+		assert.NotEmpty(t, policy.Spec.Egress)
+	})
+
+	t.Run("Allow Node Traffic", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				AllowNodeTraffic: true,
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{
+				NodeCIDRs: []string{"10.244.1.0/24", cidrHost192},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CiliumNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress, 1)
+		assert.Equal(t, []string{EntityHost, EntityRemoteNode}, policy.Spec.Ingress[0].FromEntities)
+	})
+
+	t.Run("Allow API Server", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				AllowAPIServer: true,
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{
+				APIServerCIDRs: []string{cidrHost192},
+				APIServerPorts: []int32{6443},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CiliumNetworkPolicy)
+		// DNS + API server
+		require.Len(t, policy.Spec.Egress, 2)
+		apiServer := policy.Spec.Egress[1]
+		assert.Equal(t, []string{EntityAPIServer}, apiServer.ToEntities)
+		assert.Empty(t, apiServer.ToCIDR)
+		assert.Equal(t, []CiliumPort{{Port: "6443", Protocol: ProtocolTCP}}, apiServer.ToPorts[0].Ports)
+	})
+
+	t.Run("Generate Policy with Custom DNS", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				DNS: &securityv1.DNSConfig{
+					Mode: DNSModeCustom,
+					To: []securityv1.RulePeer{
+						{CIDR: cidrNodeLocal},
+						{Namespaces: []string{nsAllowed2}, PodSelector: map[string]string{labelApp: "dns"}},
+					},
+					Protocols: []string{ProtocolUDP},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*CiliumNetworkPolicy)
+		// namespace egress + one DNS rule per resolver
+		require.Len(t, policy.Spec.Egress, 3)
+		assert.Equal(t, []string{cidrNodeLocal}, policy.Spec.Egress[1].ToCIDR)
+		assert.Equal(t, []CiliumPort{{Port: DNSPortStr, Protocol: ProtocolUDP}}, policy.Spec.Egress[1].ToPorts[0].Ports)
+		assert.Equal(t, nsAllowed2, policy.Spec.Egress[2].ToEndpoints[0].MatchLabels[LabelCiliumPodNS])
+		assert.Equal(t, "dns", policy.Spec.Egress[2].ToEndpoints[0].MatchLabels[labelApp])
+
+		spec.Spec.DNS = &securityv1.DNSConfig{Mode: DNSModeNone}
+		objects, err = engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		assert.Len(t, objects[0].(*CiliumNetworkPolicy).Spec.Egress, 1)
+	})
+
+	t.Run("Generate Policy with Pod Label Selector", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+					PodLabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{labelTier: labelValueFrontend},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: labelApp, Operator: metav1.LabelSelectorOpIn, Values: []string{"api", "worker"}},
+							{Key: "batch", Operator: metav1.LabelSelectorOpDoesNotExist},
+						},
+					},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+		policy := objects[0].(*CiliumNetworkPolicy)
+		assert.Equal(t, spec.Spec.Policy.PodLabelSelector.MatchLabels, policy.Spec.EndpointSelector.MatchLabels)
+		assert.Equal(t, spec.Spec.Policy.PodLabelSelector.MatchExpressions, policy.Spec.EndpointSelector.MatchExpressions)
+	})
+
 	t.Run("Generate Policy with Pod Selector", func(t *testing.T) {
 		spec := &securityv1.NetworkPolicyGenerator{
 			ObjectMeta: metav1.ObjectMeta{
@@ -252,6 +482,80 @@ func TestCiliumEngine(t *testing.T) {
 		assert.Contains(t, policy.Spec.Ingress[0].FromCIDR, cidr192Slash24)
 	})
 
+	t.Run("Generate Policy with ICMP And SCTP Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolICMP, ICMP: &securityv1.ICMPMatch{Type: 8}},
+					{Type: PolicyTypeDeny, Direction: DirectionIngress, Protocol: ProtocolICMPv6, ICMP: &securityv1.ICMPMatch{Type: 128}},
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolSCTP, Port: 38412},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		policy := objects[0].(*CiliumNetworkPolicy)
+
+		require.Len(t, policy.Spec.Ingress, 2)
+		assert.Empty(t, policy.Spec.Ingress[0].ToPorts)
+		assert.Equal(t, []CiliumICMPRule{{Fields: []CiliumICMPField{{Family: CiliumICMPFamilyIPv4, Type: 8}}}}, policy.Spec.Ingress[0].ICMPs)
+		assert.Equal(t, CiliumPort{Port: "38412", Protocol: ProtocolSCTP}, policy.Spec.Ingress[1].ToPorts[0].Ports[0])
+
+		require.Len(t, policy.Spec.IngressDeny, 1)
+		assert.Equal(t, []CiliumICMPRule{{Fields: []CiliumICMPField{{Family: CiliumICMPFamilyIPv6, Type: 128}}}}, policy.Spec.IngressDeny[0].ICMPs)
+	})
+
+	t.Run("Rejects ICMP Code", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{Name: nameTestPolicy, Namespace: nsTest},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{Type: PolicyTypeDeny},
+				GlobalRules: []securityv1.GlobalRule{{
+					Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolICMP,
+					ICMP: &securityv1.ICMPMatch{Type: 3, Code: ptr.To(int32(4))},
+				}},
+			},
+		}
+
+		_, err := engine.GeneratePolicies(spec)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "icmp code is not supported by the cilium policy engine")
+	})
+
+	t.Run("Generate Policy with Port Range", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 30000, EndPort: 32767},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		policy := objects[0].(*CiliumNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress, 1)
+		assert.Equal(t, CiliumPort{Port: "30000", EndPort: 32767, Protocol: ProtocolTCP}, policy.Spec.Ingress[0].ToPorts[0].Ports[0])
+	})
+
 	t.Run("Generate Policy with Named Port", func(t *testing.T) {
 		spec := &securityv1.NetworkPolicyGenerator{
 			ObjectMeta: metav1.ObjectMeta{
@@ -290,6 +594,214 @@ func TestCiliumEngine(t *testing.T) {
 		require.Len(t, policy.Spec.Egress, 2)
 		assert.Equal(t, "grpc", policy.Spec.Egress[1].ToPorts[0].Ports[0].Port)
 	})
+
+	t.Run("Generate Policy with HTTP Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{
+						Direction: DirectionIngress,
+						Protocol:  ProtocolTCP,
+						Port:      80,
+						HTTP: []securityv1.HTTPRule{
+							{Method: "GET", Path: "/api/v1/.*"},
+							{Method: "POST", Path: "/login", Headers: []string{"X-Auth: token"}},
+						},
+					},
+					{
+						Direction: DirectionEgress,
+						Protocol:  ProtocolTCP,
+						Port:      443,
+					},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*CiliumNetworkPolicy)
+		require.Len(t, policy.Spec.Ingress, 1)
+		l7 := policy.Spec.Ingress[0].ToPorts[0].Rules
+		require.NotNil(t, l7)
+		require.Len(t, l7.HTTP, 2)
+		assert.Equal(t, CiliumPortRuleHTTP{Method: "GET", Path: "/api/v1/.*"}, l7.HTTP[0])
+		assert.Equal(t, []string{"X-Auth: token"}, l7.HTTP[1].Headers)
+
+		// Rules without http matches stay plain L4
+		require.Len(t, policy.Spec.Egress, 2)
+		assert.Nil(t, policy.Spec.Egress[1].ToPorts[0].Rules)
+	})
+
+	t.Run("Generate Policy with FQDN Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				FQDNRules: []securityv1.FQDNRule{
+					{MatchName: fqdnAPI, Ports: []securityv1.FQDNPort{{Port: 443}}},
+					{MatchPattern: fqdnWildcard},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, objects, 1)
+
+		policy := objects[0].(*CiliumNetworkPolicy)
+		// DNS (with proxy rule) + 2 FQDN egress
+		require.Len(t, policy.Spec.Egress, 3)
+		dns := policy.Spec.Egress[0].ToPorts[0].Rules
+		require.NotNil(t, dns)
+		assert.Equal(t, []CiliumFQDNSelector{{MatchPattern: CiliumDNSMatchAll}}, dns.DNS)
+
+		assert.Equal(t, []CiliumFQDNSelector{{MatchName: fqdnAPI}}, policy.Spec.Egress[1].ToFQDNs)
+		assert.Equal(t, []CiliumPort{{Port: "443", Protocol: ProtocolTCP}}, policy.Spec.Egress[1].ToPorts[0].Ports)
+		assert.Equal(t, []CiliumFQDNSelector{{MatchPattern: fqdnWildcard}}, policy.Spec.Egress[2].ToFQDNs)
+		assert.Empty(t, policy.Spec.Egress[2].ToPorts)
+	})
+
+	t.Run("DNS Rule Has No Proxy Rule Without FQDN Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+
+		policy := objects[0].(*CiliumNetworkPolicy)
+		assert.Nil(t, policy.Spec.Egress[0].ToPorts[0].Rules)
+	})
+
+	t.Run("Deny Global Rules Become Deny Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeDeny, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 22},
+					{Type: PolicyTypeDeny, Direction: DirectionEgress, Protocol: ProtocolUDP, Port: 123},
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 80},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		policy := objects[0].(*CiliumNetworkPolicy)
+
+		require.Len(t, policy.Spec.IngressDeny, 1)
+		assert.Equal(t, []string{EntityAll}, policy.Spec.IngressDeny[0].FromEntities)
+		assert.Equal(t, []CiliumPort{{Port: "22", Protocol: ProtocolTCP}}, policy.Spec.IngressDeny[0].ToPorts[0].Ports)
+
+		require.Len(t, policy.Spec.EgressDeny, 1)
+		assert.Equal(t, []string{EntityAll}, policy.Spec.EgressDeny[0].ToEntities)
+		assert.Equal(t, []CiliumPort{{Port: "123", Protocol: ProtocolUDP}}, policy.Spec.EgressDeny[0].ToPorts[0].Ports)
+
+		// Only the allow rule lands in the allow list
+		require.Len(t, policy.Spec.Ingress, 1)
+		assert.Equal(t, "80", policy.Spec.Ingress[0].ToPorts[0].Ports[0].Port)
+	})
+
+	t.Run("Global Rules with Peers", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyEngine: EngineCilium,
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{
+						Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 5432,
+						From: []securityv1.RulePeer{
+							{Namespaces: []string{nsOne, nsTwo}, PodSelector: map[string]string{labelApp: labelValueWeb}},
+							{CIDR: cidr10Slash8},
+						},
+					},
+					{
+						Type: PolicyTypeDeny, Direction: DirectionEgress, Protocol: ProtocolTCP, Port: 25,
+						To: []securityv1.RulePeer{{NamespaceSelector: map[string]string{labelTier: labelValueFrontend}}},
+					},
+				},
+			},
+		}
+
+		objects, err := engine.GeneratePolicies(spec)
+		require.NoError(t, err)
+		policy := objects[0].(*CiliumNetworkPolicy)
+
+		// One rule per peer, each carrying the port
+		require.Len(t, policy.Spec.Ingress, 2)
+		assert.Equal(t, []CiliumEndpointSelector{
+			{MatchLabels: map[string]string{LabelCiliumPodNS: nsOne, labelApp: labelValueWeb}},
+			{MatchLabels: map[string]string{LabelCiliumPodNS: nsTwo, labelApp: labelValueWeb}},
+		}, policy.Spec.Ingress[0].FromEndpoints)
+		assert.Empty(t, policy.Spec.Ingress[0].FromEntities)
+		assert.Equal(t, "5432", policy.Spec.Ingress[0].ToPorts[0].Ports[0].Port)
+		assert.Equal(t, []string{cidr10Slash8}, policy.Spec.Ingress[1].FromCIDR)
+		assert.Equal(t, "5432", policy.Spec.Ingress[1].ToPorts[0].Ports[0].Port)
+
+		require.Len(t, policy.Spec.EgressDeny, 1)
+		assert.Empty(t, policy.Spec.EgressDeny[0].ToEntities)
+		assert.Equal(t, []CiliumEndpointSelector{{
+			MatchLabels: map[string]string{LabelCiliumNSLabelPrefix + labelTier: labelValueFrontend},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: LabelCiliumPodNS, Operator: metav1.LabelSelectorOpExists},
+			},
+		}}, policy.Spec.EgressDeny[0].ToEndpoints)
+	})
+
+	t.Run("Rejects HTTP Rules On Deny Global Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{Name: nameTestPolicy, Namespace: nsTest},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{Type: PolicyTypeDeny},
+				GlobalRules: []securityv1.GlobalRule{{
+					Type: PolicyTypeDeny, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 80,
+					HTTP: []securityv1.HTTPRule{{Method: "POST"}},
+				}},
+			},
+		}
+
+		_, err := engine.GeneratePolicies(spec)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be combined with type deny")
+	})
 }
 
 func TestCiliumNetworkPolicyDeepCopy(t *testing.T) {
@@ -42,6 +42,10 @@ type CiliumEndpointSelector struct {
 	// MatchLabels is a map of {key,value} pairs
 	// +optional
 	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+
+	// MatchExpressions is a list of label selector requirements
+	// +optional
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
 }
 
 // CiliumIngressRule defines an ingress rule for Cilium
@@ -61,6 +65,10 @@ type CiliumIngressRule struct {
 	// ToPorts is a list of destination L4 ports with protocol
 	// +optional
 	ToPorts []CiliumPortRule `json:"toPorts,omitempty"`
+
+	// ICMPs is a list of ICMP matches
+	// +optional
+	ICMPs []CiliumICMPRule `json:"icmps,omitempty"`
 }
 
 // CiliumEgressRule defines an egress rule for Cilium
@@ -77,15 +85,81 @@ type CiliumEgressRule struct {
 	// +optional
 	ToCIDR []string `json:"toCIDR,omitempty"`
 
+	// ToFQDNs is a list of DNS names allowed as egress destinations
+	// +optional
+	ToFQDNs []CiliumFQDNSelector `json:"toFQDNs,omitempty"`
+
 	// ToPorts is a list of destination L4 ports with protocol
 	// +optional
 	ToPorts []CiliumPortRule `json:"toPorts,omitempty"`
+
+	// ICMPs is a list of ICMP matches
+	// +optional
+	ICMPs []CiliumICMPRule `json:"icmps,omitempty"`
+}
+
+// CiliumICMPRule matches ICMP messages; it matches when any field matches
+type CiliumICMPRule struct {
+	// Fields is a list of ICMP type matches
+	Fields []CiliumICMPField `json:"fields,omitempty"`
+}
+
+// CiliumICMPField matches one ICMP type of an IP family
+type CiliumICMPField struct {
+	// Family is the IP family, IPv4 or IPv6
+	// +optional
+	Family string `json:"family,omitempty"`
+
+	// Type is the ICMP type
+	Type int32 `json:"type"`
 }
 
 // CiliumPortRule defines L4 port/protocol rules
 type CiliumPortRule struct {
 	// Ports is a list of L4 port rules
 	Ports []CiliumPort `json:"ports,omitempty"`
+
+	// Rules is a list of L7 rules enforced on the ports
+	// +optional
+	Rules *CiliumL7Rules `json:"rules,omitempty"`
+}
+
+// CiliumL7Rules is a union of L7 rule types; only HTTP and DNS are modelled
+type CiliumL7Rules struct {
+	// HTTP is a list of HTTP request matches
+	// +optional
+	HTTP []CiliumPortRuleHTTP `json:"http,omitempty"`
+
+	// DNS is a list of DNS query matches enforced by the Cilium DNS proxy
+	// +optional
+	DNS []CiliumFQDNSelector `json:"dns,omitempty"`
+}
+
+// CiliumFQDNSelector matches a DNS name exactly or by wildcard pattern.
+// The same shape is used for toFQDNs peers and L7 DNS rules.
+type CiliumFQDNSelector struct {
+	// MatchName is an exact DNS name
+	// +optional
+	MatchName string `json:"matchName,omitempty"`
+
+	// MatchPattern is a DNS name with "*" wildcards
+	// +optional
+	MatchPattern string `json:"matchPattern,omitempty"`
+}
+
+// CiliumPortRuleHTTP matches an HTTP request; fields are extended POSIX regexes
+type CiliumPortRuleHTTP struct {
+	// Path is matched against the request path
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Method is matched against the request method
+	// +optional
+	Method string `json:"method,omitempty"`
+
+	// Headers is a list of headers that must be present in the request
+	// +optional
+	Headers []string `json:"headers,omitempty"`
 }
 
 // CiliumPort represents a single L4 port/protocol pair
@@ -93,6 +167,9 @@ type CiliumPort struct {
 	// Port is the L4 port number
 	Port string `json:"port,omitempty"`
 
+	// EndPort is the last port of a range starting at Port
+	EndPort int32 `json:"endPort,omitempty"`
+
 	// Protocol is the L4 protocol (TCP, UDP, ANY)
 	Protocol string `json:"protocol,omitempty"`
 }
@@ -157,5 +234,11 @@ func (in *CiliumEndpointSelector) DeepCopy() *CiliumEndpointSelector {
 			out.MatchLabels[k] = v
 		}
 	}
+	if in.MatchExpressions != nil {
+		out.MatchExpressions = make([]metav1.LabelSelectorRequirement, len(in.MatchExpressions))
+		for i := range in.MatchExpressions {
+			in.MatchExpressions[i].DeepCopyInto(&out.MatchExpressions[i])
+		}
+	}
 	return out
 }
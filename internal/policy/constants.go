@@ -11,6 +11,23 @@ const (
 	PhaseLearning  = "Learning"
 	PhaseEnforcing = "Enforcing"
 
+	// Condition types reported in status.conditions
+	ConditionReady           = "Ready"
+	ConditionLearning        = "Learning"
+	ConditionPoliciesApplied = "PoliciesApplied"
+	ConditionDegraded        = "Degraded"
+	ConditionDryRun          = "DryRun"
+
+	// Condition reasons
+	ReasonLearning           = "Learning"
+	ReasonLearningCompleted  = "LearningCompleted"
+	ReasonEnforcing          = "Enforcing"
+	ReasonDryRun             = "DryRun"
+	ReasonPoliciesApplied    = "PoliciesApplied"
+	ReasonReconcileSucceeded = "ReconcileSucceeded"
+	ReasonReconcileFailed    = "ReconcileFailed"
+	ReasonRolledBack         = "RolledBack"
+
 	// Policy type constants
 	PolicyTypeAllow = "allow"
 	PolicyTypeDeny  = "deny"
@@ -19,35 +36,78 @@ const (
 	DirectionIngress = "ingress"
 	DirectionEgress  = "egress"
 
+	// DNS modes accepted in spec.dns.mode
+	DNSModeAuto   = "auto"
+	DNSModeNone   = "none"
+	DNSModeCustom = "custom"
+
+	// Drift policies accepted in spec.driftPolicy
+	DriftPolicyEnforce = "enforce"
+	DriftPolicyReport  = "report"
+
+	// DefaultRevisionHistoryLimit is the number of earlier policy revisions
+	// kept when spec.revisionHistoryLimit is unset
+	DefaultRevisionHistoryLimit = 10
+
 	// Protocols accepted in rule definitions
-	ProtocolTCP  = "TCP"
-	ProtocolUDP  = "UDP"
-	ProtocolSCTP = "SCTP"
+	ProtocolTCP    = "TCP"
+	ProtocolUDP    = "UDP"
+	ProtocolSCTP   = "SCTP"
+	ProtocolICMP   = "ICMP"
+	ProtocolICMPv6 = "ICMPv6"
+
+	// IP families accepted in spec.ipFamilies
+	IPFamilyIPv4 = "IPv4"
+	IPFamilyIPv6 = "IPv6"
 
 	// Label keys
-	LabelK8sNamespace     = "kubernetes.io/metadata.name"
-	LabelCiliumPodNS      = "k8s:io.kubernetes.pod.namespace"
-	LabelCiliumK8sApp     = "k8s:k8s-app"
-	LabelCiliumKubeDNSApp = "kube-dns"
-	LabelCiliumKubeSystem = "kube-system"
+	LabelK8sNamespace = "kubernetes.io/metadata.name"
+	LabelCiliumPodNS  = "k8s:io.kubernetes.pod.namespace"
+	LabelCiliumK8sApp = "k8s:k8s-app"
+	LabelK8sApp       = "k8s-app"
+
+	// Cluster DNS pods the DNS egress rule targets in spec.dns mode "auto",
+	// for every engine
+	NamespaceKubeSystem = "kube-system"
+	LabelValueKubeDNS   = "kube-dns"
+
+	// LabelCiliumNSLabelPrefix prefixes namespace labels in Cilium endpoint selectors
+	LabelCiliumNSLabelPrefix = "k8s:io.cilium.k8s.namespace.labels."
 
 	// Network constants
-	CIDRAllTraffic = "0.0.0.0/0"
-	DNSPort        = 53
-	DNSPortStr     = "53"
+	CIDRAllTraffic     = "0.0.0.0/0"
+	CIDRAllTrafficIPv6 = "::/0"
+	DNSPort            = 53
+	DNSPortStr         = "53"
 
 	// Policy engine types
 	EngineKubernetes = "kubernetes"
 	EngineCilium     = "cilium"
 	EngineCalico     = "calico"
+	EngineAntrea     = "antrea"
+
+	// EngineAuto resolves to an installed engine at reconcile time
+	EngineAuto = "auto"
 
 	// Cilium-specific
 	EntityWorld      = "world"
+	EntityAll        = "all"
+	EntityHost       = "host"
+	EntityRemoteNode = "remote-node"
+	EntityAPIServer  = "kube-apiserver"
 	CiliumAPIVersion = "cilium.io/v2"
 	CiliumKind       = "CiliumNetworkPolicy"
 	CiliumGroup      = "cilium.io"
 	CiliumVersion    = "v2"
 
+	// Cilium ICMP families
+	CiliumICMPFamilyIPv4 = "IPv4"
+	CiliumICMPFamilyIPv6 = "IPv6"
+
+	// CiliumDNSMatchAll is the L7 DNS pattern that sends every lookup through
+	// the Cilium DNS proxy, which toFQDNs rules depend on
+	CiliumDNSMatchAll = "*"
+
 	// Calico-specific
 	CalicoAPIVersion   = "crd.projectcalico.org/v1"
 	CalicoKind         = "NetworkPolicy"
@@ -56,10 +116,47 @@ const (
 	CalicoActionAllow  = "Allow"
 	CalicoActionDeny   = "Deny"
 	CalicoDefaultOrder = float64(100)
+	CalicoIPVersion4   = 4
+	CalicoIPVersion6   = 6
+	CalicoTypeIngress  = "Ingress"
+	CalicoTypeEgress   = "Egress"
+
+	// Antrea-specific
+	AntreaAPIVersion        = "crd.antrea.io/v1beta1"
+	AntreaKind              = "NetworkPolicy"
+	AntreaClusterKind       = "ClusterNetworkPolicy"
+	AntreaGroup             = "crd.antrea.io"
+	AntreaVersion           = "v1beta1"
+	AntreaActionAllow       = "Allow"
+	AntreaActionDrop        = "Drop"
+	AntreaActionReject      = "Reject"
+	AntreaDefaultTier       = "application"
+	AntreaDefaultPriority   = float64(5)
+	AntreaDefaultRuleAction = AntreaActionDrop
+
+	// AntreaNamespaceMatchSelf limits a ClusterNetworkPolicy peer to the
+	// namespace of the pods the rule applies to
+	AntreaNamespaceMatchSelf = "Self"
+
+	// Engine plugin protocol: the request written to a plugin's stdin and
+	// the response read from its stdout
+	PluginAPIVersion     = "plugin.security.policy.io/v1"
+	PluginRequestKind    = "GenerateRequest"
+	PluginResponseKind   = "GenerateResponse"
+	DefaultPluginTimeout = 10 * time.Second
 
 	// Policy naming
 	PolicyNameSuffix = "-generated"
 
+	// Owner labels the controller sets on every applied policy object
+	LabelGeneratorName      = "security.policy.io/generator-name"
+	LabelGeneratorNamespace = "security.policy.io/generator-namespace"
+	LabelEngine             = "security.policy.io/engine"
+
+	// AnnotationOwner holds the owning generator as "<namespace>/<name>",
+	// unabridged, on every applied policy object
+	AnnotationOwner = "security.policy.io/owner"
+
 	// Requeue intervals
 	DefaultRequeueInterval = 5 * time.Minute
 
@@ -73,3 +170,10 @@ const (
 func PolicyName(generatorName string) string {
 	return generatorName + PolicyNameSuffix
 }
+
+// ClusterPolicyName returns the generated name for a cluster-scoped policy.
+// The generator namespace is prefixed so generators with the same name in
+// different namespaces do not collide.
+func ClusterPolicyName(generatorNamespace, generatorName string) string {
+	return generatorNamespace + "-" + PolicyName(generatorName)
+}
@@ -0,0 +1,301 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxDiffChanges bounds the number of change lines recorded per policy
+	MaxDiffChanges = 10
+
+	// MaxDiffPatchLength bounds the length of the JSON patch recorded per
+	// policy; longer patches are omitted
+	MaxDiffPatchLength = 4096
+
+	// maxDiffValueLength bounds the length of a value quoted in a change line
+	maxDiffValueLength = 120
+)
+
+// PolicyDiff is the semantic difference between a live policy object and the
+// object the generator applied over it
+type PolicyDiff struct {
+	// Changes lists the changed rules, peers, ports and other fields, at most
+	// MaxDiffChanges lines
+	Changes []string
+
+	// Patch is the RFC 6902 JSON patch from the live object to the applied
+	// one, empty when there is no change or it exceeds MaxDiffPatchLength
+	Patch string
+}
+
+// Empty reports whether the objects do not differ
+func (d PolicyDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// DiffPolicies compares two policy objects given as unstructured content.
+// Only what the generator renders is compared: everything but metadata and
+// status, plus the labels and annotations. A nil live object yields the rules
+// of a newly created policy, without a patch.
+//
+// The change lines and the patch compare lists differently. The lines treat
+// rules, peers and ports as multisets, so a rule inserted before others reads
+// as one added rule. The patch compares lists by index, so applying it to the
+// live object yields the applied object exactly, order included, at the cost
+// of more operations for such an insertion.
+func DiffPolicies(live, applied map[string]interface{}) (PolicyDiff, error) {
+	from, to := diffView(live), diffView(applied)
+	if live == nil {
+		delete(to, "metadata")
+	}
+
+	var changes []string
+	diffValues("", from, to, &changes)
+	if len(changes) == 0 {
+		return PolicyDiff{}, nil
+	}
+	if live == nil {
+		return PolicyDiff{Changes: boundChanges(changes, MaxDiffChanges)}, nil
+	}
+
+	data, err := json.Marshal(jsonPatch("", from, to, nil))
+	if err != nil {
+		return PolicyDiff{}, fmt.Errorf("failed to marshal policy patch: %w", err)
+	}
+	if len(data) > MaxDiffPatchLength {
+		changes = boundChanges(changes, MaxDiffChanges-1)
+		return PolicyDiff{
+			Changes: append(changes, fmt.Sprintf("JSON patch of %d bytes omitted", len(data))),
+		}, nil
+	}
+	return PolicyDiff{Changes: boundChanges(changes, MaxDiffChanges), Patch: string(data)}, nil
+}
+
+// diffView returns the part of an object the generator renders. metadata is
+// always present, even empty, so a patch adds labels or annotations to a live
+// object without them instead of replacing its metadata as a whole.
+func diffView(obj map[string]interface{}) map[string]interface{} {
+	view := map[string]interface{}{}
+	for key, value := range obj {
+		switch key {
+		case "apiVersion", "kind", "metadata", "status":
+		default:
+			view[key] = value
+		}
+	}
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	rendered := map[string]interface{}{}
+	for _, key := range []string{"labels", "annotations"} {
+		if value, ok := metadata[key]; ok {
+			rendered[key] = value
+		}
+	}
+	view["metadata"] = rendered
+	return view
+}
+
+// boundChanges keeps at most limit lines, replacing the overflow with a count
+func boundChanges(changes []string, limit int) []string {
+	if len(changes) <= limit {
+		return changes
+	}
+	return append(slices.Clone(changes[:limit-1]), fmt.Sprintf("and %d more changes", len(changes)-limit+1))
+}
+
+// diffValues appends a line for every difference between from and to. Maps
+// are compared key by key, and a map or list that was added or removed as a
+// whole is compared against an empty one, so every rule is listed. Lists are
+// compared as multisets, so reordered rules are not reported; unmatched map
+// elements are paired in order and compared field by field, the rest are
+// reported as added or removed.
+func diffValues(path string, from, to interface{}, changes *[]string) {
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+	if from == nil {
+		from = emptyLike(to)
+	}
+	if to == nil {
+		to = emptyLike(from)
+	}
+
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		keys := slices.Collect(maps.Keys(fromMap))
+		for key := range toMap {
+			if _, ok := fromMap[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			diffValues(joinPath(path, key), fromMap[key], toMap[key], changes)
+		}
+		return
+	}
+
+	fromList, fromIsList := from.([]interface{})
+	toList, toIsList := to.([]interface{})
+	if fromIsList && toIsList {
+		diffLists(path, fromList, toList, changes)
+		return
+	}
+
+	switch {
+	case from == nil:
+		*changes = append(*changes, fmt.Sprintf("%s: added %s", displayPath(path), describeValue(to)))
+	case to == nil:
+		*changes = append(*changes, fmt.Sprintf("%s: removed %s", displayPath(path), describeValue(from)))
+	default:
+		*changes = append(*changes, fmt.Sprintf("%s: %s -> %s", displayPath(path), describeValue(from), describeValue(to)))
+	}
+}
+
+// emptyLike returns an empty map or list of the kind of value, or nil for
+// scalars
+func emptyLike(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}:
+		return map[string]interface{}{}
+	case []interface{}:
+		return []interface{}{}
+	default:
+		return nil
+	}
+}
+
+// diffLists appends the differences between two lists, see diffValues
+func diffLists(path string, from, to []interface{}, changes *[]string) {
+	matched := make([]bool, len(from))
+	var added []int
+	for i, value := range to {
+		j := -1
+		for k := range from {
+			if !matched[k] && reflect.DeepEqual(from[k], value) {
+				j = k
+				break
+			}
+		}
+		if j < 0 {
+			added = append(added, i)
+			continue
+		}
+		matched[j] = true
+	}
+	var removed []int
+	for j := range from {
+		if !matched[j] {
+			removed = append(removed, j)
+		}
+	}
+
+	for len(added) > 0 && len(removed) > 0 {
+		_, fromIsMap := from[removed[0]].(map[string]interface{})
+		_, toIsMap := to[added[0]].(map[string]interface{})
+		if !fromIsMap || !toIsMap {
+			break
+		}
+		diffValues(fmt.Sprintf("%s[%d]", path, added[0]), from[removed[0]], to[added[0]], changes)
+		added, removed = added[1:], removed[1:]
+	}
+	for _, j := range removed {
+		*changes = append(*changes, fmt.Sprintf("%s: removed %s", displayPath(path), describeValue(from[j])))
+	}
+	for _, i := range added {
+		*changes = append(*changes, fmt.Sprintf("%s: added %s", displayPath(path), describeValue(to[i])))
+	}
+}
+
+// joinPath appends a field to a dotted path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// displayPath names the root of the compared view
+func displayPath(path string) string {
+	if path == "" {
+		return "object"
+	}
+	return path
+}
+
+// describeValue renders a value as compact JSON, truncated to
+// maxDiffValueLength
+func describeValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	if len(data) > maxDiffValueLength {
+		return string(data[:maxDiffValueLength-3]) + "..."
+	}
+	return string(data)
+}
+
+// patchOperation is a single RFC 6902 operation
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// jsonPatch appends the operations that turn from into to. Map keys are
+// visited in sorted order so the same change always yields the same patch.
+// List elements are compared by index: the tail is removed from the end or
+// added in order, then the shared prefix is diffed in place.
+func jsonPatch(path string, from, to interface{}, ops []patchOperation) []patchOperation {
+	if reflect.DeepEqual(from, to) {
+		return ops
+	}
+	fromMap, fromIsMap := from.(map[string]interface{})
+	toMap, toIsMap := to.(map[string]interface{})
+	if fromIsMap && toIsMap {
+		for _, key := range slices.Sorted(maps.Keys(fromMap)) {
+			if _, ok := toMap[key]; !ok {
+				ops = append(ops, patchOperation{Op: "remove", Path: path + "/" + escapePointer(key)})
+			}
+		}
+		for _, key := range slices.Sorted(maps.Keys(toMap)) {
+			child := path + "/" + escapePointer(key)
+			if value, ok := fromMap[key]; ok {
+				ops = jsonPatch(child, value, toMap[key], ops)
+			} else {
+				ops = append(ops, patchOperation{Op: "add", Path: child, Value: toMap[key]})
+			}
+		}
+		return ops
+	}
+
+	fromList, fromIsList := from.([]interface{})
+	toList, toIsList := to.([]interface{})
+	if fromIsList && toIsList {
+		n := min(len(fromList), len(toList))
+		for i := len(fromList) - 1; i >= n; i-- {
+			ops = append(ops, patchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+		}
+		for i := n; i < len(toList); i++ {
+			ops = append(ops, patchOperation{Op: "add", Path: path + "/" + strconv.Itoa(i), Value: toList[i]})
+		}
+		for i := range n {
+			ops = jsonPatch(path+"/"+strconv.Itoa(i), fromList[i], toList[i], ops)
+		}
+		return ops
+	}
+
+	return append(ops, patchOperation{Op: "replace", Path: path, Value: to})
+}
+
+// escapePointer escapes a key for use in a JSON pointer (RFC 6901)
+func escapePointer(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
@@ -0,0 +1,236 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPolicyObject builds unstructured NetworkPolicy content with the given
+// ingress rules
+func testPolicyObject(ingress ...interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "NetworkPolicy",
+		"metadata": map[string]interface{}{
+			"name":            "web-generated",
+			"resourceVersion": "1",
+			"labels":          map[string]interface{}{LabelEngine: EngineKubernetes},
+		},
+		"spec": map[string]interface{}{
+			"podSelector": map[string]interface{}{},
+			"policyTypes": []interface{}{"Ingress"},
+			"ingress":     ingress,
+		},
+	}
+}
+
+func testIngressRule(app string, port int64) map[string]interface{} {
+	return map[string]interface{}{
+		"from": []interface{}{
+			map[string]interface{}{"podSelector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": app},
+			}},
+		},
+		"ports": []interface{}{
+			map[string]interface{}{"port": port, "protocol": "TCP"},
+		},
+	}
+}
+
+// applyTestPatch applies a JSON patch to an object and returns the result
+func applyTestPatch(t *testing.T, obj map[string]interface{}, patch string) map[string]interface{} {
+	t.Helper()
+	decoded, err := jsonpatch.DecodePatch([]byte(patch))
+	require.NoError(t, err)
+	patched, err := decoded.Apply([]byte(mustMarshal(t, obj)))
+	require.NoError(t, err)
+	var result map[string]interface{}
+	require.NoError(t, json.Unmarshal(patched, &result))
+	return result
+}
+
+// normalizeJSON round-trips a value through JSON, so numbers compare as float64
+func normalizeJSON(t *testing.T, value interface{}) interface{} {
+	t.Helper()
+	var result interface{}
+	require.NoError(t, json.Unmarshal([]byte(mustMarshal(t, value)), &result))
+	return result
+}
+
+func mustMarshal(t *testing.T, value interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(value)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestDiffPolicies(t *testing.T) {
+	t.Run("Identical Objects", func(t *testing.T) {
+		live := testPolicyObject(testIngressRule("web", 80))
+		applied := testPolicyObject(testIngressRule("web", 80))
+		applied["metadata"].(map[string]interface{})["resourceVersion"] = "2"
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		assert.True(t, diff.Empty())
+		assert.Empty(t, diff.Patch)
+	})
+
+	t.Run("Reordered Rules", func(t *testing.T) {
+		live := testPolicyObject(testIngressRule("web", 80), testIngressRule("api", 8080))
+		applied := testPolicyObject(testIngressRule("api", 8080), testIngressRule("web", 80))
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		assert.Equal(t, []string(nil), diff.Changes)
+	})
+
+	t.Run("Rule Added And Removed", func(t *testing.T) {
+		live := testPolicyObject(testIngressRule("web", 80))
+		applied := testPolicyObject(testIngressRule("web", 80), testIngressRule("api", 8080))
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		require.Len(t, diff.Changes, 1)
+		assert.Equal(t,
+			`spec.ingress: added {"from":[{"podSelector":{"matchLabels":{"app":"api"}}}],"ports":[{"port":8080,"protocol":"TCP"}]}`,
+			diff.Changes[0])
+
+		diff, err = DiffPolicies(applied, live)
+		require.NoError(t, err)
+		require.Len(t, diff.Changes, 1)
+		assert.Contains(t, diff.Changes[0], "spec.ingress: removed ")
+	})
+
+	t.Run("Changed Peer And Port", func(t *testing.T) {
+		live := testPolicyObject(testIngressRule("web", 80))
+		applied := testPolicyObject(testIngressRule("api", 443))
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`spec.ingress[0].from[0].podSelector.matchLabels.app: "web" -> "api"`,
+			`spec.ingress[0].ports[0].port: 80 -> 443`,
+		}, diff.Changes)
+
+		var ops []map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(diff.Patch), &ops))
+		assert.Equal(t, []map[string]interface{}{
+			{"op": "replace", "path": "/spec/ingress/0/from/0/podSelector/matchLabels/app", "value": "api"},
+			{"op": "replace", "path": "/spec/ingress/0/ports/0/port", "value": float64(443)},
+		}, ops)
+	})
+
+	t.Run("Labels Are Compared", func(t *testing.T) {
+		live := testPolicyObject(testIngressRule("web", 80))
+		applied := testPolicyObject(testIngressRule("web", 80))
+		applied["metadata"].(map[string]interface{})["labels"].(map[string]interface{})[LabelGeneratorName] = "web"
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`metadata.labels.security.policy.io/generator-name: added "web"`,
+		}, diff.Changes)
+		assert.JSONEq(t,
+			`[{"op":"add","path":"/metadata/labels/security.policy.io~1generator-name","value":"web"}]`,
+			diff.Patch)
+	})
+
+	t.Run("Patch Keeps Live Metadata", func(t *testing.T) {
+		live := testPolicyObject(testIngressRule("web", 80))
+		delete(live["metadata"].(map[string]interface{}), "labels")
+		applied := testPolicyObject(testIngressRule("web", 80))
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		assert.JSONEq(t,
+			`[{"op":"add","path":"/metadata/labels","value":{"security.policy.io/engine":"kubernetes"}}]`,
+			diff.Patch)
+
+		patched := applyTestPatch(t, live, diff.Patch)
+		assert.Equal(t, "web-generated", patched["metadata"].(map[string]interface{})["name"])
+		assert.Equal(t, "1", patched["metadata"].(map[string]interface{})["resourceVersion"])
+		assert.Equal(t, map[string]interface{}{LabelEngine: EngineKubernetes}, patched["metadata"].(map[string]interface{})["labels"])
+	})
+
+	t.Run("Inserted Rule Is One Change But An Indexed Patch", func(t *testing.T) {
+		live := testPolicyObject(testIngressRule("web", 80), testIngressRule("db", 5432))
+		applied := testPolicyObject(testIngressRule("api", 8080), testIngressRule("web", 80), testIngressRule("db", 5432))
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`spec.ingress: added {"from":[{"podSelector":{"matchLabels":{"app":"api"}}}],"ports":[{"port":8080,"protocol":"TCP"}]}`,
+		}, diff.Changes)
+
+		var ops []map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(diff.Patch), &ops))
+		assert.Greater(t, len(ops), 1)
+
+		// The patch reproduces the applied rules in their order
+		patched := applyTestPatch(t, live, diff.Patch)
+		assert.Equal(t, normalizeJSON(t, applied["spec"]), patched["spec"])
+	})
+
+	t.Run("Created Policy Lists Its Rules", func(t *testing.T) {
+		applied := testPolicyObject(testIngressRule("web", 80))
+
+		diff, err := DiffPolicies(nil, applied)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			`spec.ingress: added {"from":[{"podSelector":{"matchLabels":{"app":"web"}}}],"ports":[{"port":80,"protocol":"TCP"}]}`,
+			`spec.policyTypes: added "Ingress"`,
+		}, diff.Changes)
+		assert.Empty(t, diff.Patch)
+	})
+
+	t.Run("Changes Are Bounded", func(t *testing.T) {
+		var rules []interface{}
+		for i := range MaxDiffChanges + 5 {
+			rules = append(rules, testIngressRule(fmt.Sprintf("app-%d", i), 80))
+		}
+		live := testPolicyObject()
+		applied := testPolicyObject(rules...)
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		require.Len(t, diff.Changes, MaxDiffChanges)
+		assert.Equal(t, "and 6 more changes", diff.Changes[MaxDiffChanges-1])
+		assert.NotEmpty(t, diff.Patch)
+	})
+
+	t.Run("Long Patch Is Omitted", func(t *testing.T) {
+		var rules []interface{}
+		for i := range 100 {
+			rules = append(rules, testIngressRule(fmt.Sprintf("app-%d", i), 80))
+		}
+		live := testPolicyObject()
+		applied := testPolicyObject(rules...)
+
+		diff, err := DiffPolicies(live, applied)
+		require.NoError(t, err)
+		assert.Empty(t, diff.Patch)
+		require.Len(t, diff.Changes, MaxDiffChanges)
+		assert.Equal(t, "and 92 more changes", diff.Changes[MaxDiffChanges-2])
+		assert.Regexp(t, `^JSON patch of \d+ bytes omitted$`, diff.Changes[MaxDiffChanges-1])
+	})
+
+	t.Run("Long Values Are Truncated", func(t *testing.T) {
+		rule := testIngressRule("web", 80)
+		rule["from"] = append(rule["from"].([]interface{}), map[string]interface{}{
+			"ipBlock": map[string]interface{}{"cidr": "10.0.0.0/8", "except": []interface{}{
+				"10.1.0.0/16", "10.2.0.0/16", "10.3.0.0/16", "10.4.0.0/16", "10.5.0.0/16", "10.6.0.0/16",
+			}},
+		})
+		diff, err := DiffPolicies(testPolicyObject(), testPolicyObject(rule))
+		require.NoError(t, err)
+		require.Len(t, diff.Changes, 1)
+		assert.Len(t, diff.Changes[0], len("spec.ingress: added ")+maxDiffValueLength)
+		assert.Contains(t, diff.Changes[0], "...")
+	})
+}
@@ -2,21 +2,28 @@ package policy
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
 )
 
-// PolicyEngine is the interface that both Kubernetes and Cilium policy generators implement
+// PolicyEngine is the interface that every CNI-specific policy generator implements
 type PolicyEngine interface {
 	// GeneratePolicies generates CNI-specific network policies as runtime.Object slices
 	GeneratePolicies(generator *securityv1.NetworkPolicyGenerator) ([]runtime.Object, error)
 
-	// EngineName returns the name of the policy engine (e.g., "kubernetes", "cilium")
+	// EngineName returns the name of the policy engine (e.g., "kubernetes", "cilium", "calico", "antrea")
 	EngineName() string
 }
 
-// NewPolicyEngine creates the appropriate PolicyEngine based on the engine type
+// NewPolicyEngine creates the built-in PolicyEngine for the engine type.
+// External engines are looked up in a PluginRegistry instead.
 func NewPolicyEngine(engineType string) (PolicyEngine, error) {
 	switch engineType {
 	case EngineKubernetes, "":
@@ -25,7 +32,240 @@ func NewPolicyEngine(engineType string) (PolicyEngine, error) {
 		return NewCiliumEngine(), nil
 	case EngineCalico:
 		return NewCalicoEngine(), nil
+	case EngineAntrea:
+		return NewAntreaEngine(), nil
 	default:
 		return nil, fmt.Errorf("unsupported policy engine: %s", engineType)
 	}
 }
+
+// PolicyKind is a resource kind a policy engine emits. The controller uses
+// the kinds of an engine to pick the GroupVersionKind of objects without
+// explicit TypeMeta and to find the objects to delete on cleanup.
+type PolicyKind struct {
+	schema.GroupVersionKind
+
+	// ClusterScoped marks kinds whose objects have no namespace. They are
+	// named ClusterPolicyName; namespaced kinds are named PolicyName in every
+	// target namespace.
+	ClusterScoped bool
+}
+
+// builtinEngineKinds lists the kinds of each built-in engine, its default kind first
+var builtinEngineKinds = map[string][]PolicyKind{
+	EngineKubernetes: {
+		{GroupVersionKind: networkingv1.SchemeGroupVersion.WithKind("NetworkPolicy")},
+	},
+	EngineCilium: {
+		{GroupVersionKind: schema.GroupVersionKind{Group: CiliumGroup, Version: CiliumVersion, Kind: CiliumKind}},
+	},
+	EngineCalico: {
+		{GroupVersionKind: schema.GroupVersionKind{Group: CalicoGroup, Version: CalicoVersion, Kind: CalicoKind}},
+	},
+	EngineAntrea: {
+		{GroupVersionKind: schema.GroupVersionKind{Group: AntreaGroup, Version: AntreaVersion, Kind: AntreaKind}},
+		{GroupVersionKind: schema.GroupVersionKind{Group: AntreaGroup, Version: AntreaVersion, Kind: AntreaClusterKind}, ClusterScoped: true},
+	},
+}
+
+// EngineKinds returns the kinds a built-in engine emits, its default kind
+// first, or nil for an unknown engine. Plugin kinds come from PluginRegistry.
+func EngineKinds(engineType string) []PolicyKind {
+	if engineType == "" {
+		engineType = EngineKubernetes
+	}
+	return builtinEngineKinds[engineType]
+}
+
+// TargetNamespaces returns the namespaces a generator's namespaced policies
+// live in: for allow-type policies the denied namespaces followed by the
+// namespaces deniedNamespaceSelector matched (status.matchedNamespaces),
+// otherwise the generator's own namespace
+func TargetNamespaces(generator *securityv1.NetworkPolicyGenerator) []string {
+	if generator.Spec.Policy.Type != PolicyTypeAllow {
+		return []string{generator.Namespace}
+	}
+	if len(generator.Status.MatchedNamespaces) == 0 {
+		return generator.Spec.Policy.DeniedNamespaces
+	}
+	namespaces := slices.Clone(generator.Spec.Policy.DeniedNamespaces)
+	for _, ns := range generator.Status.MatchedNamespaces {
+		if !slices.Contains(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// EnforcesDirection reports whether a generator's policies enforce a
+// direction (DirectionIngress or DirectionEgress): those listed in
+// spec.policyTypes, or both when it is empty
+func EnforcesDirection(spec *securityv1.NetworkPolicyGeneratorSpec, direction string) bool {
+	return len(spec.PolicyTypes) == 0 || slices.Contains(spec.PolicyTypes, direction)
+}
+
+// NodeCIDRs returns the node CIDRs ingress is allowed from: status.nodeCIDRs
+// when spec.allowNodeTraffic is set, nil otherwise
+func NodeCIDRs(generator *securityv1.NetworkPolicyGenerator) []string {
+	if !generator.Spec.AllowNodeTraffic {
+		return nil
+	}
+	return generator.Status.NodeCIDRs
+}
+
+// APIServerEndpoints returns the Kubernetes API server CIDRs and ports egress
+// is allowed to: status.apiServerCIDRs and status.apiServerPorts when
+// spec.allowAPIServer is set, nil otherwise
+func APIServerEndpoints(generator *securityv1.NetworkPolicyGenerator) ([]string, []int32) {
+	if !generator.Spec.AllowAPIServer {
+		return nil, nil
+	}
+	return generator.Status.APIServerCIDRs, generator.Status.APIServerPorts
+}
+
+// DNSPeers returns the destinations of a generator's DNS egress rule: the
+// cluster DNS pods in spec.dns mode "auto" (the default), spec.dns.to in
+// mode "custom", and nil in mode "none"
+func DNSPeers(spec *securityv1.NetworkPolicyGeneratorSpec) []securityv1.RulePeer {
+	mode := DNSModeAuto
+	if spec.DNS != nil && spec.DNS.Mode != "" {
+		mode = spec.DNS.Mode
+	}
+	switch mode {
+	case DNSModeNone:
+		return nil
+	case DNSModeCustom:
+		return spec.DNS.To
+	}
+	return []securityv1.RulePeer{{
+		Namespaces:  []string{NamespaceKubeSystem},
+		PodSelector: map[string]string{LabelK8sApp: LabelValueKubeDNS},
+	}}
+}
+
+// DNSProtocols returns the protocols DNS is allowed over: spec.dns.protocols,
+// or UDP and TCP when it is empty
+func DNSProtocols(spec *securityv1.NetworkPolicyGeneratorSpec) []string {
+	if spec.DNS != nil && len(spec.DNS.Protocols) > 0 {
+		return spec.DNS.Protocols
+	}
+	return []string{ProtocolUDP, ProtocolTCP}
+}
+
+// AllowedNamespaces returns the namespaces a deny-type generator allows:
+// policy.allowedNamespaces followed by the generator's own namespace when
+// policy.allowSameNamespace is set. New generators get it recorded as true
+// when created or first reconciled, so nil only remains on generators stored
+// before the field existed, and keeps it off for them.
+func AllowedNamespaces(generator *securityv1.NetworkPolicyGenerator) []string {
+	namespaces := generator.Spec.Policy.AllowedNamespaces
+	if !ptr.Deref(generator.Spec.Policy.AllowSameNamespace, false) || slices.Contains(namespaces, generator.Namespace) {
+		return namespaces
+	}
+	return append(slices.Clone(namespaces), generator.Namespace)
+}
+
+// PodLabelSelector returns the pods a generator's policies apply to:
+// policy.podLabelSelector when set, otherwise the policy.podSelector labels.
+// The result is a copy, and an empty selector selects every pod.
+func PodLabelSelector(generator *securityv1.NetworkPolicyGenerator) *metav1.LabelSelector {
+	if sel := generator.Spec.Policy.PodLabelSelector; sel != nil {
+		return sel.DeepCopy()
+	}
+	selector := &metav1.LabelSelector{}
+	if len(generator.Spec.Policy.PodSelector) > 0 {
+		selector.MatchLabels = maps.Clone(generator.Spec.Policy.PodSelector)
+	}
+	return selector
+}
+
+// EngineTypes returns the engines a generator spec selects, in order:
+// spec.policyEngines when set, otherwise spec.policyEngine (empty meaning
+// kubernetes). Duplicates are dropped.
+func EngineTypes(spec *securityv1.NetworkPolicyGeneratorSpec) []string {
+	if len(spec.PolicyEngines) == 0 {
+		if spec.PolicyEngine == "" {
+			return []string{EngineKubernetes}
+		}
+		return []string{spec.PolicyEngine}
+	}
+
+	engines := make([]string, 0, len(spec.PolicyEngines))
+	seen := make(map[string]bool, len(spec.PolicyEngines))
+	for _, engine := range spec.PolicyEngines {
+		if !seen[engine] {
+			seen[engine] = true
+			engines = append(engines, engine)
+		}
+	}
+	return engines
+}
+
+// rejectL7Rules returns an error when a global rule carries L7 http matches
+// that the named engine cannot express. Dropping them silently would open the
+// whole port, which is broader than what the user asked for.
+func rejectL7Rules(engineName string, rules []securityv1.GlobalRule) error {
+	for i, rule := range rules {
+		if len(rule.HTTP) > 0 {
+			return fmt.Errorf("global rule %d: http rules are not supported by the %s policy engine", i, engineName)
+		}
+	}
+	return nil
+}
+
+// rejectDenyRules returns an error when a global rule has type deny, for
+// engines that can only allow traffic. Ignoring the rule would leave the
+// port open that the user asked to block.
+func rejectDenyRules(engineName string, rules []securityv1.GlobalRule) error {
+	for i, rule := range rules {
+		if rule.Type == PolicyTypeDeny {
+			return fmt.Errorf("global rule %d: deny rules are not supported by the %s policy engine, which can only allow traffic; use the cilium, calico or antrea engine", i, engineName)
+		}
+	}
+	return nil
+}
+
+// rejectICMPRules returns an error when a global rule matches ICMP or ICMPv6,
+// for engines that cannot render ICMP type matches
+func rejectICMPRules(engineName string, rules []securityv1.GlobalRule) error {
+	for i, rule := range rules {
+		if IsICMPProtocol(rule.Protocol) {
+			return fmt.Errorf("global rule %d: %s rules are not supported by the %s policy engine; use the cilium, calico or antrea engine", i, rule.Protocol, engineName)
+		}
+	}
+	return nil
+}
+
+// rejectNotICMPRules returns an error when a global rule excludes ICMP
+// messages with notICMP, for engines that can only match ICMP types
+func rejectNotICMPRules(engineName string, rules []securityv1.GlobalRule) error {
+	for i, rule := range rules {
+		if rule.NotICMP != nil {
+			return fmt.Errorf("global rule %d: notICMP is not supported by the %s policy engine, which cannot exclude ICMP types; use the calico engine", i, engineName)
+		}
+	}
+	return nil
+}
+
+// IsICMPProtocol reports whether a rule protocol matches ICMP messages
+// instead of ports
+func IsICMPProtocol(protocol string) bool {
+	return protocol == ProtocolICMP || protocol == ProtocolICMPv6
+}
+
+// fqdnRuleName returns the exact name or wildcard pattern of an FQDN rule,
+// for engines that take both forms in a single field
+func fqdnRuleName(rule securityv1.FQDNRule) string {
+	if rule.MatchName != "" {
+		return rule.MatchName
+	}
+	return rule.MatchPattern
+}
+
+// fqdnPortProtocol returns the protocol of an FQDN port, defaulting to TCP
+func fqdnPortProtocol(port securityv1.FQDNPort) string {
+	if port.Protocol == "" {
+		return ProtocolTCP
+	}
+	return port.Protocol
+}
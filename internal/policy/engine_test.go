@@ -5,7 +5,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 )
@@ -35,6 +38,12 @@ func TestNewPolicyEngine(t *testing.T) {
 		assert.Equal(t, EngineCalico, engine.EngineName())
 	})
 
+	t.Run("Antrea engine", func(t *testing.T) {
+		engine, err := NewPolicyEngine(EngineAntrea)
+		require.NoError(t, err)
+		assert.Equal(t, EngineAntrea, engine.EngineName())
+	})
+
 	t.Run("Unsupported engine", func(t *testing.T) {
 		engine, err := NewPolicyEngine("unknown")
 		assert.Error(t, err)
@@ -93,3 +102,253 @@ func TestCiliumEngineInterface(t *testing.T) {
 		assert.NotNil(t, objects[0])
 	})
 }
+
+func TestEnginesRejectHTTPRules(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{
+		ObjectMeta: metav1.ObjectMeta{Name: nameTestPolicy, Namespace: nsTest},
+		Spec: securityv1.NetworkPolicyGeneratorSpec{
+			Policy: securityv1.PolicyConfig{Type: PolicyTypeDeny},
+			GlobalRules: []securityv1.GlobalRule{{
+				Direction: DirectionIngress,
+				Protocol:  ProtocolTCP,
+				Port:      80,
+				HTTP:      []securityv1.HTTPRule{{Method: "GET"}},
+			}},
+		},
+	}
+
+	for _, engineType := range []string{EngineKubernetes, EngineCalico, EngineAntrea} {
+		t.Run(engineType, func(t *testing.T) {
+			engine, err := NewPolicyEngine(engineType)
+			require.NoError(t, err)
+
+			_, err = engine.GeneratePolicies(generator)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "http rules are not supported by the "+engineType)
+		})
+	}
+}
+
+func TestKubernetesEngineRejectsDenyGlobalRules(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{
+		ObjectMeta: metav1.ObjectMeta{Name: nameTestPolicy, Namespace: nsTest},
+		Spec: securityv1.NetworkPolicyGeneratorSpec{
+			Policy: securityv1.PolicyConfig{Type: PolicyTypeDeny},
+			GlobalRules: []securityv1.GlobalRule{
+				{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 80},
+				{Type: PolicyTypeDeny, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 22},
+			},
+		},
+	}
+
+	_, err := NewKubernetesEngine().GeneratePolicies(generator)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "global rule 1: deny rules are not supported by the kubernetes policy engine")
+}
+
+func TestEnginesRejectICMPRules(t *testing.T) {
+	tests := []struct {
+		engine PolicyEngine
+		rule   securityv1.GlobalRule
+		err    string
+	}{
+		{
+			engine: NewKubernetesEngine(),
+			rule:   securityv1.GlobalRule{Protocol: ProtocolICMP, ICMP: &securityv1.ICMPMatch{Type: 8}},
+			err:    "global rule 0: ICMP rules are not supported by the kubernetes policy engine",
+		},
+		{
+			engine: NewAntreaEngine(),
+			rule:   securityv1.GlobalRule{Protocol: ProtocolICMPv6, ICMP: &securityv1.ICMPMatch{Type: 128}},
+			err:    "global rule 0: ICMPv6 rules are not supported by the antrea policy engine",
+		},
+		{
+			engine: NewAntreaEngine(),
+			rule:   securityv1.GlobalRule{Protocol: ProtocolICMP, NotICMP: &securityv1.ICMPMatch{Type: 5}},
+			err:    "global rule 0: notICMP is not supported by the antrea policy engine",
+		},
+		{
+			engine: NewCiliumEngine(),
+			rule:   securityv1.GlobalRule{Protocol: ProtocolICMP, ICMP: &securityv1.ICMPMatch{Type: 3}, NotICMP: &securityv1.ICMPMatch{Type: 3, Code: ptr.To(int32(4))}},
+			err:    "global rule 0: notICMP is not supported by the cilium policy engine",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			tt.rule.Type = PolicyTypeAllow
+			tt.rule.Direction = DirectionIngress
+			generator := &securityv1.NetworkPolicyGenerator{
+				ObjectMeta: metav1.ObjectMeta{Name: nameTestPolicy, Namespace: nsTest},
+				Spec: securityv1.NetworkPolicyGeneratorSpec{
+					Policy:      securityv1.PolicyConfig{Type: PolicyTypeDeny},
+					GlobalRules: []securityv1.GlobalRule{tt.rule},
+				},
+			}
+			_, err := tt.engine.GeneratePolicies(generator)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestKubernetesEngineSCTPGlobalRule(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{
+		ObjectMeta: metav1.ObjectMeta{Name: nameTestPolicy, Namespace: nsTest},
+		Spec: securityv1.NetworkPolicyGeneratorSpec{
+			Policy: securityv1.PolicyConfig{Type: PolicyTypeDeny},
+			GlobalRules: []securityv1.GlobalRule{
+				{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolSCTP, Port: 38412},
+			},
+		},
+	}
+
+	objects, err := NewKubernetesEngine().GeneratePolicies(generator)
+	require.NoError(t, err)
+	port := objects[0].(*networkingv1.NetworkPolicy).Spec.Ingress[0].Ports[0]
+	assert.Equal(t, corev1.ProtocolSCTP, *port.Protocol)
+	assert.Equal(t, int32(38412), port.Port.IntVal)
+}
+
+func TestEngineTypes(t *testing.T) {
+	t.Run("Defaults To Kubernetes", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGeneratorSpec{}
+		assert.Equal(t, []string{EngineKubernetes}, EngineTypes(spec))
+	})
+
+	t.Run("Single Engine", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGeneratorSpec{PolicyEngine: EngineCilium}
+		assert.Equal(t, []string{EngineCilium}, EngineTypes(spec))
+	})
+
+	t.Run("Engine List Takes Precedence", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGeneratorSpec{
+			PolicyEngine:  EngineKubernetes,
+			PolicyEngines: []string{EngineCalico, EngineCilium, EngineCalico},
+		}
+		assert.Equal(t, []string{EngineCalico, EngineCilium}, EngineTypes(spec))
+	})
+}
+
+func TestEngineKinds(t *testing.T) {
+	t.Run("Empty Means Kubernetes", func(t *testing.T) {
+		kinds := EngineKinds("")
+		require.Len(t, kinds, 1)
+		assert.Equal(t, "NetworkPolicy", kinds[0].Kind)
+		assert.Equal(t, "networking.k8s.io", kinds[0].Group)
+	})
+
+	t.Run("Antrea Default Kind First", func(t *testing.T) {
+		kinds := EngineKinds(EngineAntrea)
+		require.Len(t, kinds, 2)
+		assert.Equal(t, AntreaKind, kinds[0].Kind)
+		assert.False(t, kinds[0].ClusterScoped)
+		assert.Equal(t, AntreaClusterKind, kinds[1].Kind)
+		assert.True(t, kinds[1].ClusterScoped)
+	})
+
+	t.Run("Unknown Engine", func(t *testing.T) {
+		assert.Nil(t, EngineKinds(pluginName))
+	})
+}
+
+func TestTargetNamespaces(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{
+		ObjectMeta: metav1.ObjectMeta{Name: nameTest, Namespace: nsTest},
+		Spec: securityv1.NetworkPolicyGeneratorSpec{
+			Policy: securityv1.PolicyConfig{Type: PolicyTypeDeny, DeniedNamespaces: []string{nsOne}},
+		},
+	}
+	assert.Equal(t, []string{nsTest}, TargetNamespaces(generator))
+
+	generator.Spec.Policy.Type = PolicyTypeAllow
+	assert.Equal(t, []string{nsOne}, TargetNamespaces(generator))
+
+	// Namespaces matched by deniedNamespaceSelector follow, without duplicates
+	generator.Status.MatchedNamespaces = []string{nsOne, nsTwo}
+	assert.Equal(t, []string{nsOne, nsTwo}, TargetNamespaces(generator))
+	assert.Equal(t, []string{nsOne}, generator.Spec.Policy.DeniedNamespaces)
+}
+
+func TestPodLabelSelector(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{}
+	assert.Equal(t, &metav1.LabelSelector{}, PodLabelSelector(generator))
+
+	generator.Spec.Policy.PodSelector = map[string]string{labelApp: labelValueWeb}
+	selector := PodLabelSelector(generator)
+	assert.Equal(t, &metav1.LabelSelector{MatchLabels: map[string]string{labelApp: labelValueWeb}}, selector)
+	selector.MatchLabels[labelApp] = labelValueFrontend
+	assert.Equal(t, labelValueWeb, generator.Spec.Policy.PodSelector[labelApp])
+
+	generator.Spec.Policy.PodSelector = nil
+	generator.Spec.Policy.PodLabelSelector = &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: labelTier, Operator: metav1.LabelSelectorOpNotIn, Values: []string{"batch"}},
+		},
+	}
+	assert.Equal(t, generator.Spec.Policy.PodLabelSelector, PodLabelSelector(generator))
+	assert.NotSame(t, generator.Spec.Policy.PodLabelSelector, PodLabelSelector(generator))
+}
+
+func TestEnforcesDirection(t *testing.T) {
+	spec := &securityv1.NetworkPolicyGeneratorSpec{}
+	assert.True(t, EnforcesDirection(spec, DirectionIngress))
+	assert.True(t, EnforcesDirection(spec, DirectionEgress))
+
+	spec.PolicyTypes = []string{DirectionIngress}
+	assert.True(t, EnforcesDirection(spec, DirectionIngress))
+	assert.False(t, EnforcesDirection(spec, DirectionEgress))
+}
+
+func TestAllowedNamespaces(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{
+		ObjectMeta: metav1.ObjectMeta{Name: nameTest, Namespace: nsTest},
+		Spec: securityv1.NetworkPolicyGeneratorSpec{
+			Policy: securityv1.PolicyConfig{Type: PolicyTypeDeny, AllowedNamespaces: []string{nsOne}},
+		},
+	}
+	// A generator stored before allowSameNamespace existed keeps its own
+	// namespace denied
+	assert.Nil(t, generator.Spec.Policy.AllowSameNamespace)
+	assert.Equal(t, []string{nsOne}, AllowedNamespaces(generator))
+
+	generator.Spec.Policy.AllowSameNamespace = ptr.To(true)
+	assert.Equal(t, []string{nsOne, nsTest}, AllowedNamespaces(generator))
+	assert.Equal(t, []string{nsOne}, generator.Spec.Policy.AllowedNamespaces)
+
+	// An own namespace that is already listed is not repeated
+	generator.Spec.Policy.AllowedNamespaces = []string{nsTest}
+	assert.Equal(t, []string{nsTest}, AllowedNamespaces(generator))
+
+	generator.Spec.Policy.AllowSameNamespace = ptr.To(false)
+	generator.Spec.Policy.AllowedNamespaces = nil
+	assert.Empty(t, AllowedNamespaces(generator))
+}
+
+func TestNodeCIDRs(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{
+		Status: securityv1.NetworkPolicyGeneratorStatus{NodeCIDRs: []string{cidrHost192}},
+	}
+	// Stale status is ignored once allowNodeTraffic is off
+	assert.Empty(t, NodeCIDRs(generator))
+
+	generator.Spec.AllowNodeTraffic = true
+	assert.Equal(t, []string{cidrHost192}, NodeCIDRs(generator))
+}
+
+func TestAPIServerEndpoints(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{
+		Status: securityv1.NetworkPolicyGeneratorStatus{
+			APIServerCIDRs: []string{cidrHost192},
+			APIServerPorts: []int32{6443},
+		},
+	}
+	cidrs, ports := APIServerEndpoints(generator)
+	assert.Empty(t, cidrs)
+	assert.Empty(t, ports)
+
+	generator.Spec.AllowAPIServer = true
+	cidrs, ports = APIServerEndpoints(generator)
+	assert.Equal(t, []string{cidrHost192}, cidrs)
+	assert.Equal(t, []int32{6443}, ports)
+}
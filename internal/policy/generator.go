@@ -1,6 +1,8 @@
 package policy
 
 import (
+	"fmt"
+
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -54,6 +56,19 @@ func (e *KubernetesEngine) GeneratePolicies(generator *securityv1.NetworkPolicyG
 
 // generateK8sPolicies contains the core Kubernetes NetworkPolicy generation logic
 func (e *KubernetesEngine) generateK8sPolicies(generator *securityv1.NetworkPolicyGenerator) ([]*networkingv1.NetworkPolicy, error) {
+	if err := rejectL7Rules(EngineKubernetes, generator.Spec.GlobalRules); err != nil {
+		return nil, err
+	}
+	if err := rejectDenyRules(EngineKubernetes, generator.Spec.GlobalRules); err != nil {
+		return nil, err
+	}
+	if err := rejectICMPRules(EngineKubernetes, generator.Spec.GlobalRules); err != nil {
+		return nil, err
+	}
+	if len(generator.Spec.FQDNRules) > 0 {
+		return nil, fmt.Errorf("fqdnRules are not supported by the kubernetes policy engine: NetworkPolicy can only match IP ranges, use the cilium, calico or antrea engine")
+	}
+
 	basePolicy := newBaseNetworkPolicy(generator)
 
 	var policies []*networkingv1.NetworkPolicy
@@ -64,25 +79,49 @@ func (e *KubernetesEngine) generateK8sPolicies(generator *securityv1.NetworkPoli
 	}
 
 	// Add DNS egress rule to all policies
-	dnsRule := dnsEgressRule()
 	for _, p := range policies {
-		p.Spec.Egress = append(p.Spec.Egress, dnsRule)
+		p.Spec.Egress = append(p.Spec.Egress, dnsEgressRules(&generator.Spec)...)
+	}
+
+	// Allow kubelet probes and other node-originated traffic
+	for _, p := range policies {
+		p.Spec.Ingress = append(p.Spec.Ingress, nodeIngressRules(NodeCIDRs(generator))...)
+	}
+
+	// Allow egress to the Kubernetes API server
+	for _, p := range policies {
+		p.Spec.Egress = append(p.Spec.Egress, apiServerEgressRules(APIServerEndpoints(generator))...)
 	}
 
 	// Apply global rules
-	e.applyGlobalRules(policies, generator.Spec.GlobalRules)
+	e.applyGlobalRules(policies, generator.Spec.GlobalRules, generator.Spec.IPFamilies)
 
 	// Apply CIDR rules
 	e.applyCIDRRules(policies, generator.Spec.CIDRRules)
 
+	// Drop the sections of directions spec.policyTypes does not enforce
+	for _, p := range policies {
+		if !EnforcesDirection(&generator.Spec, DirectionIngress) {
+			p.Spec.Ingress = nil
+		}
+		if !EnforcesDirection(&generator.Spec, DirectionEgress) {
+			p.Spec.Egress = nil
+		}
+	}
+
 	return policies, nil
 }
 
 // newBaseNetworkPolicy creates a base NetworkPolicy with common settings
 func newBaseNetworkPolicy(generator *securityv1.NetworkPolicyGenerator) *networkingv1.NetworkPolicy {
-	podSelector := metav1.LabelSelector{}
-	if len(generator.Spec.Policy.PodSelector) > 0 {
-		podSelector.MatchLabels = generator.Spec.Policy.PodSelector
+	podSelector := *PodLabelSelector(generator)
+
+	var policyTypes []networkingv1.PolicyType
+	if EnforcesDirection(&generator.Spec, DirectionIngress) {
+		policyTypes = append(policyTypes, networkingv1.PolicyTypeIngress)
+	}
+	if EnforcesDirection(&generator.Spec, DirectionEgress) {
+		policyTypes = append(policyTypes, networkingv1.PolicyTypeEgress)
 	}
 
 	return &networkingv1.NetworkPolicy{
@@ -100,10 +139,7 @@ func newBaseNetworkPolicy(generator *securityv1.NetworkPolicyGenerator) *network
 		},
 		Spec: networkingv1.NetworkPolicySpec{
 			PodSelector: podSelector,
-			PolicyTypes: []networkingv1.PolicyType{
-				networkingv1.PolicyTypeIngress,
-				networkingv1.PolicyTypeEgress,
-			},
+			PolicyTypes: policyTypes,
 		},
 	}
 }
@@ -112,15 +148,11 @@ func newBaseNetworkPolicy(generator *securityv1.NetworkPolicyGenerator) *network
 func (e *KubernetesEngine) generateAllowPolicies(basePolicy *networkingv1.NetworkPolicy, generator *securityv1.NetworkPolicyGenerator) []*networkingv1.NetworkPolicy {
 	var policies []*networkingv1.NetworkPolicy
 
-	if len(generator.Spec.Policy.DeniedNamespaces) == 0 {
-		return policies
-	}
-
-	for _, ns := range generator.Spec.Policy.DeniedNamespaces {
+	for _, ns := range TargetNamespaces(generator) {
 		policy := basePolicy.DeepCopy()
 		policy.Namespace = ns
 
-		rules := GenerateDeniedNamespaceRules(generator.Spec.Policy.DeniedNamespaces)
+		rules := GenerateDeniedNamespaceRules(generator.Spec.Policy.DeniedNamespaces, generator.Spec.Policy.DeniedNamespaceSelector)
 		policy.Spec.Ingress = rules.Ingress
 		policy.Spec.Egress = rules.Egress
 
@@ -135,8 +167,9 @@ func (e *KubernetesEngine) generateDenyPolicies(basePolicy *networkingv1.Network
 	policy := basePolicy.DeepCopy()
 	policy.Namespace = generator.Namespace
 
-	if len(generator.Spec.Policy.AllowedNamespaces) > 0 {
-		rules := GenerateNamespaceRules(generator.Spec.Policy.AllowedNamespaces)
+	allowed := AllowedNamespaces(generator)
+	if len(allowed) > 0 || generator.Spec.Policy.AllowedNamespaceSelector != nil {
+		rules := GenerateNamespaceRules(allowed, generator.Spec.Policy.AllowedNamespaceSelector)
 		policy.Spec.Ingress = rules.Ingress
 		policy.Spec.Egress = rules.Egress
 	} else {
@@ -155,35 +188,44 @@ func globalRulePort(rule securityv1.GlobalRule) intstr.IntOrString {
 	return intstr.FromInt32(rule.Port)
 }
 
-// applyGlobalRules adds global rules to all policies
-func (e *KubernetesEngine) applyGlobalRules(policies []*networkingv1.NetworkPolicy, globalRules []securityv1.GlobalRule) {
+// globalRuleEndPort returns the end of a GlobalRule port range, or nil for a
+// single port
+func globalRuleEndPort(rule securityv1.GlobalRule) *int32 {
+	if rule.EndPort == 0 {
+		return nil
+	}
+	return ptr.To(rule.EndPort)
+}
+
+// globalRuleNetworkPolicyPort converts a GlobalRule port into a NetworkPolicyPort
+func globalRuleNetworkPolicyPort(rule securityv1.GlobalRule) networkingv1.NetworkPolicyPort {
+	port := globalRulePort(rule)
+	return networkingv1.NetworkPolicyPort{
+		Protocol: (*v1.Protocol)(ptr.To(rule.Protocol)),
+		Port:     &port,
+		EndPort:  globalRuleEndPort(rule),
+	}
+}
+
+// applyGlobalRules adds global rules to all policies. Rules without from/to
+// peers are open to every address of the generator's IP families.
+func (e *KubernetesEngine) applyGlobalRules(policies []*networkingv1.NetworkPolicy, globalRules []securityv1.GlobalRule, ipFamilies []string) {
 	if globalRules == nil {
 		return
 	}
 
 	for _, p := range policies {
 		for _, rule := range globalRules {
-			port := globalRulePort(rule)
 			switch rule.Direction {
 			case DirectionIngress:
 				p.Spec.Ingress = append(p.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
-					Ports: []networkingv1.NetworkPolicyPort{{
-						Protocol: (*v1.Protocol)(&rule.Protocol),
-						Port:     &port,
-					}},
-					From: []networkingv1.NetworkPolicyPeer{{
-						IPBlock: &networkingv1.IPBlock{CIDR: CIDRAllTraffic},
-					}},
+					Ports: []networkingv1.NetworkPolicyPort{globalRuleNetworkPolicyPort(rule)},
+					From:  globalRulePeers(rule.From, ipFamilies),
 				})
 			case DirectionEgress:
 				p.Spec.Egress = append(p.Spec.Egress, networkingv1.NetworkPolicyEgressRule{
-					Ports: []networkingv1.NetworkPolicyPort{{
-						Protocol: (*v1.Protocol)(&rule.Protocol),
-						Port:     &port,
-					}},
-					To: []networkingv1.NetworkPolicyPeer{{
-						IPBlock: &networkingv1.IPBlock{CIDR: CIDRAllTraffic},
-					}},
+					Ports: []networkingv1.NetworkPolicyPort{globalRuleNetworkPolicyPort(rule)},
+					To:    globalRulePeers(rule.To, ipFamilies),
 				})
 			}
 		}
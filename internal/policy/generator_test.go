@@ -5,8 +5,10 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 )
@@ -71,6 +73,43 @@ func TestGenerator(t *testing.T) {
 		}
 	})
 
+	t.Run("Generate Policy with Namespace Selectors", func(t *testing.T) {
+		selector := &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type:                    PolicyTypeAllow,
+					DeniedNamespaceSelector: selector,
+				},
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{MatchedNamespaces: []string{"dev-a", "dev-b"}},
+		}
+
+		// Allow type: one policy per matched namespace, excluding the selector
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies, 2)
+		assert.Equal(t, "dev-a", policies[0].Namespace)
+		assert.Equal(t, "dev-b", policies[1].Namespace)
+		require.Len(t, policies[0].Spec.Ingress[0].From, 1)
+		assert.Equal(t, []metav1.LabelSelectorRequirement{
+			{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"dev"}},
+		}, policies[0].Spec.Ingress[0].From[0].NamespaceSelector.MatchExpressions)
+
+		// Deny type: the selector is allowed as is
+		spec.Spec.Policy = securityv1.PolicyConfig{Type: PolicyTypeDeny, AllowedNamespaceSelector: selector}
+		policies, err = generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies, 1)
+		assert.Equal(t, nsTest, policies[0].Namespace)
+		require.Len(t, policies[0].Spec.Ingress[0].From, 1)
+		assert.Equal(t, selector, policies[0].Spec.Ingress[0].From[0].NamespaceSelector)
+	})
+
 	t.Run("Generate Policy with Global Rules", func(t *testing.T) {
 		spec := &securityv1.NetworkPolicyGenerator{
 			ObjectMeta: metav1.ObjectMeta{
@@ -129,6 +168,181 @@ func TestGenerator(t *testing.T) {
 		assert.Len(t, policy.Spec.Egress[0].To, 2)
 	})
 
+	t.Run("Generate Deny Policy Allowing Same Namespace", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type:               PolicyTypeDeny,
+					AllowedNamespaces:  []string{nsAllowed1},
+					AllowSameNamespace: ptr.To(true),
+				},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies, 1)
+		require.Len(t, policies[0].Spec.Ingress[0].From, 2)
+		assert.Equal(t, nsTest, policies[0].Spec.Ingress[0].From[1].NamespaceSelector.MatchLabels[LabelK8sNamespace])
+		assert.Equal(t, nsTest, policies[0].Spec.Egress[0].To[1].NamespaceSelector.MatchLabels[LabelK8sNamespace])
+	})
+
+	t.Run("Generate Ingress-Only Policy", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				PolicyTypes: []string{DirectionIngress},
+				Policy: securityv1.PolicyConfig{
+					Type:              PolicyTypeDeny,
+					AllowedNamespaces: []string{nsAllowed1},
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress},
+					{Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress},
+				},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies, 1)
+		assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}, policies[0].Spec.PolicyTypes)
+		assert.Len(t, policies[0].Spec.Ingress, 2)
+		assert.Nil(t, policies[0].Spec.Egress)
+
+		spec.Spec.PolicyTypes = []string{DirectionEgress}
+		policies, err = generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		assert.Equal(t, []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}, policies[0].Spec.PolicyTypes)
+		assert.Nil(t, policies[0].Spec.Ingress)
+		assert.NotEmpty(t, policies[0].Spec.Egress)
+	})
+
+	t.Run("Allow Node Traffic", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				AllowNodeTraffic: true,
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{
+				NodeCIDRs: []string{"10.244.1.0/24", cidrHost192},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies, 1)
+		require.Len(t, policies[0].Spec.Ingress, 1)
+		rule := policies[0].Spec.Ingress[0]
+		assert.Empty(t, rule.Ports)
+		require.Len(t, rule.From, 2)
+		assert.Equal(t, "10.244.1.0/24", rule.From[0].IPBlock.CIDR)
+		assert.Equal(t, cidrHost192, rule.From[1].IPBlock.CIDR)
+	})
+
+	t.Run("Allow API Server", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				AllowAPIServer: true,
+			},
+			Status: securityv1.NetworkPolicyGeneratorStatus{
+				APIServerCIDRs: []string{cidrHost192},
+				APIServerPorts: []int32{6443},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies, 1)
+		// DNS + API server
+		require.Len(t, policies[0].Spec.Egress, 2)
+		rule := policies[0].Spec.Egress[1]
+		require.Len(t, rule.To, 1)
+		assert.Equal(t, cidrHost192, rule.To[0].IPBlock.CIDR)
+		require.Len(t, rule.Ports, 1)
+		assert.Equal(t, int32(6443), rule.Ports[0].Port.IntVal)
+	})
+
+	t.Run("Generate Policy with Custom DNS", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				DNS: &securityv1.DNSConfig{
+					Mode: DNSModeCustom,
+					To:   []securityv1.RulePeer{{CIDR: cidrNodeLocal}},
+				},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies, 1)
+		require.Len(t, policies[0].Spec.Egress, 1)
+		dns := policies[0].Spec.Egress[0]
+		assert.Len(t, dns.Ports, 2)
+		require.Len(t, dns.To, 1)
+		assert.Equal(t, cidrNodeLocal, dns.To[0].IPBlock.CIDR)
+
+		spec.Spec.DNS = &securityv1.DNSConfig{Mode: DNSModeNone}
+		policies, err = generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		assert.Empty(t, policies[0].Spec.Egress)
+		assert.Contains(t, policies[0].Spec.PolicyTypes, networkingv1.PolicyTypeEgress)
+	})
+
+	t.Run("Generate Policy with Pod Label Selector", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+					PodLabelSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{labelTier: labelValueFrontend},
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: labelApp, Operator: metav1.LabelSelectorOpIn, Values: []string{"api", "worker"}},
+							{Key: "batch", Operator: metav1.LabelSelectorOpDoesNotExist},
+						},
+					},
+				},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies, 1)
+		assert.Equal(t, *spec.Spec.Policy.PodLabelSelector, policies[0].Spec.PodSelector)
+		assert.NotSame(t, &spec.Spec.Policy.PodLabelSelector.MatchExpressions[0], &policies[0].Spec.PodSelector.MatchExpressions[0])
+	})
+
 	t.Run("Generate Policy with Pod Selector", func(t *testing.T) {
 		spec := &securityv1.NetworkPolicyGenerator{
 			ObjectMeta: metav1.ObjectMeta{
@@ -250,6 +464,93 @@ func TestGenerator(t *testing.T) {
 		assert.Equal(t, "grpc", policy.Spec.Egress[1].Ports[0].Port.StrVal)
 	})
 
+	t.Run("Generate Dual-Stack Global Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				IPFamilies: []string{IPFamilyIPv4, IPFamilyIPv6},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 443},
+				},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		from := policies[0].Spec.Ingress[0].From
+		require.Len(t, from, 2)
+		assert.Equal(t, CIDRAllTraffic, from[0].IPBlock.CIDR)
+		assert.Equal(t, CIDRAllTrafficIPv6, from[1].IPBlock.CIDR)
+	})
+
+	t.Run("Generate Global Rules with Peers", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{
+						Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 5432,
+						From: []securityv1.RulePeer{
+							{Namespaces: []string{nsOne}, PodSelector: map[string]string{labelApp: labelValueWeb}},
+							{NamespaceSelector: map[string]string{labelTier: labelValueFrontend}},
+							{CIDR: cidr10Slash8},
+						},
+					},
+				},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies[0].Spec.Ingress, 1)
+		rule := policies[0].Spec.Ingress[0]
+		assert.Equal(t, int32(5432), rule.Ports[0].Port.IntVal)
+		require.Len(t, rule.From, 3)
+
+		assert.Equal(t, []string{nsOne}, rule.From[0].NamespaceSelector.MatchExpressions[0].Values)
+		assert.Equal(t, map[string]string{labelApp: labelValueWeb}, rule.From[0].PodSelector.MatchLabels)
+		assert.Equal(t, map[string]string{labelTier: labelValueFrontend}, rule.From[1].NamespaceSelector.MatchLabels)
+		assert.Nil(t, rule.From[1].PodSelector)
+		assert.Equal(t, cidr10Slash8, rule.From[2].IPBlock.CIDR)
+	})
+
+	t.Run("Generate Policy with Port Range", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				GlobalRules: []securityv1.GlobalRule{
+					{Type: PolicyTypeAllow, Direction: DirectionIngress, Protocol: ProtocolTCP, Port: 30000, EndPort: 32767},
+				},
+			},
+		}
+
+		policies, err := generator.GenerateNetworkPolicies(spec)
+		require.NoError(t, err)
+		require.Len(t, policies[0].Spec.Ingress, 1)
+		port := policies[0].Spec.Ingress[0].Ports[0]
+		assert.Equal(t, int32(30000), port.Port.IntVal)
+		require.NotNil(t, port.EndPort)
+		assert.Equal(t, int32(32767), *port.EndPort)
+	})
+
 	t.Run("Generate Allow Policy with Pod Selector", func(t *testing.T) {
 		spec := &securityv1.NetworkPolicyGenerator{
 			ObjectMeta: metav1.ObjectMeta{
@@ -273,4 +574,23 @@ func TestGenerator(t *testing.T) {
 		assert.Equal(t, "api", policies[0].Spec.PodSelector.MatchLabels[labelApp])
 		assert.Equal(t, "denied-ns", policies[0].Namespace)
 	})
+
+	t.Run("Reject FQDN Rules", func(t *testing.T) {
+		spec := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeDeny,
+				},
+				FQDNRules: []securityv1.FQDNRule{{MatchName: fqdnAPI}},
+			},
+		}
+
+		_, err := generator.GenerateNetworkPolicies(spec)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "fqdnRules are not supported by the kubernetes policy engine")
+	})
 }
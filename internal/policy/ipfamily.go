@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+)
+
+// AllTrafficCIDRs returns the CIDRs matching every address of the given IP
+// families. No families means IPv4 only, which is what every engine emitted
+// before spec.ipFamilies existed.
+func AllTrafficCIDRs(ipFamilies []string) []string {
+	if len(ipFamilies) == 0 {
+		return []string{CIDRAllTraffic}
+	}
+	cidrs := make([]string, 0, len(ipFamilies))
+	for _, family := range ipFamilies {
+		switch family {
+		case IPFamilyIPv4:
+			cidrs = append(cidrs, CIDRAllTraffic)
+		case IPFamilyIPv6:
+			cidrs = append(cidrs, CIDRAllTrafficIPv6)
+		}
+	}
+	return cidrs
+}
+
+// ParseIPFamilies parses a comma-separated IP family list, as given to the
+// --default-ip-families flag
+func ParseIPFamilies(value string) ([]string, error) {
+	var families []string
+	for _, family := range strings.Split(value, ",") {
+		family = strings.TrimSpace(family)
+		if family == "" {
+			continue
+		}
+		if family != IPFamilyIPv4 && family != IPFamilyIPv6 {
+			return nil, fmt.Errorf("invalid IP family %q: must be %s or %s", family, IPFamilyIPv4, IPFamilyIPv6)
+		}
+		if slices.Contains(families, family) {
+			return nil, fmt.Errorf("duplicate IP family: %s", family)
+		}
+		families = append(families, family)
+	}
+	return families, nil
+}
+
+// cidrFamily returns the IP family of a parsed CIDR
+func cidrFamily(ipNet *net.IPNet) string {
+	if ipNet.IP.To4() != nil {
+		return IPFamilyIPv4
+	}
+	return IPFamilyIPv6
+}
@@ -0,0 +1,38 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllTrafficCIDRs(t *testing.T) {
+	assert.Equal(t, []string{CIDRAllTraffic}, AllTrafficCIDRs(nil))
+	assert.Equal(t, []string{CIDRAllTrafficIPv6}, AllTrafficCIDRs([]string{IPFamilyIPv6}))
+	assert.Equal(t, []string{CIDRAllTrafficIPv6, CIDRAllTraffic}, AllTrafficCIDRs([]string{IPFamilyIPv6, IPFamilyIPv4}))
+}
+
+func TestParseIPFamilies(t *testing.T) {
+	t.Run("Dual Stack", func(t *testing.T) {
+		families, err := ParseIPFamilies(" IPv4, IPv6 ,")
+		require.NoError(t, err)
+		assert.Equal(t, []string{IPFamilyIPv4, IPFamilyIPv6}, families)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		families, err := ParseIPFamilies("")
+		require.NoError(t, err)
+		assert.Empty(t, families)
+	})
+
+	t.Run("Invalid Family", func(t *testing.T) {
+		_, err := ParseIPFamilies("IPv4,ipv6")
+		assert.ErrorContains(t, err, "invalid IP family")
+	})
+
+	t.Run("Duplicate Family", func(t *testing.T) {
+		_, err := ParseIPFamilies("IPv6,IPv6")
+		assert.ErrorContains(t, err, "duplicate IP family")
+	})
+}
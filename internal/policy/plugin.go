@@ -0,0 +1,289 @@
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+)
+
+// PluginsConfig is the operator config file that registers external policy
+// engine plugins, as given to the --engine-plugins-config flag
+type PluginsConfig struct {
+	Plugins []PluginConfig `json:"plugins"`
+}
+
+// PluginConfig registers a single external policy engine
+type PluginConfig struct {
+	// Name is the engine name generators select in policyEngine / policyEngines
+	Name string `json:"name"`
+
+	// Command is the absolute path of the plugin executable
+	Command string `json:"command"`
+
+	// Args are passed to the plugin executable
+	Args []string `json:"args,omitempty"`
+
+	// Timeout bounds a single plugin run. Defaults to DefaultPluginTimeout.
+	Timeout metav1.Duration `json:"timeout,omitempty"`
+
+	// Kinds lists every resource kind the plugin may emit. Objects of other
+	// kinds are rejected, and cleanup deletes the generated objects of each kind.
+	Kinds []PluginKind `json:"kinds"`
+}
+
+// PluginKind declares a resource kind a plugin emits
+type PluginKind struct {
+	APIVersion    string `json:"apiVersion"`
+	Kind          string `json:"kind"`
+	ClusterScoped bool   `json:"clusterScoped,omitempty"`
+}
+
+// PluginRequest is written to the plugin's stdin as JSON
+type PluginRequest struct {
+	APIVersion string                             `json:"apiVersion"`
+	Kind       string                             `json:"kind"`
+	Generator  *securityv1.NetworkPolicyGenerator `json:"generator"`
+}
+
+// PluginResponse is read from the plugin's stdout as JSON. A plugin reports
+// a spec it cannot express through Error rather than a partial object list.
+type PluginResponse struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Objects    []json.RawMessage `json:"objects,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// PluginRegistry holds the external policy engines loaded from the operator
+// config. A nil registry has no plugins.
+type PluginRegistry struct {
+	plugins map[string]*PluginEngine
+}
+
+// LoadPluginRegistry reads and validates a PluginsConfig file
+func LoadPluginRegistry(path string) (*PluginRegistry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read engine plugins config: %w", err)
+	}
+	var config PluginsConfig
+	if err := yaml.UnmarshalStrict(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse engine plugins config %s: %w", path, err)
+	}
+	return NewPluginRegistry(config.Plugins)
+}
+
+// NewPluginRegistry validates the plugin configs and creates a registry
+func NewPluginRegistry(configs []PluginConfig) (*PluginRegistry, error) {
+	registry := &PluginRegistry{plugins: make(map[string]*PluginEngine, len(configs))}
+	for i, config := range configs {
+		engine, err := NewPluginEngine(config)
+		if err != nil {
+			return nil, fmt.Errorf("plugins[%d]: %w", i, err)
+		}
+		if _, exists := registry.plugins[config.Name]; exists {
+			return nil, fmt.Errorf("plugins[%d]: duplicate plugin name %q", i, config.Name)
+		}
+		registry.plugins[config.Name] = engine
+	}
+	return registry, nil
+}
+
+// Engine returns the plugin registered under name
+func (r *PluginRegistry) Engine(name string) (*PluginEngine, bool) {
+	if r == nil {
+		return nil, false
+	}
+	engine, ok := r.plugins[name]
+	return engine, ok
+}
+
+// Kinds returns the kinds the named plugin emits, or nil when no plugin is
+// registered under name
+func (r *PluginRegistry) Kinds(name string) []PolicyKind {
+	engine, ok := r.Engine(name)
+	if !ok {
+		return nil
+	}
+	return engine.kinds
+}
+
+// Names returns the registered plugin names in sorted order
+func (r *PluginRegistry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.plugins))
+	for name := range r.plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PluginEngine is a PolicyEngine backed by an external executable. Each
+// GeneratePolicies call runs the executable once, writes a PluginRequest to
+// its stdin and reads a PluginResponse from its stdout.
+type PluginEngine struct {
+	config PluginConfig
+	kinds  []PolicyKind
+}
+
+// NewPluginEngine validates a plugin config and creates its engine
+func NewPluginEngine(config PluginConfig) (*PluginEngine, error) {
+	if errs := validation.IsDNS1123Label(config.Name); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid plugin name %q: %s", config.Name, strings.Join(errs, "; "))
+	}
+	if config.Name == EngineAuto || EngineKinds(config.Name) != nil {
+		return nil, fmt.Errorf("plugin name %q is reserved for a built-in policy engine", config.Name)
+	}
+	if !filepath.IsAbs(config.Command) {
+		return nil, fmt.Errorf("plugin %s: command must be an absolute path, got %q", config.Name, config.Command)
+	}
+	if config.Timeout.Duration < 0 {
+		return nil, fmt.Errorf("plugin %s: timeout must not be negative", config.Name)
+	}
+	if config.Timeout.Duration == 0 {
+		config.Timeout.Duration = DefaultPluginTimeout
+	}
+	if len(config.Kinds) == 0 {
+		return nil, fmt.Errorf("plugin %s: at least one kind must be declared", config.Name)
+	}
+
+	kinds := make([]PolicyKind, 0, len(config.Kinds))
+	for i, kind := range config.Kinds {
+		gv, err := schema.ParseGroupVersion(kind.APIVersion)
+		if err != nil || gv.Version == "" {
+			return nil, fmt.Errorf("plugin %s: kinds[%d]: invalid apiVersion %q", config.Name, i, kind.APIVersion)
+		}
+		if kind.Kind == "" {
+			return nil, fmt.Errorf("plugin %s: kinds[%d]: kind is required", config.Name, i)
+		}
+		kinds = append(kinds, PolicyKind{GroupVersionKind: gv.WithKind(kind.Kind), ClusterScoped: kind.ClusterScoped})
+	}
+
+	return &PluginEngine{config: config, kinds: kinds}, nil
+}
+
+// EngineName returns the plugin name
+func (e *PluginEngine) EngineName() string {
+	return e.config.Name
+}
+
+// GeneratePolicies runs the plugin and returns the objects it emitted as
+// *unstructured.Unstructured. Every object must be of a declared kind and
+// follow the generated naming scheme, so cleanup can find it again.
+func (e *PluginEngine) GeneratePolicies(generator *securityv1.NetworkPolicyGenerator) ([]runtime.Object, error) {
+	request, err := json.Marshal(PluginRequest{
+		APIVersion: PluginAPIVersion,
+		Kind:       PluginRequestKind,
+		Generator:  generator,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+
+	output, err := e.run(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var response PluginResponse
+	if err := json.Unmarshal(output, &response); err != nil {
+		return nil, fmt.Errorf("plugin %s returned an invalid response: %w", e.config.Name, err)
+	}
+	if response.APIVersion != PluginAPIVersion || response.Kind != PluginResponseKind {
+		return nil, fmt.Errorf("plugin %s returned %s %s, expected %s %s", e.config.Name,
+			response.APIVersion, response.Kind, PluginAPIVersion, PluginResponseKind)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %s: %s", e.config.Name, response.Error)
+	}
+
+	policies := make([]runtime.Object, 0, len(response.Objects))
+	for i, raw := range response.Objects {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw); err != nil {
+			return nil, fmt.Errorf("plugin %s: objects[%d]: %w", e.config.Name, i, err)
+		}
+		if err := e.validateObject(generator, obj); err != nil {
+			return nil, fmt.Errorf("plugin %s: objects[%d]: %w", e.config.Name, i, err)
+		}
+		policies = append(policies, obj)
+	}
+	return policies, nil
+}
+
+// run executes the plugin with the request on stdin and returns its stdout
+func (e *PluginEngine) run(request []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), e.config.Timeout.Duration)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, e.config.Command, e.config.Args...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// Do not wait on output pipes that a killed plugin's children keep open.
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("plugin %s timed out after %s", e.config.Name, e.config.Timeout.Duration)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin %s failed: %w: %s", e.config.Name, err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w", e.config.Name, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// validateObject checks an emitted object against the declared kinds and the
+// naming scheme the controller relies on for cleanup
+func (e *PluginEngine) validateObject(generator *securityv1.NetworkPolicyGenerator, obj *unstructured.Unstructured) error {
+	gvk := obj.GroupVersionKind()
+	idx := slices.IndexFunc(e.kinds, func(k PolicyKind) bool { return k.GroupVersionKind == gvk })
+	if idx < 0 {
+		return fmt.Errorf("kind %s is not declared by the plugin", gvk)
+	}
+
+	if e.kinds[idx].ClusterScoped {
+		if obj.GetNamespace() != "" {
+			return fmt.Errorf("%s is cluster-scoped but has namespace %q", gvk.Kind, obj.GetNamespace())
+		}
+		if want := ClusterPolicyName(generator.Namespace, generator.Name); obj.GetName() != want {
+			return fmt.Errorf("%s must be named %q, got %q", gvk.Kind, want, obj.GetName())
+		}
+		return nil
+	}
+
+	if !slices.Contains(TargetNamespaces(generator), obj.GetNamespace()) {
+		return fmt.Errorf("%s namespace %q is not a target namespace of the generator", gvk.Kind, obj.GetNamespace())
+	}
+	if want := PolicyName(generator.Name); obj.GetName() != want {
+		return fmt.Errorf("%s must be named %q, got %q", gvk.Kind, want, obj.GetName())
+	}
+	return nil
+}
+
+// Ensure PluginEngine implements PolicyEngine (compile-time check)
+var _ PolicyEngine = (*PluginEngine)(nil)
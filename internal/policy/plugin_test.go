@@ -0,0 +1,242 @@
+package policy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
+)
+
+// writePlugin writes an executable shell script plugin and returns its path
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o755))
+	return path
+}
+
+// pluginConfig returns a valid plugin config emitting NetworkPolicy and a
+// cluster-scoped example kind
+func pluginConfig(command string) PluginConfig {
+	return PluginConfig{
+		Name:    pluginName,
+		Command: command,
+		Kinds: []PluginKind{
+			{APIVersion: "networking.k8s.io/v1", Kind: "NetworkPolicy"},
+			{APIVersion: pluginAPIVersion, Kind: "ClusterPolicy", ClusterScoped: true},
+		},
+	}
+}
+
+// pluginResponse builds a script that saves the request to requestPath and
+// responds with the objects
+func pluginResponse(t *testing.T, requestPath string, objects ...map[string]interface{}) string {
+	t.Helper()
+	raw := make([]json.RawMessage, len(objects))
+	for i, obj := range objects {
+		data, err := json.Marshal(obj)
+		require.NoError(t, err)
+		raw[i] = data
+	}
+	data, err := json.Marshal(PluginResponse{APIVersion: PluginAPIVersion, Kind: PluginResponseKind, Objects: raw})
+	require.NoError(t, err)
+	return "cat > " + requestPath + "\ncat <<'EOF'\n" + string(data) + "\nEOF\n"
+}
+
+func pluginObject(apiVersion, kind, namespace, name string) map[string]interface{} {
+	metadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	return map[string]interface{}{"apiVersion": apiVersion, "kind": kind, "metadata": metadata}
+}
+
+func pluginGenerator() *securityv1.NetworkPolicyGenerator {
+	return &securityv1.NetworkPolicyGenerator{
+		ObjectMeta: metav1.ObjectMeta{Name: nameTest, Namespace: nsTest},
+		Spec: securityv1.NetworkPolicyGeneratorSpec{
+			PolicyEngine: pluginName,
+			Policy:       securityv1.PolicyConfig{Type: PolicyTypeDeny, AllowedNamespaces: []string{nsOne}},
+		},
+	}
+}
+
+func TestNewPluginRegistry(t *testing.T) {
+	t.Run("Valid Config", func(t *testing.T) {
+		registry, err := NewPluginRegistry([]PluginConfig{pluginConfig("/usr/local/bin/acme")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{pluginName}, registry.Names())
+
+		engine, ok := registry.Engine(pluginName)
+		require.True(t, ok)
+		assert.Equal(t, pluginName, engine.EngineName())
+		assert.Equal(t, DefaultPluginTimeout, engine.config.Timeout.Duration)
+
+		kinds := registry.Kinds(pluginName)
+		require.Len(t, kinds, 2)
+		assert.Equal(t, "networking.k8s.io", kinds[0].Group)
+		assert.True(t, kinds[1].ClusterScoped)
+	})
+
+	t.Run("Nil Registry Has No Plugins", func(t *testing.T) {
+		var registry *PluginRegistry
+		_, ok := registry.Engine(pluginName)
+		assert.False(t, ok)
+		assert.Nil(t, registry.Kinds(pluginName))
+		assert.Empty(t, registry.Names())
+	})
+
+	invalid := map[string]func(*PluginConfig){
+		"Invalid Name":        func(c *PluginConfig) { c.Name = "Acme_CNI" },
+		"Built-in Name":       func(c *PluginConfig) { c.Name = EngineCilium },
+		"Auto Name":           func(c *PluginConfig) { c.Name = EngineAuto },
+		"Relative Command":    func(c *PluginConfig) { c.Command = "acme" },
+		"Negative Timeout":    func(c *PluginConfig) { c.Timeout = metav1.Duration{Duration: -time.Second} },
+		"No Kinds":            func(c *PluginConfig) { c.Kinds = nil },
+		"Invalid API Version": func(c *PluginConfig) { c.Kinds[0].APIVersion = "a/b/c" },
+		"Missing Kind":        func(c *PluginConfig) { c.Kinds[0].Kind = "" },
+	}
+	for name, mutate := range invalid {
+		t.Run(name, func(t *testing.T) {
+			config := pluginConfig("/usr/local/bin/acme")
+			mutate(&config)
+			_, err := NewPluginRegistry([]PluginConfig{config})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Duplicate Name", func(t *testing.T) {
+		config := pluginConfig("/usr/local/bin/acme")
+		_, err := NewPluginRegistry([]PluginConfig{config, config})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "duplicate plugin name")
+	})
+}
+
+func TestLoadPluginRegistry(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Valid File", func(t *testing.T) {
+		path := filepath.Join(dir, "plugins.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`plugins:
+  - name: acme
+    command: /usr/local/bin/acme
+    args: ["--mode", "strict"]
+    timeout: 30s
+    kinds:
+      - apiVersion: acme.example.com/v1
+        kind: AcmePolicy
+`), 0o600))
+
+		registry, err := LoadPluginRegistry(path)
+		require.NoError(t, err)
+		engine, ok := registry.Engine(pluginName)
+		require.True(t, ok)
+		assert.Equal(t, []string{"--mode", "strict"}, engine.config.Args)
+		assert.Equal(t, 30*time.Second, engine.config.Timeout.Duration)
+	})
+
+	t.Run("Unknown Field", func(t *testing.T) {
+		path := filepath.Join(dir, "typo.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("plugins:\n  - name: acme\n    comand: /usr/local/bin/acme\n"), 0o600))
+		_, err := LoadPluginRegistry(path)
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing File", func(t *testing.T) {
+		_, err := LoadPluginRegistry(filepath.Join(dir, "missing.yaml"))
+		assert.Error(t, err)
+	})
+}
+
+func TestPluginEngineGeneratePolicies(t *testing.T) {
+	generator := pluginGenerator()
+	namespacedObject := pluginObject("networking.k8s.io/v1", "NetworkPolicy", nsTest, PolicyName(nameTest))
+	clusterObject := pluginObject(pluginAPIVersion, "ClusterPolicy", "", ClusterPolicyName(nsTest, nameTest))
+
+	t.Run("Sends Request And Returns Objects", func(t *testing.T) {
+		requestPath := filepath.Join(t.TempDir(), "request.json")
+		script := pluginResponse(t, requestPath, namespacedObject, clusterObject)
+		engine, err := NewPluginEngine(pluginConfig(writePlugin(t, script)))
+		require.NoError(t, err)
+
+		objects, err := engine.GeneratePolicies(generator)
+		require.NoError(t, err)
+		require.Len(t, objects, 2)
+		u, ok := objects[0].(*unstructured.Unstructured)
+		require.True(t, ok)
+		assert.Equal(t, "NetworkPolicy", u.GetKind())
+		assert.Equal(t, nsTest, u.GetNamespace())
+
+		data, err := os.ReadFile(requestPath)
+		require.NoError(t, err)
+		var request PluginRequest
+		require.NoError(t, json.Unmarshal(data, &request))
+		assert.Equal(t, PluginAPIVersion, request.APIVersion)
+		assert.Equal(t, PluginRequestKind, request.Kind)
+		assert.Equal(t, nameTest, request.Generator.Name)
+		assert.Equal(t, []string{nsOne}, request.Generator.Spec.Policy.AllowedNamespaces)
+	})
+
+	rejected := map[string]map[string]interface{}{
+		"Undeclared Kind":         pluginObject(pluginAPIVersion, "OtherPolicy", nsTest, PolicyName(nameTest)),
+		"Foreign Namespace":       pluginObject("networking.k8s.io/v1", "NetworkPolicy", nsOne, PolicyName(nameTest)),
+		"Wrong Name":              pluginObject("networking.k8s.io/v1", "NetworkPolicy", nsTest, "custom"),
+		"Namespaced Cluster Kind": pluginObject(pluginAPIVersion, "ClusterPolicy", nsTest, ClusterPolicyName(nsTest, nameTest)),
+	}
+	for name, obj := range rejected {
+		t.Run(name, func(t *testing.T) {
+			engine, err := NewPluginEngine(pluginConfig(writePlugin(t, pluginResponse(t, "/dev/null", obj))))
+			require.NoError(t, err)
+			_, err = engine.GeneratePolicies(generator)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "objects[0]")
+		})
+	}
+
+	t.Run("Plugin Reports Error", func(t *testing.T) {
+		script := `cat > /dev/null
+echo '{"apiVersion":"` + PluginAPIVersion + `","kind":"` + PluginResponseKind + `","error":"fqdnRules are not supported"}'
+`
+		engine, err := NewPluginEngine(pluginConfig(writePlugin(t, script)))
+		require.NoError(t, err)
+		_, err = engine.GeneratePolicies(generator)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "fqdnRules are not supported")
+	})
+
+	t.Run("Unexpected Response Kind", func(t *testing.T) {
+		script := "cat > /dev/null\necho '{\"apiVersion\":\"v1\",\"kind\":\"List\"}'\n"
+		engine, err := NewPluginEngine(pluginConfig(writePlugin(t, script)))
+		require.NoError(t, err)
+		_, err = engine.GeneratePolicies(generator)
+		assert.Error(t, err)
+	})
+
+	t.Run("Non-Zero Exit Includes Stderr", func(t *testing.T) {
+		script := "cat > /dev/null\necho 'cannot reach controller' >&2\nexit 3\n"
+		engine, err := NewPluginEngine(pluginConfig(writePlugin(t, script)))
+		require.NoError(t, err)
+		_, err = engine.GeneratePolicies(generator)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot reach controller")
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		config := pluginConfig(writePlugin(t, "exec sleep 5\n"))
+		config.Timeout = metav1.Duration{Duration: 100 * time.Millisecond}
+		engine, err := NewPluginEngine(config)
+		require.NoError(t, err)
+		_, err = engine.GeneratePolicies(generator)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "timed out")
+	})
+}
@@ -1,6 +1,9 @@
 package policy
 
 import (
+	"maps"
+	"slices"
+
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -16,21 +19,22 @@ type NamespaceRules struct {
 	Egress  []networkingv1.NetworkPolicyEgressRule
 }
 
-// GenerateNamespaceRules generates rules for allowed namespaces
-func GenerateNamespaceRules(namespaces []string) NamespaceRules {
+// GenerateNamespaceRules generates rules for allowed namespaces, given by
+// name and/or by an optional label selector
+func GenerateNamespaceRules(namespaces []string, selector *metav1.LabelSelector) NamespaceRules {
 	var rules NamespaceRules
 
-	ingressRule := networkingv1.NetworkPolicyIngressRule{
-		From: make([]networkingv1.NetworkPolicyPeer, len(namespaces)),
+	peers := make([]networkingv1.NetworkPolicyPeer, 0, len(namespaces)+1)
+	for _, ns := range namespaces {
+		peers = append(peers, namespacePeer(ns))
 	}
-	egressRule := networkingv1.NetworkPolicyEgressRule{
-		To: make([]networkingv1.NetworkPolicyPeer, len(namespaces)),
+	if selector != nil {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{NamespaceSelector: selector.DeepCopy()})
 	}
 
-	for i, ns := range namespaces {
-		peer := namespacePeer(ns)
-		ingressRule.From[i] = peer
-		egressRule.To[i] = peer
+	ingressRule := networkingv1.NetworkPolicyIngressRule{From: peers}
+	egressRule := networkingv1.NetworkPolicyEgressRule{
+		To: append([]networkingv1.NetworkPolicyPeer(nil), peers...),
 	}
 
 	rules.Ingress = []networkingv1.NetworkPolicyIngressRule{ingressRule}
@@ -38,34 +42,85 @@ func GenerateNamespaceRules(namespaces []string) NamespaceRules {
 	return rules
 }
 
-// GenerateDeniedNamespaceRules generates rules that exclude denied namespaces
-func GenerateDeniedNamespaceRules(namespaces []string) NamespaceRules {
+// GenerateDeniedNamespaceRules generates rules that exclude denied namespaces,
+// given by name and/or by an optional label selector. A namespace selector
+// cannot be negated directly, so the selector is excluded with one peer per
+// requirement, each matching the namespaces that fail that requirement.
+func GenerateDeniedNamespaceRules(namespaces []string, selector *metav1.LabelSelector) NamespaceRules {
 	var rules NamespaceRules
 
-	if len(namespaces) == 0 {
+	if len(namespaces) == 0 && selector == nil {
 		return rules
 	}
 
-	excludePeer := networkingv1.NetworkPolicyPeer{
-		NamespaceSelector: &metav1.LabelSelector{
-			MatchExpressions: []metav1.LabelSelectorRequirement{{
-				Key:      LabelK8sNamespace,
-				Operator: metav1.LabelSelectorOpNotIn,
-				Values:   namespaces,
-			}},
-		},
+	var excludeNames []metav1.LabelSelectorRequirement
+	if len(namespaces) > 0 {
+		excludeNames = []metav1.LabelSelectorRequirement{{
+			Key:      LabelK8sNamespace,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   namespaces,
+		}}
 	}
 
-	rules.Ingress = []networkingv1.NetworkPolicyIngressRule{{
-		From: []networkingv1.NetworkPolicyPeer{excludePeer},
-	}}
+	var peers []networkingv1.NetworkPolicyPeer
+	if selector == nil {
+		peers = []networkingv1.NetworkPolicyPeer{{
+			NamespaceSelector: &metav1.LabelSelector{MatchExpressions: excludeNames},
+		}}
+	}
+	for _, requirement := range negatedRequirements(selector) {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{
+				MatchExpressions: append(slices.Clone(excludeNames), requirement),
+			},
+		})
+	}
+
+	// An empty selector denies every namespace, leaving nothing to allow
+	if len(peers) == 0 {
+		return rules
+	}
+
+	rules.Ingress = []networkingv1.NetworkPolicyIngressRule{{From: peers}}
 	rules.Egress = []networkingv1.NetworkPolicyEgressRule{{
-		To: []networkingv1.NetworkPolicyPeer{excludePeer},
+		To: append([]networkingv1.NetworkPolicyPeer(nil), peers...),
 	}}
 
 	return rules
 }
 
+// negatedRequirements returns the negation of each requirement of a label
+// selector; a label set fails the selector exactly when it matches one of
+// them. matchLabels entries come first, sorted by key.
+func negatedRequirements(selector *metav1.LabelSelector) []metav1.LabelSelectorRequirement {
+	if selector == nil {
+		return nil
+	}
+	requirements := make([]metav1.LabelSelectorRequirement, 0, len(selector.MatchLabels)+len(selector.MatchExpressions))
+	for _, key := range slices.Sorted(maps.Keys(selector.MatchLabels)) {
+		requirements = append(requirements, metav1.LabelSelectorRequirement{
+			Key:      key,
+			Operator: metav1.LabelSelectorOpNotIn,
+			Values:   []string{selector.MatchLabels[key]},
+		})
+	}
+	for _, expr := range selector.MatchExpressions {
+		negated := metav1.LabelSelectorRequirement{Key: expr.Key, Values: slices.Clone(expr.Values)}
+		switch expr.Operator {
+		case metav1.LabelSelectorOpIn:
+			negated.Operator = metav1.LabelSelectorOpNotIn
+		case metav1.LabelSelectorOpNotIn:
+			negated.Operator = metav1.LabelSelectorOpIn
+		case metav1.LabelSelectorOpExists:
+			negated.Operator = metav1.LabelSelectorOpDoesNotExist
+		case metav1.LabelSelectorOpDoesNotExist:
+			negated.Operator = metav1.LabelSelectorOpExists
+		}
+		requirements = append(requirements, negated)
+	}
+	return requirements
+}
+
 // namespacePeer creates a NetworkPolicyPeer that matches a specific namespace
 func namespacePeer(namespace string) networkingv1.NetworkPolicyPeer {
 	return networkingv1.NetworkPolicyPeer{
@@ -77,50 +132,134 @@ func namespacePeer(namespace string) networkingv1.NetworkPolicyPeer {
 	}
 }
 
-// dnsEgressRule creates an egress rule that allows DNS resolution (UDP/TCP port 53)
-func dnsEgressRule() networkingv1.NetworkPolicyEgressRule {
-	udp := v1.ProtocolUDP
-	tcp := v1.ProtocolTCP
+// dnsEgressRules creates the egress rules that allow DNS resolution on port 53
+// to the destinations spec.dns selects. Mode "none" yields no rule.
+func dnsEgressRules(spec *securityv1.NetworkPolicyGeneratorSpec) []networkingv1.NetworkPolicyEgressRule {
+	peers := DNSPeers(spec)
+	if len(peers) == 0 {
+		return nil
+	}
 	dnsPort := intstr.FromInt32(DNSPort)
+	protocols := DNSProtocols(spec)
+	ports := make([]networkingv1.NetworkPolicyPort, len(protocols))
+	for i, protocol := range protocols {
+		ports[i] = networkingv1.NetworkPolicyPort{Protocol: ptr.To(v1.Protocol(protocol)), Port: &dnsPort}
+	}
 
-	return networkingv1.NetworkPolicyEgressRule{
-		Ports: []networkingv1.NetworkPolicyPort{
-			{Protocol: &udp, Port: &dnsPort},
-			{Protocol: &tcp, Port: &dnsPort},
-		},
+	return []networkingv1.NetworkPolicyEgressRule{{
+		Ports: ports,
+		To:    globalRulePeers(peers, spec.IPFamilies),
+	}}
+}
+
+// nodeIngressRules creates the ingress rule that allows every port from the
+// given node CIDRs, or no rule without CIDRs
+func nodeIngressRules(cidrs []string) []networkingv1.NetworkPolicyIngressRule {
+	if len(cidrs) == 0 {
+		return nil
 	}
+	peers := make([]networkingv1.NetworkPolicyPeer, len(cidrs))
+	for i, cidr := range cidrs {
+		peers[i] = networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}
+	}
+	return []networkingv1.NetworkPolicyIngressRule{{From: peers}}
 }
 
-// GenerateGlobalRules generates rules based on global configuration
-func GenerateGlobalRules(rules []securityv1.GlobalRule) ([]networkingv1.NetworkPolicyIngressRule, []networkingv1.NetworkPolicyEgressRule) {
+// apiServerEgressRules creates the egress rule that allows TCP to the given
+// API server CIDRs and ports, or no rule without CIDRs. Without ports every
+// port of the CIDRs is allowed.
+func apiServerEgressRules(cidrs []string, ports []int32) []networkingv1.NetworkPolicyEgressRule {
+	if len(cidrs) == 0 {
+		return nil
+	}
+	rule := networkingv1.NetworkPolicyEgressRule{}
+	for _, cidr := range cidrs {
+		rule.To = append(rule.To, networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}})
+	}
+	for _, port := range ports {
+		rule.Ports = append(rule.Ports, networkingv1.NetworkPolicyPort{
+			Protocol: ptr.To(v1.ProtocolTCP),
+			Port:     ptr.To(intstr.FromInt32(port)),
+		})
+	}
+	return []networkingv1.NetworkPolicyEgressRule{rule}
+}
+
+// GenerateGlobalRules generates rules based on global configuration. Rules
+// without from/to peers cover every address of the given IP families. Deny rules are
+// skipped: NetworkPolicy rules can only allow traffic, and the kubernetes
+// engine rejects deny rules before calling this.
+func GenerateGlobalRules(rules []securityv1.GlobalRule, ipFamilies []string) ([]networkingv1.NetworkPolicyIngressRule, []networkingv1.NetworkPolicyEgressRule) {
 	var ingressRules []networkingv1.NetworkPolicyIngressRule
 	var egressRules []networkingv1.NetworkPolicyEgressRule
 
 	for _, rule := range rules {
-		port := globalRulePort(rule)
+		if rule.Type == PolicyTypeDeny {
+			continue
+		}
 		switch rule.Direction {
 		case DirectionIngress:
 			ingressRules = append(ingressRules, networkingv1.NetworkPolicyIngressRule{
-				Ports: []networkingv1.NetworkPolicyPort{{
-					Protocol: (*v1.Protocol)(ptr.To(rule.Protocol)),
-					Port:     &port,
-				}},
-				From: []networkingv1.NetworkPolicyPeer{{
-					IPBlock: &networkingv1.IPBlock{CIDR: CIDRAllTraffic},
-				}},
+				Ports: []networkingv1.NetworkPolicyPort{globalRuleNetworkPolicyPort(rule)},
+				From:  globalRulePeers(rule.From, ipFamilies),
 			})
 		case DirectionEgress:
 			egressRules = append(egressRules, networkingv1.NetworkPolicyEgressRule{
-				Ports: []networkingv1.NetworkPolicyPort{{
-					Protocol: (*v1.Protocol)(ptr.To(rule.Protocol)),
-					Port:     &port,
-				}},
-				To: []networkingv1.NetworkPolicyPeer{{
-					IPBlock: &networkingv1.IPBlock{CIDR: CIDRAllTraffic},
-				}},
+				Ports: []networkingv1.NetworkPolicyPort{globalRuleNetworkPolicyPort(rule)},
+				To:    globalRulePeers(rule.To, ipFamilies),
 			})
 		}
 	}
 
 	return ingressRules, egressRules
 }
+
+// allTrafficPeers returns one ipBlock peer per IP family, together matching
+// every address
+func allTrafficPeers(ipFamilies []string) []networkingv1.NetworkPolicyPeer {
+	cidrs := AllTrafficCIDRs(ipFamilies)
+	peers := make([]networkingv1.NetworkPolicyPeer, len(cidrs))
+	for i, cidr := range cidrs {
+		peers[i] = networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}
+	}
+	return peers
+}
+
+// globalRulePeers converts the from/to peers of a global rule into
+// NetworkPolicy peers. Without peers the rule is open to every address of
+// the given IP families.
+func globalRulePeers(peers []securityv1.RulePeer, ipFamilies []string) []networkingv1.NetworkPolicyPeer {
+	if len(peers) == 0 {
+		return allTrafficPeers(ipFamilies)
+	}
+	result := make([]networkingv1.NetworkPolicyPeer, len(peers))
+	for i, peer := range peers {
+		if peer.CIDR != "" {
+			result[i] = networkingv1.NetworkPolicyPeer{IPBlock: &networkingv1.IPBlock{CIDR: peer.CIDR}}
+			continue
+		}
+		result[i] = networkingv1.NetworkPolicyPeer{NamespaceSelector: peerNamespaceSelector(peer)}
+		if len(peer.PodSelector) > 0 {
+			result[i].PodSelector = &metav1.LabelSelector{MatchLabels: peer.PodSelector}
+		}
+	}
+	return result
+}
+
+// peerNamespaceSelector returns the namespace selector of a rule peer, or nil
+// when the peer only selects pods in the policy's own namespace
+func peerNamespaceSelector(peer securityv1.RulePeer) *metav1.LabelSelector {
+	switch {
+	case len(peer.Namespaces) > 0:
+		return &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      LabelK8sNamespace,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   append([]string(nil), peer.Namespaces...),
+			}},
+		}
+	case len(peer.NamespaceSelector) > 0:
+		return &metav1.LabelSelector{MatchLabels: peer.NamespaceSelector}
+	}
+	return nil
+}
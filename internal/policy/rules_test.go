@@ -5,18 +5,49 @@ import (
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
-func TestDnsEgressRule(t *testing.T) {
-	rule := dnsEgressRule()
-	assert.Len(t, rule.Ports, 2)
-	assert.Equal(t, int32(53), rule.Ports[0].Port.IntVal)
-	assert.Equal(t, int32(53), rule.Ports[1].Port.IntVal)
+func TestDnsEgressRules(t *testing.T) {
+	t.Run("Auto Mode Targets Cluster DNS", func(t *testing.T) {
+		rules := dnsEgressRules(&securityv1.NetworkPolicyGeneratorSpec{})
+		require.Len(t, rules, 1)
+		assert.Len(t, rules[0].Ports, 2)
+		assert.Equal(t, int32(53), rules[0].Ports[0].Port.IntVal)
+		assert.Equal(t, int32(53), rules[0].Ports[1].Port.IntVal)
+		require.Len(t, rules[0].To, 1)
+		assert.Equal(t, NamespaceKubeSystem,
+			rules[0].To[0].NamespaceSelector.MatchExpressions[0].Values[0])
+		assert.Equal(t, LabelValueKubeDNS, rules[0].To[0].PodSelector.MatchLabels[LabelK8sApp])
+	})
+
+	t.Run("None Mode Adds No Rule", func(t *testing.T) {
+		rules := dnsEgressRules(&securityv1.NetworkPolicyGeneratorSpec{
+			DNS: &securityv1.DNSConfig{Mode: DNSModeNone},
+		})
+		assert.Empty(t, rules)
+	})
+
+	t.Run("Custom Mode Targets Listed Resolvers", func(t *testing.T) {
+		rules := dnsEgressRules(&securityv1.NetworkPolicyGeneratorSpec{
+			DNS: &securityv1.DNSConfig{
+				Mode:      DNSModeCustom,
+				To:        []securityv1.RulePeer{{CIDR: cidrNodeLocal}},
+				Protocols: []string{ProtocolUDP},
+			},
+		})
+		require.Len(t, rules, 1)
+		require.Len(t, rules[0].Ports, 1)
+		assert.Equal(t, "UDP", string(*rules[0].Ports[0].Protocol))
+		require.Len(t, rules[0].To, 1)
+		assert.Equal(t, cidrNodeLocal, rules[0].To[0].IPBlock.CIDR)
+	})
 }
 
 func TestGenerateDeniedNamespaceRulesEmpty(t *testing.T) {
-	rules := GenerateDeniedNamespaceRules([]string{})
+	rules := GenerateDeniedNamespaceRules([]string{}, nil)
 	assert.Empty(t, rules.Ingress)
 	assert.Empty(t, rules.Egress)
 }
@@ -24,7 +55,7 @@ func TestGenerateDeniedNamespaceRulesEmpty(t *testing.T) {
 func TestRules(t *testing.T) {
 	t.Run("Generate Namespace Rules", func(t *testing.T) {
 		namespaces := []string{nsOne, nsTwo}
-		rules := GenerateNamespaceRules(namespaces)
+		rules := GenerateNamespaceRules(namespaces, nil)
 
 		assert.Len(t, rules.Ingress, 1)
 		assert.Len(t, rules.Egress, 1)
@@ -40,7 +71,7 @@ func TestRules(t *testing.T) {
 
 	t.Run("Generate Denied Namespace Rules", func(t *testing.T) {
 		namespaces := []string{nsOne, nsTwo}
-		rules := GenerateDeniedNamespaceRules(namespaces)
+		rules := GenerateDeniedNamespaceRules(namespaces, nil)
 
 		assert.Len(t, rules.Ingress, 1)
 		assert.Len(t, rules.Egress, 1)
@@ -58,6 +89,68 @@ func TestRules(t *testing.T) {
 		assert.ElementsMatch(t, namespaces, egressNS.Values)
 	})
 
+	t.Run("Generate Namespace Rules With Selector", func(t *testing.T) {
+		selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "platform"}}
+		rules := GenerateNamespaceRules([]string{nsOne}, selector)
+
+		require.Len(t, rules.Ingress[0].From, 2)
+		assert.Equal(t, selector, rules.Ingress[0].From[1].NamespaceSelector)
+		assert.NotSame(t, selector, rules.Ingress[0].From[1].NamespaceSelector)
+		assert.Equal(t, rules.Ingress[0].From, rules.Egress[0].To)
+	})
+
+	t.Run("Generate Denied Namespace Rules With Selector", func(t *testing.T) {
+		selector := &metav1.LabelSelector{
+			MatchLabels: map[string]string{"env": "dev"},
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"sandbox"}},
+				{Key: "quarantine", Operator: metav1.LabelSelectorOpExists},
+			},
+		}
+		rules := GenerateDeniedNamespaceRules([]string{nsOne}, selector)
+
+		// One peer per negated requirement, each also excluding the names
+		require.Len(t, rules.Ingress[0].From, 3)
+		excludeNames := metav1.LabelSelectorRequirement{
+			Key: LabelK8sNamespace, Operator: metav1.LabelSelectorOpNotIn, Values: []string{nsOne},
+		}
+		expected := []metav1.LabelSelectorRequirement{
+			{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"dev"}},
+			{Key: "tier", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"sandbox"}},
+			{Key: "quarantine", Operator: metav1.LabelSelectorOpDoesNotExist},
+		}
+		for i, peer := range rules.Ingress[0].From {
+			assert.Equal(t, []metav1.LabelSelectorRequirement{excludeNames, expected[i]},
+				peer.NamespaceSelector.MatchExpressions)
+		}
+		assert.Equal(t, rules.Ingress[0].From, rules.Egress[0].To)
+	})
+
+	t.Run("Generate Denied Namespace Rules With Selector Only", func(t *testing.T) {
+		selector := &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{
+				{Key: "env", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"prod"}},
+				{Key: "legacy", Operator: metav1.LabelSelectorOpDoesNotExist},
+			},
+		}
+		rules := GenerateDeniedNamespaceRules(nil, selector)
+
+		require.Len(t, rules.Ingress[0].From, 2)
+		assert.Equal(t, []metav1.LabelSelectorRequirement{
+			{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod"}},
+		}, rules.Ingress[0].From[0].NamespaceSelector.MatchExpressions)
+		assert.Equal(t, []metav1.LabelSelectorRequirement{
+			{Key: "legacy", Operator: metav1.LabelSelectorOpExists},
+		}, rules.Ingress[0].From[1].NamespaceSelector.MatchExpressions)
+	})
+
+	t.Run("Generate Denied Namespace Rules With Empty Selector", func(t *testing.T) {
+		// An empty selector denies every namespace
+		rules := GenerateDeniedNamespaceRules([]string{nsOne}, &metav1.LabelSelector{})
+		assert.Empty(t, rules.Ingress)
+		assert.Empty(t, rules.Egress)
+	})
+
 	t.Run("Generate Global Rules", func(t *testing.T) {
 		globalRules := []securityv1.GlobalRule{
 			{
@@ -72,7 +165,7 @@ func TestRules(t *testing.T) {
 			},
 		}
 
-		ingressRules, egressRules := GenerateGlobalRules(globalRules)
+		ingressRules, egressRules := GenerateGlobalRules(globalRules, nil)
 
 		// Verify ingress rules
 		assert.Len(t, ingressRules, 1)
@@ -99,7 +192,7 @@ func TestRules(t *testing.T) {
 			},
 		}
 
-		ingressRules, egressRules := GenerateGlobalRules(globalRules)
+		ingressRules, egressRules := GenerateGlobalRules(globalRules, nil)
 
 		assert.Len(t, ingressRules, 1)
 		assert.Equal(t, namedPortHTTP, ingressRules[0].Ports[0].Port.StrVal)
@@ -107,6 +200,49 @@ func TestRules(t *testing.T) {
 		assert.Len(t, egressRules, 1)
 		assert.Equal(t, "https", egressRules[0].Ports[0].Port.StrVal)
 	})
+
+	t.Run("Generate IPv6-Only Global Rules", func(t *testing.T) {
+		globalRules := []securityv1.GlobalRule{
+			{Direction: DirectionEgress, Protocol: ProtocolTCP, Port: 443},
+		}
+
+		_, egressRules := GenerateGlobalRules(globalRules, []string{IPFamilyIPv6})
+
+		assert.Len(t, egressRules, 1)
+		assert.Len(t, egressRules[0].To, 1)
+		assert.Equal(t, CIDRAllTrafficIPv6, egressRules[0].To[0].IPBlock.CIDR)
+	})
+
+	t.Run("Generate Global Rules with Pod Peer", func(t *testing.T) {
+		globalRules := []securityv1.GlobalRule{
+			{
+				Direction: DirectionEgress, Protocol: ProtocolTCP, Port: 5432,
+				To: []securityv1.RulePeer{{PodSelector: map[string]string{labelApp: labelValueWeb}}},
+			},
+		}
+
+		_, egressRules := GenerateGlobalRules(globalRules, nil)
+
+		assert.Len(t, egressRules, 1)
+		assert.Len(t, egressRules[0].To, 1)
+		assert.Nil(t, egressRules[0].To[0].NamespaceSelector)
+		assert.Nil(t, egressRules[0].To[0].IPBlock)
+		assert.Equal(t, labelValueWeb, egressRules[0].To[0].PodSelector.MatchLabels[labelApp])
+	})
+
+	t.Run("Generate Global Rules with Port Range", func(t *testing.T) {
+		globalRules := []securityv1.GlobalRule{
+			{Direction: DirectionEgress, Protocol: ProtocolTCP, Port: 20, EndPort: 21},
+			{Direction: DirectionEgress, Protocol: ProtocolTCP, Port: 443},
+		}
+
+		_, egressRules := GenerateGlobalRules(globalRules, nil)
+
+		assert.Len(t, egressRules, 2)
+		assert.Equal(t, int32(20), egressRules[0].Ports[0].Port.IntVal)
+		assert.Equal(t, ptr.To(int32(21)), egressRules[0].Ports[0].EndPort)
+		assert.Nil(t, egressRules[1].Ports[0].EndPort)
+	})
 }
 
 func TestGlobalRulePort(t *testing.T) {
@@ -1,7 +1,8 @@
 package policy
 
 import (
-	"strconv"
+	"reflect"
+	"slices"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 )
@@ -107,37 +108,20 @@ func ListTemplateNames() []string {
 	return names
 }
 
-// mergeGlobalRules merges template rules with user-defined rules (user rules take precedence)
+// mergeGlobalRules appends template rules to the user-defined rules. User
+// rules are kept as written, even when several share a port: they can differ
+// in type, peers or HTTP match, and a deny rule must never be folded into an
+// allow. A template rule is only left out when a user rule is identical to it.
 func mergeGlobalRules(userRules []securityv1.GlobalRule, templateRules ...securityv1.GlobalRule) []securityv1.GlobalRule {
-	// Start with template rules, then append user rules (user overrides)
-	seen := make(map[string]bool)
-	var result []securityv1.GlobalRule
+	result := append([]securityv1.GlobalRule(nil), userRules...)
 
-	// User rules first (higher priority)
-	for _, r := range userRules {
-		key := globalRuleKey(r)
-		if !seen[key] {
-			seen[key] = true
-			result = append(result, r)
-		}
-	}
-
-	// Template rules (only add if not already defined by user)
 	for _, r := range templateRules {
-		key := globalRuleKey(r)
-		if !seen[key] {
-			seen[key] = true
+		if !slices.ContainsFunc(userRules, func(u securityv1.GlobalRule) bool {
+			return reflect.DeepEqual(u, r)
+		}) {
 			result = append(result, r)
 		}
 	}
 
 	return result
 }
-
-// globalRuleKey generates a dedup key for a global rule
-func globalRuleKey(r securityv1.GlobalRule) string {
-	if r.NamedPort != "" {
-		return r.Direction + "/" + r.Protocol + "/" + r.NamedPort
-	}
-	return r.Direction + "/" + r.Protocol + "/" + strconv.Itoa(int(r.Port))
-}
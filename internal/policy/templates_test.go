@@ -1,10 +1,12 @@
 package policy
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 )
@@ -144,7 +146,7 @@ func TestTemplateApply(t *testing.T) {
 }
 
 func TestMergeGlobalRules(t *testing.T) {
-	t.Run("user rules take precedence", func(t *testing.T) {
+	t.Run("template rule on a user rule's port is kept", func(t *testing.T) {
 		userRules := []securityv1.GlobalRule{
 			{Type: PolicyTypeDeny, Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress},
 		}
@@ -155,11 +157,38 @@ func TestMergeGlobalRules(t *testing.T) {
 
 		result := mergeGlobalRules(userRules, templateRules...)
 
-		// User's deny rule for port 80 should win over template's allow
-		require.Len(t, result, 2) // port 80 (user) + port 443 (template)
-		assert.Equal(t, PolicyTypeDeny, result[0].Type)
-		assert.Equal(t, int32(80), result[0].Port)
-		assert.Equal(t, int32(443), result[1].Port)
+		// The user's deny differs in type, so the template allow stays too;
+		// engines order deny rules first, so the deny still wins
+		require.Len(t, result, 3)
+		assert.Equal(t, userRules[0], result[0])
+		assert.Equal(t, templateRules, result[1:])
+	})
+
+	t.Run("identical template rule is deduped", func(t *testing.T) {
+		userRules := []securityv1.GlobalRule{
+			{Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress},
+		}
+		templateRules := []securityv1.GlobalRule{
+			{Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress},
+		}
+
+		result := mergeGlobalRules(userRules, templateRules...)
+		assert.Equal(t, userRules, result)
+	})
+
+	t.Run("template rule with other peers is kept", func(t *testing.T) {
+		userRules := []securityv1.GlobalRule{
+			{
+				Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress,
+				To: []securityv1.RulePeer{{CIDR: "10.0.0.0/8"}},
+			},
+		}
+		templateRules := []securityv1.GlobalRule{
+			{Type: PolicyTypeAllow, Port: 443, Protocol: ProtocolTCP, Direction: DirectionEgress},
+		}
+
+		result := mergeGlobalRules(userRules, templateRules...)
+		assert.Len(t, result, 2)
 	})
 
 	t.Run("empty user rules uses all template rules", func(t *testing.T) {
@@ -193,6 +222,18 @@ func TestMergeGlobalRules(t *testing.T) {
 		result := mergeGlobalRules(userRules, templateRules...)
 		assert.Len(t, result, 1) // deduped
 	})
+
+	t.Run("port ranges with different ends are kept", func(t *testing.T) {
+		userRules := []securityv1.GlobalRule{
+			{Type: PolicyTypeAllow, Port: 8000, EndPort: 8010, Protocol: ProtocolTCP, Direction: DirectionIngress},
+		}
+		templateRules := []securityv1.GlobalRule{
+			{Type: PolicyTypeAllow, Port: 8000, Protocol: ProtocolTCP, Direction: DirectionIngress},
+		}
+
+		result := mergeGlobalRules(userRules, templateRules...)
+		assert.Len(t, result, 2)
+	})
 }
 
 func TestTemplateWithUserRulesPreserved(t *testing.T) {
@@ -216,25 +257,80 @@ func TestTemplateWithUserRulesPreserved(t *testing.T) {
 	assert.Greater(t, len(spec.GlobalRules), 1, "should have template rules too")
 }
 
-// TestGlobalRuleKeyDistinguishesPorts guards against encoding the port as a rune.
-// The CRD accepts ports 1-65535, but 55296-57343 map onto the UTF-16 surrogate
-// range, which is not a valid code point: string(rune(p)) collapses every one of
-// them to U+FFFD, so distinct rules would share a dedup key and silently drop.
-func TestGlobalRuleKeyDistinguishesPorts(t *testing.T) {
+func TestTemplateKeepsUserRulesOnTheSamePort(t *testing.T) {
+	allowApp := securityv1.GlobalRule{
+		Type: PolicyTypeAllow, Port: 5432, Protocol: ProtocolTCP, Direction: DirectionIngress,
+		From: []securityv1.RulePeer{{Namespaces: []string{"app"}}},
+	}
+	denyAll := securityv1.GlobalRule{
+		Type: PolicyTypeDeny, Port: 5432, Protocol: ProtocolTCP, Direction: DirectionIngress,
+	}
+	spec := &securityv1.NetworkPolicyGeneratorSpec{
+		GlobalRules: []securityv1.GlobalRule{allowApp, denyAll},
+	}
+
+	GetTemplate(TemplateZeroTrust).Apply(spec)
+
+	assert.Equal(t, []securityv1.GlobalRule{allowApp, denyAll}, spec.GlobalRules)
+}
+
+func TestZeroTrustKeepsDNSBesideUserDNSRules(t *testing.T) {
+	generator := &securityv1.NetworkPolicyGenerator{
+		ObjectMeta: metav1.ObjectMeta{Name: nameTestPolicy, Namespace: nsTest},
+		Spec: securityv1.NetworkPolicyGeneratorSpec{
+			PolicyEngine: EngineCalico,
+			TemplateName: TemplateZeroTrust,
+			GlobalRules: []securityv1.GlobalRule{
+				{
+					Type: PolicyTypeAllow, Port: DNSPort, Protocol: ProtocolUDP, Direction: DirectionEgress,
+					To: []securityv1.RulePeer{{CIDR: "10.96.0.10/32"}},
+				},
+				{Type: PolicyTypeDeny, Port: DNSPort, Protocol: ProtocolUDP, Direction: DirectionEgress},
+			},
+		},
+	}
+	userRules := slices.Clone(generator.Spec.GlobalRules)
+
+	GetTemplate(TemplateZeroTrust).Apply(&generator.Spec)
+	require.Equal(t, userRules, generator.Spec.GlobalRules)
+
+	objects, err := NewCalicoEngine().GeneratePolicies(generator)
+	require.NoError(t, err)
+	policy := objects[0].(*CalicoNetworkPolicy)
+
+	// The narrower user rule neither replaces nor absorbs the cluster DNS rules
+	for _, rule := range dnsEgressRulesCalico(&generator.Spec) {
+		assert.Contains(t, policy.Spec.Egress, rule)
+	}
+	assert.Contains(t, policy.Spec.Egress, CalicoRule{
+		Action:      CalicoActionAllow,
+		Protocol:    ProtocolUDP,
+		Destination: &CalicoEntityRule{Nets: []string{"10.96.0.10/32"}, Ports: []interface{}{DNSPortStr}},
+	})
+	assert.Equal(t, CalicoActionDeny, policy.Spec.Egress[0].Action)
+}
+
+// TestMergeGlobalRulesDistinguishesPorts guards against comparing rules by an
+// encoded key: ports 55296-57343 map onto the UTF-16 surrogate range, so a
+// string(rune(p)) key would collapse them and silently drop rules.
+func TestMergeGlobalRulesDistinguishesPorts(t *testing.T) {
 	ports := []int32{1, 80, 443, 55295, 55296, 55297, 57343, 57344, 65535}
 
-	seen := make(map[string]int32, len(ports))
+	userRules := []securityv1.GlobalRule{
+		{Type: PolicyTypeAllow, Port: 55296, Protocol: ProtocolTCP, Direction: DirectionIngress},
+	}
+	templateRules := make([]securityv1.GlobalRule, 0, len(ports))
 	for _, port := range ports {
-		key := globalRuleKey(securityv1.GlobalRule{
+		templateRules = append(templateRules, securityv1.GlobalRule{
 			Type:      PolicyTypeAllow,
 			Port:      port,
 			Protocol:  ProtocolTCP,
 			Direction: DirectionIngress,
 		})
-		prev, dup := seen[key]
-		require.Falsef(t, dup, "ports %d and %d collide on key %q", prev, port, key)
-		seen[key] = port
 	}
 
-	assert.Len(t, seen, len(ports))
+	result := mergeGlobalRules(userRules, templateRules...)
+
+	// Only the template rule identical to the user rule is dropped
+	assert.Len(t, result, len(ports))
 }
@@ -24,4 +24,11 @@ const (
 	cidr10Slash8   = "10.0.0.0/8"
 	cidr192Slash24 = "192.168.1.0/24"
 	cidrHost192    = "192.168.1.100/32"
+	cidrNodeLocal  = "169.254.20.10/32"
+
+	fqdnAPI      = "api.example.com"
+	fqdnWildcard = "*.example.com"
+
+	pluginName       = "acme"
+	pluginAPIVersion = "acme.example.com/v1"
 )
@@ -6,6 +6,7 @@ import (
 
 	securityv1 "github.com/somaz94/network-policy-generator/api/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Validator handles NetworkPolicy validation
@@ -67,6 +68,15 @@ func (v *Validator) validatePorts(ports []networkingv1.NetworkPolicyPort) error
 			}
 		}
 
+		if port.EndPort != nil {
+			if port.Port == nil || port.Port.Type != 0 {
+				return fmt.Errorf("endPort for port %d requires a numeric port", i)
+			}
+			if *port.EndPort < port.Port.IntVal || *port.EndPort > 65535 {
+				return fmt.Errorf("endPort %d for port %d must be between port %d and 65535", *port.EndPort, i, port.Port.IntVal)
+			}
+		}
+
 		if port.Protocol != nil {
 			protocol := *port.Protocol
 			if protocol != ProtocolTCP && protocol != ProtocolUDP && protocol != ProtocolSCTP {
@@ -92,18 +102,134 @@ func (v *Validator) validateNamespaceConfigs(generator *securityv1.NetworkPolicy
 		}
 	}
 
+	if sel := generator.Spec.Policy.PodLabelSelector; sel != nil {
+		if len(generator.Spec.Policy.PodSelector) > 0 {
+			return fmt.Errorf("podSelector and podLabelSelector are mutually exclusive")
+		}
+		if _, err := metav1.LabelSelectorAsSelector(sel); err != nil {
+			return fmt.Errorf("invalid podLabelSelector: %w", err)
+		}
+	}
+	if sel := generator.Spec.Policy.AllowedNamespaceSelector; sel != nil {
+		if _, err := metav1.LabelSelectorAsSelector(sel); err != nil {
+			return fmt.Errorf("invalid allowedNamespaceSelector: %w", err)
+		}
+	}
+	if sel := generator.Spec.Policy.DeniedNamespaceSelector; sel != nil {
+		if _, err := metav1.LabelSelectorAsSelector(sel); err != nil {
+			return fmt.Errorf("invalid deniedNamespaceSelector: %w", err)
+		}
+	}
+
 	return nil
 }
 
 // ValidateGlobalRules checks if the global rules are valid
 func (v *Validator) ValidateGlobalRules(rules []securityv1.GlobalRule) error {
 	for i, rule := range rules {
+		if err := v.validateRulePeers(rule); err != nil {
+			return fmt.Errorf("global rule %d: %w", i, err)
+		}
+		if IsICMPProtocol(rule.Protocol) {
+			if err := v.validateICMPRule(rule); err != nil {
+				return fmt.Errorf("global rule %d: %w", i, err)
+			}
+			continue
+		}
+		if rule.ICMP != nil {
+			return fmt.Errorf("global rule %d: icmp requires protocol ICMP or ICMPv6", i)
+		}
+		if rule.NotICMP != nil {
+			return fmt.Errorf("global rule %d: notICMP requires protocol ICMP or ICMPv6", i)
+		}
 		if rule.Port == 0 && rule.NamedPort == "" {
 			return fmt.Errorf("global rule %d: either port or namedPort must be specified", i)
 		}
 		if rule.Port != 0 && rule.NamedPort != "" {
 			return fmt.Errorf("global rule %d: port and namedPort are mutually exclusive", i)
 		}
+		if rule.EndPort != 0 {
+			if rule.EndPort < 1 || rule.EndPort > 65535 {
+				return fmt.Errorf("global rule %d: endPort %d is out of valid range (1-65535)", i, rule.EndPort)
+			}
+			if rule.Port == 0 {
+				return fmt.Errorf("global rule %d: endPort requires a numeric port", i)
+			}
+			if rule.EndPort < rule.Port {
+				return fmt.Errorf("global rule %d: endPort %d must be greater than or equal to port %d", i, rule.EndPort, rule.Port)
+			}
+			if len(rule.HTTP) > 0 {
+				return fmt.Errorf("global rule %d: http rules cannot be combined with endPort", i)
+			}
+		}
+		if len(rule.HTTP) > 0 && rule.Protocol != ProtocolTCP {
+			return fmt.Errorf("global rule %d: http rules require protocol TCP", i)
+		}
+		if len(rule.HTTP) > 0 && rule.Type == PolicyTypeDeny {
+			return fmt.Errorf("global rule %d: http rules cannot be combined with type deny", i)
+		}
+	}
+	return nil
+}
+
+// validateRulePeers checks that from is only set on ingress rules and to only
+// on egress rules, and that every peer selects either pods or a CIDR
+func (v *Validator) validateRulePeers(rule securityv1.GlobalRule) error {
+	if len(rule.From) > 0 && rule.Direction != DirectionIngress {
+		return fmt.Errorf("from requires direction ingress")
+	}
+	if len(rule.To) > 0 && rule.Direction != DirectionEgress {
+		return fmt.Errorf("to requires direction egress")
+	}
+	peers := rule.From
+	if rule.Direction == DirectionEgress {
+		peers = rule.To
+	}
+	for i, peer := range peers {
+		selectsPods := len(peer.Namespaces) > 0 || len(peer.NamespaceSelector) > 0 || len(peer.PodSelector) > 0
+		if (peer.CIDR != "") == selectsPods {
+			return fmt.Errorf("peer %d: set either cidr or at least one of namespaces, namespaceSelector and podSelector", i)
+		}
+		if len(peer.Namespaces) > 0 && len(peer.NamespaceSelector) > 0 {
+			return fmt.Errorf("peer %d: namespaces and namespaceSelector are mutually exclusive", i)
+		}
+		if peer.CIDR != "" {
+			if _, _, err := net.ParseCIDR(peer.CIDR); err != nil {
+				return fmt.Errorf("peer %d: invalid CIDR %q: %w", i, peer.CIDR, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateICMPRule checks an ICMP or ICMPv6 global rule, which matches or
+// excludes a message type instead of a port
+func (v *Validator) validateICMPRule(rule securityv1.GlobalRule) error {
+	if rule.Port != 0 || rule.NamedPort != "" || rule.EndPort != 0 {
+		return fmt.Errorf("%s rules cannot specify a port", rule.Protocol)
+	}
+	if len(rule.HTTP) > 0 {
+		return fmt.Errorf("http rules require protocol TCP")
+	}
+	if rule.ICMP == nil && rule.NotICMP == nil {
+		return fmt.Errorf("%s rules require icmp or notICMP", rule.Protocol)
+	}
+	if err := validateICMPMatch("icmp", rule.ICMP); err != nil {
+		return err
+	}
+	return validateICMPMatch("notICMP", rule.NotICMP)
+}
+
+// validateICMPMatch checks the type and code range of an icmp or notICMP match
+func validateICMPMatch(field string, match *securityv1.ICMPMatch) error {
+	if match == nil {
+		return nil
+	}
+	if match.Type < 0 || match.Type > 255 {
+		return fmt.Errorf("%s type %d is out of valid range (0-255)", field, match.Type)
+	}
+	if match.Code != nil && (*match.Code < 0 || *match.Code > 255) {
+		return fmt.Errorf("%s code %d is out of valid range (0-255)", field, *match.Code)
 	}
 	return nil
 }
@@ -111,13 +237,18 @@ func (v *Validator) ValidateGlobalRules(rules []securityv1.GlobalRule) error {
 // ValidateCIDRRules checks if the CIDR rules are valid
 func (v *Validator) ValidateCIDRRules(rules []securityv1.CIDRRule) error {
 	for i, rule := range rules {
-		if _, _, err := net.ParseCIDR(rule.CIDR); err != nil {
+		_, ipNet, err := net.ParseCIDR(rule.CIDR)
+		if err != nil {
 			return fmt.Errorf("CIDR rule %d: invalid CIDR %q: %w", i, rule.CIDR, err)
 		}
 		for j, except := range rule.Except {
-			if _, _, err := net.ParseCIDR(except); err != nil {
+			_, exceptNet, err := net.ParseCIDR(except)
+			if err != nil {
 				return fmt.Errorf("CIDR rule %d except %d: invalid CIDR %q: %w", i, j, except, err)
 			}
+			if err := validateCIDRExcept(ipNet, exceptNet); err != nil {
+				return fmt.Errorf("CIDR rule %d except %d: %w", i, j, err)
+			}
 		}
 		if rule.Direction != DirectionIngress && rule.Direction != DirectionEgress {
 			return fmt.Errorf("CIDR rule %d: direction must be 'ingress' or 'egress'", i)
@@ -125,3 +256,33 @@ func (v *Validator) ValidateCIDRRules(rules []securityv1.CIDRRule) error {
 	}
 	return nil
 }
+
+// validateCIDRExcept checks that an except CIDR is of the same IP family as
+// the rule CIDR and lies within it
+func validateCIDRExcept(ipNet, exceptNet *net.IPNet) error {
+	if cidrFamily(ipNet) != cidrFamily(exceptNet) {
+		return fmt.Errorf("%s is %s but the rule CIDR %s is %s", exceptNet, cidrFamily(exceptNet), ipNet, cidrFamily(ipNet))
+	}
+	ones, _ := ipNet.Mask.Size()
+	exceptOnes, _ := exceptNet.Mask.Size()
+	if !ipNet.Contains(exceptNet.IP) || exceptOnes < ones {
+		return fmt.Errorf("%s is not within the rule CIDR %s", exceptNet, ipNet)
+	}
+	return nil
+}
+
+// ValidateFQDNRules checks that every FQDN rule sets exactly one of matchName
+// or matchPattern and that its ports are in range
+func (v *Validator) ValidateFQDNRules(rules []securityv1.FQDNRule) error {
+	for i, rule := range rules {
+		if (rule.MatchName == "") == (rule.MatchPattern == "") {
+			return fmt.Errorf("FQDN rule %d: exactly one of matchName or matchPattern must be specified", i)
+		}
+		for j, port := range rule.Ports {
+			if port.Port < 1 || port.Port > 65535 {
+				return fmt.Errorf("FQDN rule %d port %d: port %d is out of valid range (1-65535)", i, j, port.Port)
+			}
+		}
+	}
+	return nil
+}
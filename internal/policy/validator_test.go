@@ -244,6 +244,57 @@ func TestValidator(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("Validate Invalid Namespace Selector", func(t *testing.T) {
+		generator := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type: PolicyTypeAllow,
+					DeniedNamespaceSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "env", Operator: metav1.LabelSelectorOpExists, Values: []string{"dev"}},
+						},
+					},
+				},
+			},
+		}
+		policy := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+		}
+		err := validator.ValidatePolicy(policy, generator)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid deniedNamespaceSelector")
+	})
+
+	t.Run("Validate Pod Selector And Pod Label Selector", func(t *testing.T) {
+		generator := &securityv1.NetworkPolicyGenerator{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: nsTest,
+			},
+			Spec: securityv1.NetworkPolicyGeneratorSpec{
+				Policy: securityv1.PolicyConfig{
+					Type:             PolicyTypeDeny,
+					PodSelector:      map[string]string{labelApp: labelValueWeb},
+					PodLabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{labelTier: labelValueFrontend}},
+				},
+			},
+		}
+		policy := &networkingv1.NetworkPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      nameTestPolicy,
+				Namespace: nsTest,
+			},
+		}
+		err := validator.ValidatePolicy(policy, generator)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "mutually exclusive")
+	})
+
 	t.Run("Validate Invalid Protocol", func(t *testing.T) {
 		policy := &networkingv1.NetworkPolicy{
 			ObjectMeta: metav1.ObjectMeta{
@@ -322,6 +373,39 @@ func TestValidateGlobalRules(t *testing.T) {
 		assert.NoError(t, err)
 	})
 
+	t.Run("Valid Peers", func(t *testing.T) {
+		rules := []securityv1.GlobalRule{
+			{Port: 5432, Protocol: ProtocolTCP, Direction: DirectionIngress, From: []securityv1.RulePeer{
+				{Namespaces: []string{nsOne}, PodSelector: map[string]string{labelApp: labelValueWeb}},
+				{CIDR: cidr10Slash8},
+			}},
+		}
+		assert.NoError(t, validator.ValidateGlobalRules(rules))
+	})
+
+	t.Run("Error When Peers Do Not Match Direction", func(t *testing.T) {
+		rules := []securityv1.GlobalRule{
+			{Port: 5432, Protocol: ProtocolTCP, Direction: DirectionIngress, To: []securityv1.RulePeer{{CIDR: cidr10Slash8}}},
+		}
+		assert.ErrorContains(t, validator.ValidateGlobalRules(rules), "to requires direction egress")
+	})
+
+	t.Run("Error When Peer Mixes CIDR And Selectors", func(t *testing.T) {
+		rules := []securityv1.GlobalRule{
+			{Port: 5432, Protocol: ProtocolTCP, Direction: DirectionEgress, To: []securityv1.RulePeer{
+				{CIDR: cidr10Slash8, Namespaces: []string{nsOne}},
+			}},
+		}
+		assert.ErrorContains(t, validator.ValidateGlobalRules(rules), "set either cidr")
+	})
+
+	t.Run("Error When Peer CIDR Is Invalid", func(t *testing.T) {
+		rules := []securityv1.GlobalRule{
+			{Port: 5432, Protocol: ProtocolTCP, Direction: DirectionEgress, To: []securityv1.RulePeer{{CIDR: "10.0.0.0"}}},
+		}
+		assert.ErrorContains(t, validator.ValidateGlobalRules(rules), "invalid CIDR")
+	})
+
 	t.Run("Error When Neither Port Nor NamedPort", func(t *testing.T) {
 		rules := []securityv1.GlobalRule{
 			{Protocol: ProtocolTCP, Direction: DirectionIngress},
@@ -339,6 +423,72 @@ func TestValidateGlobalRules(t *testing.T) {
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "mutually exclusive")
 	})
+
+	t.Run("Error When HTTP Rules On UDP", func(t *testing.T) {
+		rules := []securityv1.GlobalRule{
+			{Port: 53, Protocol: ProtocolUDP, Direction: DirectionIngress, HTTP: []securityv1.HTTPRule{{Method: "GET"}}},
+		}
+		err := validator.ValidateGlobalRules(rules)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "require protocol TCP")
+	})
+
+	t.Run("Valid Port Range", func(t *testing.T) {
+		rules := []securityv1.GlobalRule{
+			{Port: 30000, EndPort: 32767, Protocol: ProtocolTCP, Direction: DirectionIngress},
+			{Port: 53, EndPort: 53, Protocol: ProtocolUDP, Direction: DirectionEgress},
+		}
+		assert.NoError(t, validator.ValidateGlobalRules(rules))
+	})
+
+	t.Run("Error When Port Range Is Invalid", func(t *testing.T) {
+		invalid := map[string]securityv1.GlobalRule{
+			"must be greater than or equal to port": {Port: 8080, EndPort: 8000, Protocol: ProtocolTCP, Direction: DirectionIngress},
+			"requires a numeric port":               {NamedPort: namedPortHTTP, EndPort: 8000, Protocol: ProtocolTCP, Direction: DirectionIngress},
+			"out of valid range":                    {Port: 8080, EndPort: 70000, Protocol: ProtocolTCP, Direction: DirectionIngress},
+			"cannot be combined with endPort": {Port: 8080, EndPort: 8090, Protocol: ProtocolTCP, Direction: DirectionIngress,
+				HTTP: []securityv1.HTTPRule{{Method: "GET"}}},
+		}
+		for msg, rule := range invalid {
+			err := validator.ValidateGlobalRules([]securityv1.GlobalRule{rule})
+			assert.ErrorContains(t, err, msg)
+		}
+	})
+
+	t.Run("Valid ICMP Rules", func(t *testing.T) {
+		rules := []securityv1.GlobalRule{
+			{Protocol: ProtocolICMP, Direction: DirectionIngress, ICMP: &securityv1.ICMPMatch{Type: 8}},
+			{Protocol: ProtocolICMPv6, Direction: DirectionIngress, ICMP: &securityv1.ICMPMatch{Type: 1, Code: ptr.To(int32(4))}},
+			{Protocol: ProtocolICMP, Direction: DirectionEgress, NotICMP: &securityv1.ICMPMatch{Type: 5}},
+			{Port: 38412, Protocol: ProtocolSCTP, Direction: DirectionIngress},
+		}
+		assert.NoError(t, validator.ValidateGlobalRules(rules))
+	})
+
+	t.Run("Error When ICMP Rule Is Invalid", func(t *testing.T) {
+		invalid := map[string]securityv1.GlobalRule{
+			"cannot specify a port":       {Port: 8, Protocol: ProtocolICMP, Direction: DirectionIngress, ICMP: &securityv1.ICMPMatch{Type: 8}},
+			"require icmp":                {Protocol: ProtocolICMP, Direction: DirectionIngress},
+			"type 300 is out of valid":    {Protocol: ProtocolICMP, Direction: DirectionIngress, ICMP: &securityv1.ICMPMatch{Type: 300}},
+			"code 256 is out of valid":    {Protocol: ProtocolICMP, Direction: DirectionIngress, ICMP: &securityv1.ICMPMatch{Type: 3, Code: ptr.To(int32(256))}},
+			"icmp requires protocol ICMP": {Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress, ICMP: &securityv1.ICMPMatch{Type: 8}},
+			"notICMP requires protocol":   {Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress, NotICMP: &securityv1.ICMPMatch{Type: 8}},
+			"notICMP type 300 is out":     {Protocol: ProtocolICMP, Direction: DirectionIngress, NotICMP: &securityv1.ICMPMatch{Type: 300}},
+		}
+		for msg, rule := range invalid {
+			err := validator.ValidateGlobalRules([]securityv1.GlobalRule{rule})
+			assert.ErrorContains(t, err, msg)
+		}
+	})
+
+	t.Run("Error When HTTP Rules On Deny Rule", func(t *testing.T) {
+		rules := []securityv1.GlobalRule{
+			{Type: PolicyTypeDeny, Port: 80, Protocol: ProtocolTCP, Direction: DirectionIngress, HTTP: []securityv1.HTTPRule{{Method: "GET"}}},
+		}
+		err := validator.ValidateGlobalRules(rules)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be combined with type deny")
+	})
 }
 
 func TestValidateCIDRRules(t *testing.T) {
@@ -371,6 +521,29 @@ func TestValidateCIDRRules(t *testing.T) {
 		assert.Contains(t, err.Error(), "except")
 	})
 
+	t.Run("Valid IPv6 Except", func(t *testing.T) {
+		rules := []securityv1.CIDRRule{
+			{CIDR: "2001:db8::/32", Except: []string{"2001:db8:1::/48"}, Direction: DirectionEgress},
+		}
+		assert.NoError(t, validator.ValidateCIDRRules(rules))
+	})
+
+	t.Run("Mixed Family Except", func(t *testing.T) {
+		rules := []securityv1.CIDRRule{
+			{CIDR: cidr10Slash8, Except: []string{"2001:db8::/32"}, Direction: DirectionEgress},
+		}
+		err := validator.ValidateCIDRRules(rules)
+		assert.ErrorContains(t, err, "is IPv6 but the rule CIDR 10.0.0.0/8 is IPv4")
+	})
+
+	t.Run("Except Outside CIDR", func(t *testing.T) {
+		rules := []securityv1.CIDRRule{
+			{CIDR: cidr192Slash24, Except: []string{"10.1.0.0/16"}, Direction: DirectionEgress},
+		}
+		err := validator.ValidateCIDRRules(rules)
+		assert.ErrorContains(t, err, "is not within the rule CIDR")
+	})
+
 	t.Run("Invalid Direction", func(t *testing.T) {
 		rules := []securityv1.CIDRRule{
 			{CIDR: cidr10Slash8, Direction: "invalid"},
@@ -385,3 +558,35 @@ func TestValidateCIDRRules(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestValidateFQDNRules(t *testing.T) {
+	validator := NewValidator()
+
+	t.Run("Valid Name And Pattern", func(t *testing.T) {
+		rules := []securityv1.FQDNRule{
+			{MatchName: fqdnAPI, Ports: []securityv1.FQDNPort{{Port: 443, Protocol: ProtocolTCP}}},
+			{MatchPattern: fqdnWildcard},
+		}
+		assert.NoError(t, validator.ValidateFQDNRules(rules))
+	})
+
+	t.Run("Error When Neither Name Nor Pattern", func(t *testing.T) {
+		err := validator.ValidateFQDNRules([]securityv1.FQDNRule{{}})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "exactly one of matchName or matchPattern")
+	})
+
+	t.Run("Error When Both Name And Pattern", func(t *testing.T) {
+		err := validator.ValidateFQDNRules([]securityv1.FQDNRule{{MatchName: fqdnAPI, MatchPattern: fqdnWildcard}})
+		assert.Error(t, err)
+	})
+
+	t.Run("Error When Port Out Of Range", func(t *testing.T) {
+		rules := []securityv1.FQDNRule{
+			{MatchName: fqdnAPI, Ports: []securityv1.FQDNPort{{Port: 70000}}},
+		}
+		err := validator.ValidateFQDNRules(rules)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "out of valid range")
+	})
+}